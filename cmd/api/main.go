@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"ss-api/internal/app"
+	"ss-api/internal/config"
+	httpserver "ss-api/internal/http"
+	"ss-api/internal/logging"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "stella-api: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	load := func() (config.Config, error) {
+		return config.Resolve(os.Args[0], os.Args[1:], os.LookupEnv)
+	}
+
+	cfg, err := load()
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	logger, err := logging.New(cfg.Log)
+	if err != nil {
+		return err
+	}
+	defer logger.Close()
+	logger.Install()
+
+	appInstance := app.New(cfg)
+	appInstance.SetConfigLoader(load)
+	appInstance.OnReload(func(_, next config.Config) {
+		if err := logger.SetLevel(next.Log.Level); err != nil {
+			logger.Error("config reload: invalid log level", "error", err)
+		}
+		logger.SetAccessFilter(next.Log.Access)
+	})
+	server := httpserver.New(appInstance, logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go reloadOnHangup(ctx, appInstance)
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("starting server", "addr", cfg.Server.Addr)
+		errCh <- appInstance.Start(ctx, server.Handler(), cfg.Server.Addr)
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := appInstance.Shutdown(shutdownCtx); err != nil {
+		slog.Error("shutdown failed", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// reloadOnHangup re-reads the configuration on every SIGHUP until ctx ends.
+// Rejected or invalid reloads are logged and the running config is kept.
+func reloadOnHangup(ctx context.Context, appInstance *app.App) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			if _, err := appInstance.Reload(); err != nil {
+				slog.Error("config reload rejected", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Command stella-import loads a catalog dump directory into Mongo:
+//
+//	stella-import [-dry-run] [-force] [config flags] <dump-dir>
+//
+// The directory holds <region>/<collection>.json, .yaml or .yml files,
+// e.g. EN/characters.json. Each file replaces the region's documents once
+// the dump passes validation. -dry-run prints the diff and the validation
+// issues without writing; -force writes despite validation errors. Every
+// other flag, -config and STELLA_* variables configure the connection as
+// for the API.
+//
+// The result is printed as JSON. The exit status is 0 when the import was
+// applied or the dry run found no errors, 2 when validation found errors,
+// and 1 when the import could not run or writing failed.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"ss-api/internal/app"
+	"ss-api/internal/config"
+	"ss-api/internal/importer"
+	"ss-api/internal/validate"
+)
+
+func main() {
+	ok, err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "stella-import: %v\n", err)
+		os.Exit(1)
+	}
+	if !ok {
+		os.Exit(2)
+	}
+}
+
+func run() (bool, error) {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "validate and print the diff without writing")
+	force := fs.Bool("force", false, "write even when validation finds errors")
+
+	own, rest, dirs := splitArgs(fs, os.Args[1:])
+	if err := fs.Parse(own); err != nil {
+		return false, err
+	}
+
+	cfg, err := config.Resolve(os.Args[0], rest, os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "usage: %s [-dry-run] [-force] [config flags] <dump-dir>\n", filepath.Base(os.Args[0]))
+		fs.SetOutput(os.Stderr)
+		fs.PrintDefaults()
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if len(dirs) != 1 {
+		return false, errors.New("expected one dump directory")
+	}
+
+	dumps, err := importer.ReadDir(dirs[0])
+	if err != nil {
+		return false, err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	appInstance := app.New(cfg)
+	if err := appInstance.Connect(ctx); err != nil {
+		return false, fmt.Errorf("connect to mongo: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = appInstance.Shutdown(shutdownCtx)
+	}()
+
+	source, err := filepath.Abs(dirs[0])
+	if err != nil {
+		source = dirs[0]
+	}
+	db := appInstance.MongoClient().Database(appInstance.DatabaseName())
+	result, err := importer.Import(ctx, db, dumps, importer.Options{
+		Source: source,
+		DryRun: *dryRun,
+		Force:  *force,
+		Assets: validate.NewAssets(appInstance.AssetsDir()),
+	})
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(result); encodeErr != nil && err == nil {
+		err = encodeErr
+	}
+	if err != nil {
+		return false, err
+	}
+	return result.Validation.OK || result.Status == importer.StatusApplied, nil
+}
+
+// splitArgs separates the flags defined on fs from the configuration flags
+// and the positional arguments. Configuration flags all take a value, so a
+// bare one consumes the next argument.
+func splitArgs(fs *flag.FlagSet, args []string) (own, rest, positional []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if f := fs.Lookup(name); f != nil {
+			own = append(own, arg)
+			if boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && boolFlag.IsBoolFlag() {
+				continue
+			}
+			if !hasValue && i+1 < len(args) {
+				i++
+				own = append(own, args[i])
+			}
+			continue
+		}
+
+		rest = append(rest, arg)
+		if !hasValue && name != "h" && name != "help" && i+1 < len(args) {
+			i++
+			rest = append(rest, args[i])
+		}
+	}
+	return own, rest, positional
+}
@@ -0,0 +1,71 @@
+// Command stella-validate checks the catalog collections for data problems
+// and prints the report as JSON. It reads the same configuration as the
+// API, so -config, STELLA_* variables and flags such as -mongo.uri and
+// -assets.dir apply.
+//
+// The exit status is 0 when the report has no errors, 2 when it has, and 1
+// when validation could not run.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ss-api/internal/app"
+	"ss-api/internal/config"
+	"ss-api/internal/validate"
+)
+
+func main() {
+	ok, err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "stella-validate: %v\n", err)
+		os.Exit(1)
+	}
+	if !ok {
+		os.Exit(2)
+	}
+}
+
+func run() (bool, error) {
+	cfg, err := config.Resolve(os.Args[0], os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	appInstance := app.New(cfg)
+	if err := appInstance.Connect(ctx); err != nil {
+		return false, fmt.Errorf("connect to mongo: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = appInstance.Shutdown(shutdownCtx)
+	}()
+
+	db := appInstance.MongoClient().Database(appInstance.DatabaseName())
+	report, err := validate.Run(ctx, db, validate.NewAssets(appInstance.AssetsDir()))
+	if err != nil {
+		return false, err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return false, err
+	}
+	return report.OK, nil
+}
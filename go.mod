@@ -3,7 +3,7 @@ module ss-api
 go 1.25.0
 
 require (
-	github.com/jasonlvhit/gocron v0.0.1
+	github.com/andybalholm/brotli v1.2.6
 	go.mongodb.org/mongo-driver v1.17.7
 	golang.org/x/sync v0.20.0
 	gopkg.in/yaml.v3 v3.0.1
@@ -18,6 +18,5 @@ require (
 	github.com/xdg-go/stringprep v1.0.4 // indirect
 	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
 	golang.org/x/crypto v0.52.0 // indirect
-	golang.org/x/sys v0.45.0 // indirect
 	golang.org/x/text v0.37.0 // indirect
 )
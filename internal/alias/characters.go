@@ -2,7 +2,9 @@ package alias
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -17,19 +19,30 @@ var (
 
 func InitCharacterNamesFromDB(client *mongo.Client, dbName string) {
 	if client == nil {
-		log.Println("alias: mongo client is nil, skipping character name initialization")
+		slog.Warn("alias: mongo client is nil, skipping character name initialization")
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	if err := LoadCharacterNames(ctx, client, dbName); err != nil {
+		slog.Error("alias: failed to load character names", "error", err)
+	}
+}
+
+// LoadCharacterNames rebuilds the ID → English name map from the EN
+// character documents. The previous map is kept when loading fails.
+func LoadCharacterNames(ctx context.Context, client *mongo.Client, dbName string) error {
+	if client == nil {
+		return errors.New("mongo client not initialised")
+	}
+
 	collection := client.Database(dbName).Collection("characters")
 
 	cursor, err := collection.Find(ctx, bson.D{{Key: "region", Value: "EN"}})
 	if err != nil {
-		log.Printf("alias: failed to query EN characters: %v", err)
-		return
+		return fmt.Errorf("failed to query EN characters: %w", err)
 	}
 	defer cursor.Close(ctx)
 
@@ -44,7 +57,7 @@ func InitCharacterNamesFromDB(client *mongo.Client, dbName string) {
 		}
 
 		if err := cursor.Decode(&doc); err != nil {
-			log.Printf("alias: failed to decode character document: %v", err)
+			slog.WarnContext(ctx, "alias: failed to decode character document", "error", err)
 			continue
 		}
 
@@ -56,15 +69,15 @@ func InitCharacterNamesFromDB(client *mongo.Client, dbName string) {
 	}
 
 	if err := cursor.Err(); err != nil {
-		log.Printf("alias: cursor error: %v", err)
-		return
+		return fmt.Errorf("cursor error: %w", err)
 	}
 
 	characterNamesMu.Lock()
 	characterEnglishNames = names
 	characterNamesMu.Unlock()
 
-	log.Printf("alias: loaded %d character English names from database", len(names))
+	slog.InfoContext(ctx, "alias: loaded character English names from database", "count", len(names))
+	return nil
 }
 
 // EnglishNameFromID returns the English name for a character given their ID.
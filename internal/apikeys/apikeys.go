@@ -0,0 +1,319 @@
+// Package apikeys keeps the API keys and rate limit tiers stored in Mongo.
+// The rate limiter looks keys up on every request, so the store holds an
+// in-memory copy that is reloaded after each change made through the admin
+// API and on the api-keys-reload interval, which picks up changes made by
+// other instances.
+package apikeys
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ss-api/internal/app"
+	"ss-api/internal/config"
+)
+
+// Mongo collections holding keys and tiers.
+const (
+	KeysCollection  = "api_keys"
+	TiersCollection = "api_tiers"
+)
+
+const reloadJobName = "api-keys-reload"
+
+// Tier is a named quota shared by every key assigned to it.
+type Tier struct {
+	Name        string `bson:"_id" json:"name"`
+	Description string `bson:"description,omitempty" json:"description,omitempty"`
+	// RequestsPerMinute is the sustained rate; Burst is how many requests
+	// may arrive at once after a quiet period.
+	RequestsPerMinute int `bson:"requestsPerMinute" json:"requestsPerMinute"`
+	Burst             int `bson:"burst" json:"burst"`
+	// Unlimited exempts the tier from rate limiting, e.g. for our own sites.
+	Unlimited bool      `bson:"unlimited,omitempty" json:"unlimited,omitempty"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Key is an issued API key. Only the SHA-256 hash of the secret is stored;
+// Prefix keeps enough of it to recognise a key in logs and listings.
+type Key struct {
+	ID     string `bson:"_id" json:"id"`
+	Name   string `bson:"name" json:"name"`
+	Tier   string `bson:"tier" json:"tier"`
+	Hash   string `bson:"hash" json:"-"`
+	Prefix string `bson:"prefix" json:"prefix"`
+	// RequestsPerMinute and Burst override the tier's quota when set.
+	RequestsPerMinute int       `bson:"requestsPerMinute,omitempty" json:"requestsPerMinute,omitempty"`
+	Burst             int       `bson:"burst,omitempty" json:"burst,omitempty"`
+	Disabled          bool      `bson:"disabled" json:"disabled"`
+	CreatedAt         time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt         time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Quota is the token bucket size a client is limited by.
+type Quota struct {
+	// Name identifies the tier in headers and metrics.
+	Name              string `json:"name"`
+	RequestsPerMinute int    `json:"requestsPerMinute"`
+	Burst             int    `json:"burst"`
+	Unlimited         bool   `json:"unlimited,omitempty"`
+}
+
+// Anonymous is the quota of requests without a key, keyed by client IP.
+func Anonymous(cfg config.RateLimitConfig) Quota {
+	return Quota{Name: "anonymous", RequestsPerMinute: cfg.RequestsPerMinute, Burst: cfg.Burst}
+}
+
+// Usage counts a key's requests since the process started.
+type Usage struct {
+	Requests   int64      `json:"requests"`
+	Limited    int64      `json:"limited"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+type usage struct {
+	requests atomic.Int64
+	limited  atomic.Int64
+	lastUsed atomic.Int64 // unix nanoseconds
+}
+
+// Store holds keys and tiers in memory and writes changes through to Mongo.
+type Store struct {
+	app *app.App
+
+	mu     sync.RWMutex
+	byHash map[string]Key
+	byID   map[string]Key
+	tiers  map[string]Tier
+	loaded bool
+
+	usage   sync.Map // key ID → *usage
+	indexed atomic.Bool
+}
+
+// New builds the store and registers the job that reloads it.
+func New(appInstance *app.App) *Store {
+	s := &Store{
+		app:    appInstance,
+		byHash: map[string]Key{},
+		byID:   map[string]Key{},
+		tiers:  map[string]Tier{},
+	}
+
+	_ = appInstance.Scheduler().Register(app.Job{
+		Name:       reloadJobName,
+		Interval:   appInstance.Config().RateLimit.KeyRefreshInterval,
+		RunOnStart: true,
+		Timeout:    30 * time.Second,
+		Run:        s.Load,
+	})
+	appInstance.OnReload(func(old, next config.Config) {
+		if old.RateLimit.KeyRefreshInterval != next.RateLimit.KeyRefreshInterval {
+			_ = appInstance.Scheduler().Reschedule(reloadJobName, next.RateLimit.KeyRefreshInterval)
+		}
+	})
+
+	return s
+}
+
+// Loaded reports whether keys have been read from Mongo at least once. Until
+// then a presented key cannot be told apart from an unknown one.
+func (s *Store) Loaded() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loaded
+}
+
+// Load replaces the in-memory keys and tiers with the ones in Mongo. The
+// previous set is kept when loading fails.
+func (s *Store) Load(ctx context.Context) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	s.ensureIndexes(ctx, db)
+
+	var tiers []Tier
+	cursor, err := db.Collection(TiersCollection).Find(ctx, bson.D{})
+	if err != nil {
+		return fmt.Errorf("query tiers: %w", err)
+	}
+	if err := cursor.All(ctx, &tiers); err != nil {
+		return fmt.Errorf("decode tiers: %w", err)
+	}
+
+	var keys []Key
+	cursor, err = db.Collection(KeysCollection).Find(ctx, bson.D{})
+	if err != nil {
+		return fmt.Errorf("query keys: %w", err)
+	}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return fmt.Errorf("decode keys: %w", err)
+	}
+
+	byHash := make(map[string]Key, len(keys))
+	byID := make(map[string]Key, len(keys))
+	for _, key := range keys {
+		byHash[key.Hash] = key
+		byID[key.ID] = key
+	}
+	tierMap := make(map[string]Tier, len(tiers))
+	for _, tier := range tiers {
+		tierMap[tier.Name] = tier
+	}
+
+	s.mu.Lock()
+	s.byHash, s.byID, s.tiers, s.loaded = byHash, byID, tierMap, true
+	s.mu.Unlock()
+	return nil
+}
+
+// Lookup finds the key matching a secret presented by a client.
+func (s *Store) Lookup(secret string) (Key, bool) {
+	hash := hashSecret(secret)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.byHash[hash]
+	return key, ok
+}
+
+// Quota resolves the limits of key: its own overrides on top of its tier.
+// An override also limits a key on an unlimited tier; a value it leaves
+// unset there defaults to the one it sets. A key whose tier does not exist,
+// e.g. after a manual edit in Mongo, falls back to the anonymous quota.
+func (s *Store) Quota(key Key, cfg config.RateLimitConfig) Quota {
+	s.mu.RLock()
+	tier, ok := s.tiers[key.Tier]
+	s.mu.RUnlock()
+
+	quota := Anonymous(cfg)
+	if ok {
+		quota = Quota{
+			Name:              tier.Name,
+			RequestsPerMinute: tier.RequestsPerMinute,
+			Burst:             tier.Burst,
+			Unlimited:         tier.Unlimited,
+		}
+	}
+	if key.RequestsPerMinute <= 0 && key.Burst <= 0 {
+		return quota
+	}
+
+	if quota.Unlimited {
+		quota = Quota{Name: quota.Name, RequestsPerMinute: key.Burst, Burst: key.RequestsPerMinute}
+	}
+	if key.RequestsPerMinute > 0 {
+		quota.RequestsPerMinute = key.RequestsPerMinute
+	}
+	if key.Burst > 0 {
+		quota.Burst = key.Burst
+	}
+	return quota
+}
+
+// Keys returns every key sorted by creation time.
+func (s *Store) Keys() []Key {
+	s.mu.RLock()
+	keys := make([]Key, 0, len(s.byID))
+	for _, key := range s.byID {
+		keys = append(keys, key)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(keys, func(a, b Key) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return keys
+}
+
+// Key returns one key by ID.
+func (s *Store) Key(id string) (Key, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.byID[id]
+	return key, ok
+}
+
+// Tiers returns every tier sorted by name.
+func (s *Store) Tiers() []Tier {
+	s.mu.RLock()
+	tiers := make([]Tier, 0, len(s.tiers))
+	for _, tier := range s.tiers {
+		tiers = append(tiers, tier)
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(tiers, func(a, b Tier) int { return strings.Compare(a.Name, b.Name) })
+	return tiers
+}
+
+// RecordUse counts a request made with key id; limited marks a 429.
+func (s *Store) RecordUse(id string, limited bool) {
+	value, _ := s.usage.LoadOrStore(id, &usage{})
+	u := value.(*usage)
+	u.requests.Add(1)
+	if limited {
+		u.limited.Add(1)
+	}
+	u.lastUsed.Store(time.Now().UnixNano())
+}
+
+// Usage returns the counters recorded for key id.
+func (s *Store) Usage(id string) Usage {
+	value, ok := s.usage.Load(id)
+	if !ok {
+		return Usage{}
+	}
+	u := value.(*usage)
+	result := Usage{Requests: u.requests.Load(), Limited: u.limited.Load()}
+	if nanos := u.lastUsed.Load(); nanos > 0 {
+		lastUsed := time.Unix(0, nanos).UTC()
+		result.LastUsedAt = &lastUsed
+	}
+	return result
+}
+
+func (s *Store) database() (*mongo.Database, error) {
+	client := s.app.MongoClient()
+	if client == nil {
+		return nil, errors.New("mongo client not initialised")
+	}
+	return client.Database(s.app.DatabaseName()), nil
+}
+
+// ensureIndexes creates the unique index on key hashes once per process.
+func (s *Store) ensureIndexes(ctx context.Context, db *mongo.Database) {
+	if s.indexed.Load() {
+		return
+	}
+	_, err := db.Collection(KeysCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "hash", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("hash_unique"),
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "apikeys: failed to create key index", "error", err)
+		return
+	}
+	s.indexed.Store(true)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
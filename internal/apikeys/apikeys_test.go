@@ -0,0 +1,33 @@
+package apikeys
+
+import (
+	"testing"
+
+	"ss-api/internal/config"
+)
+
+func TestQuotaAppliesKeyOverrides(t *testing.T) {
+	s := &Store{tiers: map[string]Tier{
+		"partner":  {Name: "partner", RequestsPerMinute: 600, Burst: 100},
+		"internal": {Name: "internal", Unlimited: true},
+	}}
+	cfg := config.RateLimitConfig{RequestsPerMinute: 60, Burst: 30}
+
+	for _, tc := range []struct {
+		name string
+		key  Key
+		want Quota
+	}{
+		{"tier", Key{Tier: "partner"}, Quota{Name: "partner", RequestsPerMinute: 600, Burst: 100}},
+		{"override on tier", Key{Tier: "partner", RequestsPerMinute: 120}, Quota{Name: "partner", RequestsPerMinute: 120, Burst: 100}},
+		{"unlimited", Key{Tier: "internal"}, Quota{Name: "internal", Unlimited: true}},
+		{"both on unlimited", Key{Tier: "internal", RequestsPerMinute: 300, Burst: 50}, Quota{Name: "internal", RequestsPerMinute: 300, Burst: 50}},
+		{"rpm on unlimited", Key{Tier: "internal", RequestsPerMinute: 300}, Quota{Name: "internal", RequestsPerMinute: 300, Burst: 300}},
+		{"burst on unlimited", Key{Tier: "internal", Burst: 20}, Quota{Name: "internal", RequestsPerMinute: 20, Burst: 20}},
+		{"unknown tier", Key{Tier: "gone"}, Quota{Name: "anonymous", RequestsPerMinute: 60, Burst: 30}},
+	} {
+		if got := s.Quota(tc.key, cfg); got != tc.want {
+			t.Errorf("%s: Quota = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
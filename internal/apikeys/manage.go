@@ -0,0 +1,272 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// secretPrefix marks issued keys so they are easy to spot in leaked config
+// files and bug reports.
+const secretPrefix = "stk_"
+
+// prefixLength is how much of a secret Key.Prefix keeps.
+const prefixLength = len(secretPrefix) + 6
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrTierInUse  = errors.New("tier is assigned to keys")
+	ErrInvalid    = errors.New("invalid")
+	tierNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+)
+
+// invalidError carries a message for the client and matches ErrInvalid.
+type invalidError struct{ msg string }
+
+func (e invalidError) Error() string        { return e.msg }
+func (e invalidError) Is(target error) bool { return target == ErrInvalid }
+
+func invalidf(format string, args ...any) error {
+	return invalidError{msg: fmt.Sprintf(format, args...)}
+}
+
+// KeySpec describes a key to create.
+type KeySpec struct {
+	Name              string `json:"name"`
+	Tier              string `json:"tier"`
+	RequestsPerMinute int    `json:"requestsPerMinute,omitempty"`
+	Burst             int    `json:"burst,omitempty"`
+}
+
+// KeyUpdate changes the fields that are set and leaves the others alone.
+type KeyUpdate struct {
+	Name              *string `json:"name"`
+	Tier              *string `json:"tier"`
+	RequestsPerMinute *int    `json:"requestsPerMinute"`
+	Burst             *int    `json:"burst"`
+	Disabled          *bool   `json:"disabled"`
+}
+
+// CreateKey issues a new key and returns it with its secret. The secret is
+// not stored and cannot be shown again.
+func (s *Store) CreateKey(ctx context.Context, spec KeySpec) (Key, string, error) {
+	spec.Name = strings.TrimSpace(spec.Name)
+	spec.Tier = strings.TrimSpace(spec.Tier)
+	if err := s.validateKey(spec.Name, spec.Tier, spec.RequestsPerMinute, spec.Burst); err != nil {
+		return Key{}, "", err
+	}
+	db, err := s.database()
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return Key{}, "", err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	now := time.Now().UTC()
+	key := Key{
+		ID:                id,
+		Name:              spec.Name,
+		Tier:              spec.Tier,
+		Hash:              hashSecret(secret),
+		Prefix:            secret[:prefixLength],
+		RequestsPerMinute: spec.RequestsPerMinute,
+		Burst:             spec.Burst,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if _, err := db.Collection(KeysCollection).InsertOne(ctx, key); err != nil {
+		return Key{}, "", fmt.Errorf("insert key: %w", err)
+	}
+	s.reload(ctx)
+	return key, secret, nil
+}
+
+// UpdateKey applies update to key id.
+func (s *Store) UpdateKey(ctx context.Context, id string, update KeyUpdate) (Key, error) {
+	key, ok := s.Key(id)
+	if !ok {
+		return Key{}, ErrNotFound
+	}
+
+	if update.Name != nil {
+		key.Name = strings.TrimSpace(*update.Name)
+	}
+	if update.Tier != nil {
+		key.Tier = strings.TrimSpace(*update.Tier)
+	}
+	if update.RequestsPerMinute != nil {
+		key.RequestsPerMinute = *update.RequestsPerMinute
+	}
+	if update.Burst != nil {
+		key.Burst = *update.Burst
+	}
+	if update.Disabled != nil {
+		key.Disabled = *update.Disabled
+	}
+	if err := s.validateKey(key.Name, key.Tier, key.RequestsPerMinute, key.Burst); err != nil {
+		return Key{}, err
+	}
+	key.UpdatedAt = time.Now().UTC()
+
+	return key, s.replaceKey(ctx, key)
+}
+
+// RotateKey issues a new secret for key id. The old secret stops working
+// immediately on this instance and after the next reload on others.
+func (s *Store) RotateKey(ctx context.Context, id string) (Key, string, error) {
+	key, ok := s.Key(id)
+	if !ok {
+		return Key{}, "", ErrNotFound
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return Key{}, "", err
+	}
+
+	key.Hash = hashSecret(secret)
+	key.Prefix = secret[:prefixLength]
+	key.UpdatedAt = time.Now().UTC()
+	if err := s.replaceKey(ctx, key); err != nil {
+		return Key{}, "", err
+	}
+	return key, secret, nil
+}
+
+// DeleteKey revokes key id.
+func (s *Store) DeleteKey(ctx context.Context, id string) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	result, err := db.Collection(KeysCollection).DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("delete key: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	s.usage.Delete(id)
+	s.reload(ctx)
+	return nil
+}
+
+// PutTier creates or replaces a tier.
+func (s *Store) PutTier(ctx context.Context, tier Tier) (Tier, error) {
+	tier.Name = strings.TrimSpace(tier.Name)
+	if !tierNameRegex.MatchString(tier.Name) {
+		return Tier{}, invalidf("tier name must be 1-32 lower-case letters, digits, '-' or '_', got %q", tier.Name)
+	}
+	if !tier.Unlimited && (tier.RequestsPerMinute < 1 || tier.Burst < 1) {
+		return Tier{}, invalidf("requestsPerMinute and burst must be at least 1 unless the tier is unlimited")
+	}
+	db, err := s.database()
+	if err != nil {
+		return Tier{}, err
+	}
+
+	tier.UpdatedAt = time.Now().UTC()
+	_, err = db.Collection(TiersCollection).ReplaceOne(ctx, bson.M{"_id": tier.Name}, tier, options.Replace().SetUpsert(true))
+	if err != nil {
+		return Tier{}, fmt.Errorf("save tier: %w", err)
+	}
+	s.reload(ctx)
+	return tier, nil
+}
+
+// DeleteTier removes a tier that no key is assigned to.
+func (s *Store) DeleteTier(ctx context.Context, name string) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	inUse, err := db.Collection(KeysCollection).CountDocuments(ctx, bson.M{"tier": name})
+	if err != nil {
+		return fmt.Errorf("count keys: %w", err)
+	}
+	if inUse > 0 {
+		return fmt.Errorf("%w: %d key(s) use tier %q", ErrTierInUse, inUse, name)
+	}
+
+	result, err := db.Collection(TiersCollection).DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return fmt.Errorf("delete tier: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	s.reload(ctx)
+	return nil
+}
+
+func (s *Store) replaceKey(ctx context.Context, key Key) error {
+	db, err := s.database()
+	if err != nil {
+		return err
+	}
+	result, err := db.Collection(KeysCollection).ReplaceOne(ctx, bson.M{"_id": key.ID}, key)
+	if err != nil {
+		return fmt.Errorf("save key: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	s.reload(ctx)
+	return nil
+}
+
+// reload refreshes the in-memory copy after a write. The write already
+// succeeded, so a failure only delays it until the next scheduled reload.
+func (s *Store) reload(ctx context.Context) {
+	if err := s.Load(ctx); err != nil {
+		slog.WarnContext(ctx, "apikeys: reload after write failed", "error", err)
+	}
+}
+
+func (s *Store) validateKey(name, tier string, requestsPerMinute, burst int) error {
+	if name == "" || len(name) > 100 {
+		return invalidf("name must be 1-100 characters")
+	}
+	s.mu.RLock()
+	_, known := s.tiers[tier]
+	s.mu.RUnlock()
+	if !known {
+		return invalidf("unknown tier %q; create it under /stella/admin/tiers first", tier)
+	}
+	if requestsPerMinute < 0 || burst < 0 {
+		return invalidf("requestsPerMinute and burst must not be negative")
+	}
+	return nil
+}
+
+func newSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate key: %w", err)
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate key id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
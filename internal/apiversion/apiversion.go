@@ -0,0 +1,148 @@
+// Package apiversion lists the API versions served under /stella/{version}/,
+// what changed in each, and the Deprecation and Sunset headers sent for
+// versions that are being retired.
+package apiversion
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Default is the version unversioned paths such as /stella/characters
+// resolve to.
+const Default = "v1"
+
+// Statuses of a version.
+const (
+	// Stable versions only gain fields; existing fields keep their shape.
+	Stable = "stable"
+	// Preview versions may still change shape before they become stable.
+	Preview = "preview"
+)
+
+// Change is one entry of a version's changelog.
+type Change struct {
+	Routes      []string `json:"routes,omitempty"`
+	Description string   `json:"description"`
+	// Breaking marks changes existing clients had to adapt to. Since
+	// versioning, they are only made in a new version.
+	Breaking bool `json:"breaking,omitempty"`
+}
+
+// Version is an API version and its changelog, oldest change first.
+type Version struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Changes []Change `json:"changes"`
+}
+
+// All lists the versions, oldest first. A new version is only added
+// together with the handler changes that make it differ, so every listed
+// version serves its own payloads.
+var All = []Version{
+	{
+		Name:   "v1",
+		Status: Stable,
+		Changes: []Change{
+			{
+				Routes:      []string{"/characters", "/discs"},
+				Description: "List entries include an icon asset path.",
+			},
+			{
+				Routes:      []string{"/character/{identifier}", "/disc/{identifier}"},
+				Description: "Details flatten textures into root-level icon, portrait, background and variants asset paths.",
+			},
+			{
+				Routes:      []string{"/banners"},
+				Description: "Banners without an end date are grouped under permanent and flagged with permanent: true.",
+			},
+			{
+				Routes:      []string{"/news/{category}"},
+				Description: "Pages report count, total, pages and hasNext, and accept before, from and to; nextCursor continues a before scroll.",
+			},
+			{
+				Routes:      []string{"/changelog", "/character/{identifier}/history"},
+				Description: "Catalog changelog: added and removed IDs and changed fields per data update, and the history of one character.",
+			},
+			{
+				Routes:      []string{"/characters", "/discs", "/banners", "/events", "/export"},
+				Description: "Lists answer format=csv or ndjson, or Accept: text/csv or application/x-ndjson; /export bundles a region's catalog as a zip of JSON and CSV files.",
+			},
+			{
+				Description: "Every error answers with the {\"error\": {\"code\", \"message\", \"details\", \"requestId\"}} envelope.",
+				Breaking:    true,
+			},
+			{
+				Description: "An unsupported lang answers 400 unsupported_lang instead of falling back to EN; Accept-Language is negotiated when lang is absent and Content-Language names the region served.",
+				Breaking:    true,
+			},
+		},
+	},
+}
+
+// Names returns the version names, oldest first.
+func Names() []string {
+	names := make([]string, len(All))
+	for i, v := range All {
+		names[i] = v.Name
+	}
+	return names
+}
+
+// Lookup finds a version by name.
+func Lookup(name string) (Version, bool) {
+	for _, v := range All {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return Version{}, false
+}
+
+// Index returns the position of a version in All, or -1.
+func Index(name string) int {
+	for i, v := range All {
+		if v.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Lifecycle is the retirement schedule of a version. Zero times mean
+// nothing is scheduled.
+type Lifecycle struct {
+	Deprecated time.Time `json:"deprecated,omitzero"`
+	Sunset     time.Time `json:"sunset,omitzero"`
+	// Link points at the migration guide.
+	Link string `json:"link,omitempty"`
+}
+
+// SetHeaders announces the schedule: Deprecation as a structured date
+// (RFC 9745), Sunset as an HTTP date (RFC 8594) and the guide as a Link
+// with rel="deprecation".
+func (l Lifecycle) SetHeaders(header http.Header) {
+	if !l.Deprecated.IsZero() {
+		header.Set("Deprecation", "@"+strconv.FormatInt(l.Deprecated.Unix(), 10))
+		if l.Link != "" {
+			header.Add("Link", "<"+l.Link+`>; rel="deprecation"`)
+		}
+	}
+	if !l.Sunset.IsZero() {
+		header.Set("Sunset", l.Sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
+// ParseDate reads a date as YYYY-MM-DD (midnight UTC) or RFC 3339.
+func ParseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected YYYY-MM-DD or RFC 3339, got %q", value)
+	}
+	return t, nil
+}
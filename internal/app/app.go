@@ -3,52 +3,82 @@ package app
 import (
 	"context"
 	"net/http"
+	"slices"
 	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"ss-api/internal/alias"
+	"ss-api/internal/config"
+	"ss-api/internal/metrics"
 )
 
-type Config struct {
-	MongoURI      string
-	MongoDatabase string
-}
+var mongoCommandDuration = metrics.NewHistogramVec(
+	"stella_mongo_command_duration_seconds",
+	"Duration of Mongo commands by command name and result.",
+	nil,
+	"command", "result",
+)
+
+// Jobs that are triggered from outside the package that registers them.
+const (
+	// CatalogReloadJob reloads the character name map used for aliases.
+	CatalogReloadJob = "catalog-reload"
+	// AssetRebuildJob rebuilds the friendly asset alias table.
+	AssetRebuildJob = "asset-cache-rebuild"
+)
 
 type App struct {
-	config      Config
+	configMu    sync.RWMutex
+	config      config.Config
+	loader      func() (config.Config, error)
+	reloadMu    sync.Mutex
+	onReload    []func(old, next config.Config)
 	httpServer  *http.Server
 	mongoClient *mongo.Client
 	initOnce    sync.Once
 	startTime   time.Time
 	endpoints   []string
+	scheduler   *Scheduler
+	caches      cacheRegistry
+	watcher     *catalogWatcher
 }
 
-func New(cfg Config) *App {
-	return &App{
-		config:    cfg,
+// New builds the app from a resolved configuration. The assets directory is
+// made absolute so later working directory changes do not affect it.
+func New(cfg config.Config) *App {
+	a := &App{
+		config:    normalizeConfig(cfg),
 		startTime: time.Now(),
-		endpoints: []string{
-			"/stella/",
-			"/stella/assets/{friendlyName}",
-			"/stella/characters",
-			"/stella/character/{idOrName}",
-			"/stella/discs",
-			"/stella/disc/{idOrName}",
-			"/stella/banners",
-			"/stella/events",
-			"/stella/news/updates",
-			"/stella/news/notices",
-			"/stella/news/news",
-			"/stella/news/events",
-			"/news/updates",
-			"/news/notices",
-			"/news/news",
-			"/news/events",
-		},
+		scheduler: NewScheduler(),
 	}
+
+	a.watcher = &catalogWatcher{app: a}
+
+	_ = a.scheduler.Register(Job{
+		Name:     CatalogReloadJob,
+		Interval: time.Hour,
+		Timeout:  30 * time.Second,
+		Run: func(ctx context.Context) error {
+			return alias.LoadCharacterNames(ctx, a.mongoClient, a.DatabaseName())
+		},
+	})
+
+	a.OnCatalogChange(func(change CatalogChange) {
+		if change.Collection == "characters" {
+			_ = a.scheduler.Trigger(CatalogReloadJob)
+		}
+	})
+	a.OnReload(func(old, next config.Config) {
+		if old.Watch.PollInterval != next.Watch.PollInterval {
+			_ = a.scheduler.Reschedule(catalogPollJobName, next.Watch.PollInterval)
+		}
+	})
+
+	return a
 }
 
 func (a *App) Start(ctx context.Context, handler http.Handler, addr string) error {
@@ -56,14 +86,27 @@ func (a *App) Start(ctx context.Context, handler http.Handler, addr string) erro
 		return err
 	}
 
+	a.scheduler.Start(ctx)
+	a.watcher.start(ctx)
+
+	cfg := a.Config()
 	a.httpServer = &http.Server{
-		Addr:    addr,
-		Handler: handler,
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
 	}
 
 	return a.httpServer.ListenAndServe()
 }
 
+// Connect connects to Mongo without serving, for command-line tools such
+// as stella-validate. Shutdown disconnects again.
+func (a *App) Connect(ctx context.Context) error {
+	return a.initMongo(ctx)
+}
+
 func (a *App) Shutdown(ctx context.Context) error {
 	if a.httpServer != nil {
 		if err := a.httpServer.Shutdown(ctx); err != nil {
@@ -71,6 +114,14 @@ func (a *App) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	if err := a.watcher.stop(ctx); err != nil {
+		return err
+	}
+
+	if err := a.scheduler.Stop(ctx); err != nil {
+		return err
+	}
+
 	if a.mongoClient != nil {
 		return a.mongoClient.Disconnect(ctx)
 	}
@@ -82,27 +133,60 @@ func (a *App) MongoClient() *mongo.Client {
 	return a.mongoClient
 }
 
+// Scheduler returns the scheduler that runs all background jobs.
+func (a *App) Scheduler() *Scheduler {
+	return a.scheduler
+}
+
 func (a *App) StartTime() time.Time {
 	return a.startTime
 }
 
+// Config returns the current configuration. Reloadable settings may differ
+// between calls, so read it where the value is used rather than caching it.
+func (a *App) Config() config.Config {
+	a.configMu.RLock()
+	defer a.configMu.RUnlock()
+	return a.config
+}
+
 func (a *App) DatabaseName() string {
-	return a.config.MongoDatabase
+	return a.Config().Mongo.Database
+}
+
+// AssetsDir returns the absolute path of the directory served under
+// /stella/assets/.
+func (a *App) AssetsDir() string {
+	return a.Config().Assets.Dir
+}
+
+// SetEndpoints records the paths listed by the status route. The HTTP
+// server sets them from its route registry before it starts serving.
+func (a *App) SetEndpoints(paths []string) {
+	a.endpoints = slices.Clone(paths)
 }
 
 func (a *App) Endpoints() []string {
-	result := make([]string, len(a.endpoints))
-	copy(result, a.endpoints)
-	return result
+	return slices.Clone(a.endpoints)
 }
 
 func (a *App) initMongo(ctx context.Context) error {
 	var err error
 	a.initOnce.Do(func() {
-		clientCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		mongoCfg := a.Config().Mongo
+
+		clientCtx, cancel := context.WithTimeout(ctx, mongoCfg.ConnectTimeout)
 		defer cancel()
 
-		client, connectErr := mongo.Connect(clientCtx, options.Client().ApplyURI(a.config.MongoURI))
+		clientOpts := options.Client().
+			ApplyURI(mongoCfg.URI).
+			SetConnectTimeout(mongoCfg.ConnectTimeout).
+			SetMaxPoolSize(uint64(mongoCfg.MaxPoolSize)).
+			SetMinPoolSize(uint64(mongoCfg.MinPoolSize)).
+			SetMaxConnIdleTime(mongoCfg.MaxConnIdleTime).
+			SetMonitor(commandMonitor())
+
+		client, connectErr := mongo.Connect(clientCtx, clientOpts)
 		if connectErr != nil {
 			err = connectErr
 			return
@@ -116,8 +200,21 @@ func (a *App) initMongo(ctx context.Context) error {
 
 		a.mongoClient = client
 
-		alias.InitCharacterNamesFromDB(client, a.config.MongoDatabase)
+		alias.InitCharacterNamesFromDB(client, mongoCfg.Database)
 	})
 
 	return err
 }
+
+// commandMonitor records the duration of every Mongo command the driver
+// issues, including cursor getMore round trips.
+func commandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, evt *event.CommandSucceededEvent) {
+			mongoCommandDuration.WithLabelValues(evt.CommandName, "success").Observe(evt.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, evt *event.CommandFailedEvent) {
+			mongoCommandDuration.WithLabelValues(evt.CommandName, "error").Observe(evt.Duration.Seconds())
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"sort"
+	"sync"
+)
+
+// Cache is implemented by in-memory caches that report their size in the
+// status payload.
+type Cache interface {
+	Len() int
+}
+
+type cacheRegistry struct {
+	mu     sync.RWMutex
+	caches map[string]Cache
+}
+
+// RegisterCache makes a cache visible to diagnostics under the given name.
+// Registering a name twice replaces the earlier cache.
+func (a *App) RegisterCache(name string, cache Cache) {
+	if name == "" || cache == nil {
+		return
+	}
+
+	a.caches.mu.Lock()
+	defer a.caches.mu.Unlock()
+
+	if a.caches.caches == nil {
+		a.caches.caches = make(map[string]Cache)
+	}
+	a.caches.caches[name] = cache
+}
+
+// CacheSizes returns the number of entries held by each registered cache.
+func (a *App) CacheSizes() map[string]int {
+	a.caches.mu.RLock()
+	defer a.caches.mu.RUnlock()
+
+	sizes := make(map[string]int, len(a.caches.caches))
+	for name, cache := range a.caches.caches {
+		sizes[name] = cache.Len()
+	}
+	return sizes
+}
+
+// CacheNames lists the registered caches in sorted order.
+func (a *App) CacheNames() []string {
+	a.caches.mu.RLock()
+	defer a.caches.mu.RUnlock()
+
+	names := make([]string, 0, len(a.caches.caches))
+	for name := range a.caches.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,433 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ss-api/internal/metrics"
+)
+
+// CatalogCollections are the region-keyed collections served by the API.
+var CatalogCollections = []string{"characters", "discs", "gacha", "events"}
+
+const (
+	catalogPollJobName = "catalog-poll"
+
+	// changeStreamsUnsupported is the server error for $changeStream on a
+	// standalone deployment.
+	changeStreamsUnsupported = 40573
+	// unauthorized is the server error when the user may not open a change
+	// stream.
+	unauthorized = 13
+
+	maxWatchBackoff = time.Minute
+)
+
+// resumeLost lists the server errors after which a stream cannot continue
+// from its resume token: InvalidResumeToken, ChangeStreamFatalError and
+// ChangeStreamHistoryLost.
+var resumeLost = []int{260, 280, 286}
+
+// Watch modes reported by CatalogWatchMode.
+const (
+	WatchOff          = "off"
+	WatchStarting     = "starting"
+	WatchChangeStream = "change_stream"
+	WatchPoll         = "poll"
+)
+
+var catalogChanges = metrics.NewCounterVec(
+	"stella_catalog_changes_total",
+	"Catalog changes detected in Mongo by collection and source (change_stream or poll).",
+	"collection", "source",
+)
+
+// CatalogChange reports that documents of a catalog collection changed.
+type CatalogChange struct {
+	Collection string
+	// Regions lists the affected regions in upper case. It is empty when
+	// the region could not be determined, e.g. for deletes, and every
+	// region must be treated as changed.
+	Regions []string
+}
+
+// OnCatalogChange registers fn to run after Mongo catalog data changed.
+// Changes are debounced by watch.debounce, so a bulk import arrives as one
+// call per collection.
+func (a *App) OnCatalogChange(fn func(CatalogChange)) {
+	if fn == nil {
+		return
+	}
+	a.watcher.mu.Lock()
+	a.watcher.subscribers = append(a.watcher.subscribers, fn)
+	a.watcher.mu.Unlock()
+}
+
+// CatalogWatchMode reports how catalog changes are currently detected.
+func (a *App) CatalogWatchMode() string {
+	a.watcher.mu.Lock()
+	defer a.watcher.mu.Unlock()
+	if a.watcher.mode == "" {
+		return WatchOff
+	}
+	return a.watcher.mode
+}
+
+// catalogWatcher turns Mongo change events, or differences between polled
+// fingerprints, into debounced CatalogChange notifications.
+type catalogWatcher struct {
+	app *App
+
+	mu          sync.Mutex
+	mode        string
+	subscribers []func(CatalogChange)
+	pending     map[string]map[string]struct{} // collection → regions; empty means all
+	flushTimer  *time.Timer
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// fingerprints is only touched by the poll job, which never overlaps
+	// with itself.
+	fingerprints map[string]map[string]regionFingerprint
+}
+
+type regionFingerprint struct {
+	Region    string        `bson:"_id"`
+	UpdatedAt bson.RawValue `bson:"updatedAt"`
+	Version   bson.RawValue `bson:"version"`
+	Count     int64         `bson:"count"`
+}
+
+func (f regionFingerprint) equal(other regionFingerprint) bool {
+	return f.Count == other.Count && f.UpdatedAt.Equal(other.UpdatedAt) && f.Version.Equal(other.Version)
+}
+
+func (w *catalogWatcher) start(ctx context.Context) {
+	mode := w.app.Config().Watch.Mode
+	if mode == WatchOff {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	w.mu.Lock()
+	w.cancel = cancel
+	w.mu.Unlock()
+
+	if mode == WatchPoll {
+		w.startPolling()
+		return
+	}
+
+	w.setMode(WatchStarting)
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.runChangeStream(ctx, mode == "auto")
+	}()
+}
+
+func (w *catalogWatcher) stop(ctx context.Context) error {
+	w.mu.Lock()
+	cancel := w.cancel
+	if w.flushTimer != nil {
+		w.flushTimer.Stop()
+	}
+	w.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *catalogWatcher) setMode(mode string) {
+	w.mu.Lock()
+	w.mode = mode
+	w.mu.Unlock()
+}
+
+// runChangeStream watches the catalog collections until ctx ends, resuming
+// after transient errors. A resume token the server no longer accepts is
+// dropped and every collection reported as changed, since events may have
+// been missed. When change streams cannot run at all, because the deployment
+// does not support them or the user may not open them, it switches to
+// polling so changes are still detected.
+func (w *catalogWatcher) runChangeStream(ctx context.Context, fallback bool) {
+	var resumeToken bson.Raw
+	backoff := time.Second
+
+	for {
+		err := w.watchOnce(ctx, &resumeToken)
+		if ctx.Err() != nil {
+			return
+		}
+
+		var serverErr mongo.ServerError
+		if errors.As(err, &serverErr) {
+			if serverErr.HasErrorCode(changeStreamsUnsupported) || serverErr.HasErrorCode(unauthorized) {
+				interval := w.app.Config().Watch.PollInterval.String()
+				if fallback {
+					slog.Info("catalog watch: change streams unavailable, polling instead", "error", err, "interval", interval)
+				} else {
+					slog.Error("catalog watch: watch.mode is change_stream but change streams are unavailable, polling instead", "error", err, "interval", interval)
+				}
+				w.startPolling()
+				return
+			}
+
+			if resumeToken != nil && (serverErr.HasErrorLabel("NonResumableChangeStreamError") || slices.ContainsFunc(resumeLost, serverErr.HasErrorCode)) {
+				slog.Warn("catalog watch: cannot resume change stream, restarting from now", "error", err)
+				resumeToken = nil
+				for _, name := range CatalogCollections {
+					w.record(name, "", WatchChangeStream)
+				}
+				backoff = time.Second
+				continue
+			}
+		}
+
+		if err != nil {
+			slog.Warn("catalog watch: change stream failed, retrying", "error", err, "retry_in", backoff.String())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxWatchBackoff)
+	}
+}
+
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	Namespace     struct {
+		Collection string `bson:"coll"`
+	} `bson:"ns"`
+	FullDocument struct {
+		Region string `bson:"region"`
+	} `bson:"fullDocument"`
+}
+
+func (w *catalogWatcher) watchOnce(ctx context.Context, resumeToken *bson.Raw) error {
+	client := w.app.MongoClient()
+	if client == nil {
+		return errors.New("mongo client not initialised")
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.D{
+		{Key: "ns.coll", Value: bson.D{{Key: "$in", Value: CatalogCollections}}},
+	}}}}
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if *resumeToken != nil {
+		opts.SetResumeAfter(*resumeToken)
+	}
+
+	stream, err := client.Database(w.app.DatabaseName()).Watch(ctx, pipeline, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.WithoutCancel(ctx))
+
+	w.setMode(WatchChangeStream)
+
+	for stream.Next(ctx) {
+		var event changeEvent
+		if err := stream.Decode(&event); err != nil {
+			slog.Warn("catalog watch: undecodable change event", "error", err)
+			continue
+		}
+		*resumeToken = stream.ResumeToken()
+
+		switch event.OperationType {
+		case "insert", "update", "replace", "delete":
+			w.record(event.Namespace.Collection, event.FullDocument.Region, WatchChangeStream)
+		case "drop", "rename":
+			w.record(event.Namespace.Collection, "", WatchChangeStream)
+		case "dropDatabase", "invalidate":
+			for _, name := range CatalogCollections {
+				w.record(name, "", WatchChangeStream)
+			}
+			// An invalidated stream cannot be resumed.
+			*resumeToken = nil
+		}
+	}
+
+	return stream.Err()
+}
+
+// startPolling registers the poll job. The first run only records the
+// baseline; later runs report every region whose fingerprint moved.
+func (w *catalogWatcher) startPolling() {
+	w.setMode(WatchPoll)
+
+	err := w.app.scheduler.Register(Job{
+		Name:       catalogPollJobName,
+		Interval:   w.app.Config().Watch.PollInterval,
+		RunOnStart: true,
+		Timeout:    30 * time.Second,
+		Run:        w.poll,
+	})
+	if err != nil && !errors.Is(err, ErrDuplicateJob) {
+		slog.Error("catalog watch: failed to schedule polling", "error", err)
+	}
+}
+
+func (w *catalogWatcher) poll(ctx context.Context) error {
+	client := w.app.MongoClient()
+	if client == nil {
+		return errors.New("mongo client not initialised")
+	}
+
+	db := client.Database(w.app.DatabaseName())
+	first := w.fingerprints == nil
+	if first {
+		w.fingerprints = make(map[string]map[string]regionFingerprint, len(CatalogCollections))
+	}
+
+	var errs []error
+	for _, name := range CatalogCollections {
+		current, err := fingerprintCollection(ctx, db.Collection(name))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		previous, known := w.fingerprints[name]
+		w.fingerprints[name] = current
+		if first || !known {
+			continue
+		}
+
+		for region, fp := range current {
+			if old, ok := previous[region]; !ok || !old.equal(fp) {
+				w.record(name, region, WatchPoll)
+			}
+		}
+		for region := range previous {
+			if _, ok := current[region]; !ok {
+				w.record(name, region, WatchPoll)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// fingerprintCollection summarises each region by its newest updatedAt, its
+// highest version and its document count. Writers should bump updatedAt or
+// version so in-place edits are noticed.
+func fingerprintCollection(ctx context.Context, collection *mongo.Collection) (map[string]regionFingerprint, error) {
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: "$region"},
+		{Key: "updatedAt", Value: bson.D{{Key: "$max", Value: "$updatedAt"}}},
+		{Key: "version", Value: bson.D{{Key: "$max", Value: "$version"}}},
+		{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+	}}}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	result := make(map[string]regionFingerprint)
+	for cursor.Next(ctx) {
+		var fp regionFingerprint
+		if err := cursor.Decode(&fp); err != nil {
+			return nil, err
+		}
+		result[fp.Region] = fp
+	}
+	return result, cursor.Err()
+}
+
+// record queues a change and schedules a flush after the debounce delay.
+func (w *catalogWatcher) record(collection, region, source string) {
+	if collection == "" {
+		return
+	}
+	catalogChanges.WithLabelValues(collection, source).Inc()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pending == nil {
+		w.pending = make(map[string]map[string]struct{})
+	}
+	regions, ok := w.pending[collection]
+	switch {
+	case !ok:
+		regions = make(map[string]struct{})
+		if region != "" {
+			regions[strings.ToUpper(region)] = struct{}{}
+		}
+		w.pending[collection] = regions
+	case len(regions) == 0:
+		// Already invalidating every region.
+	case region == "":
+		w.pending[collection] = map[string]struct{}{}
+	default:
+		regions[strings.ToUpper(region)] = struct{}{}
+	}
+
+	if w.flushTimer == nil {
+		w.flushTimer = time.AfterFunc(w.app.Config().Watch.Debounce, w.flush)
+	}
+}
+
+func (w *catalogWatcher) flush() {
+	w.mu.Lock()
+	pending := w.pending
+	subscribers := slices.Clone(w.subscribers)
+	w.pending = nil
+	w.flushTimer = nil
+	w.mu.Unlock()
+
+	collections := make([]string, 0, len(pending))
+	for name := range pending {
+		collections = append(collections, name)
+	}
+	sort.Strings(collections)
+
+	for _, name := range collections {
+		change := CatalogChange{Collection: name}
+		for region := range pending[name] {
+			change.Regions = append(change.Regions, region)
+		}
+		sort.Strings(change.Regions)
+
+		regions := "all"
+		if len(change.Regions) > 0 {
+			regions = strings.Join(change.Regions, ",")
+		}
+		slog.Info("catalog changed", "collection", name, "regions", regions)
+
+		for _, fn := range subscribers {
+			fn(change)
+		}
+	}
+}
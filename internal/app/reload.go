@@ -0,0 +1,96 @@
+package app
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+
+	"ss-api/internal/config"
+)
+
+// ErrNoConfigLoader is returned by Reload when no loader was installed.
+var ErrNoConfigLoader = errors.New("config reload is not configured")
+
+// SetConfigLoader installs the function Reload uses to read the
+// configuration again, normally config.Resolve with the process arguments.
+func (a *App) SetConfigLoader(loader func() (config.Config, error)) {
+	a.reloadMu.Lock()
+	a.loader = loader
+	a.reloadMu.Unlock()
+}
+
+// OnReload registers fn to run after a reload changed at least one setting.
+// Subscribers run in registration order with the previous and the new
+// configuration.
+func (a *App) OnReload(fn func(old, next config.Config)) {
+	if fn == nil {
+		return
+	}
+	a.reloadMu.Lock()
+	a.onReload = append(a.onReload, fn)
+	a.reloadMu.Unlock()
+}
+
+// Reload reads the configuration through the installed loader and applies
+// it. See ApplyConfig.
+func (a *App) Reload() ([]config.Change, error) {
+	a.reloadMu.Lock()
+	loader := a.loader
+	a.reloadMu.Unlock()
+
+	if loader == nil {
+		return nil, ErrNoConfigLoader
+	}
+
+	next, err := loader()
+	if err != nil {
+		return nil, err
+	}
+	return a.ApplyConfig(next)
+}
+
+// ApplyConfig swaps in next when every changed setting is reloadable. When a
+// restart-only setting changed nothing is applied and the error is a
+// *config.RestartRequiredError naming those settings. Open connections and
+// running jobs are left untouched either way.
+func (a *App) ApplyConfig(next config.Config) ([]config.Change, error) {
+	next = normalizeConfig(next)
+
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	old := a.Config()
+	changes := config.Changes(old, next)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	if paths := config.RestartRequired(changes); len(paths) > 0 {
+		return changes, &config.RestartRequiredError{Paths: paths}
+	}
+
+	a.configMu.Lock()
+	a.config = next
+	a.configMu.Unlock()
+
+	for _, fn := range a.onReload {
+		fn(old, next)
+	}
+
+	paths := make([]string, 0, len(changes))
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	slog.Info("config reloaded", "changed", paths)
+
+	return changes, nil
+}
+
+// normalizeConfig makes the assets directory absolute so later working
+// directory changes do not affect it and reloads compare like with like.
+func normalizeConfig(cfg config.Config) config.Config {
+	if abs, err := filepath.Abs(cfg.Assets.Dir); err == nil {
+		cfg.Assets.Dir = abs
+	}
+	return cfg
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"ss-api/internal/config"
+)
+
+func TestApplyConfig(t *testing.T) {
+	a := New(config.Default())
+	var calls int
+	a.OnReload(func(old, next config.Config) {
+		calls++
+		if old.Cache.CharacterTTL == next.Cache.CharacterTTL {
+			t.Error("subscriber got the same TTL twice")
+		}
+	})
+
+	next := a.Config()
+	next.Cache.CharacterTTL = time.Hour
+	changes, err := a.ApplyConfig(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "cache.character_ttl" {
+		t.Errorf("changes = %+v, want cache.character_ttl", changes)
+	}
+	if a.Config().Cache.CharacterTTL != time.Hour || calls != 1 {
+		t.Errorf("ttl = %s after %d calls, want 1h after 1", a.Config().Cache.CharacterTTL, calls)
+	}
+
+	if changes, err := a.ApplyConfig(a.Config()); err != nil || changes != nil || calls != 1 {
+		t.Errorf("unchanged reload = %+v, %v after %d calls; want nothing", changes, err, calls)
+	}
+}
+
+func TestApplyConfigRejectsRestartOnlySettings(t *testing.T) {
+	a := New(config.Default())
+	a.OnReload(func(old, next config.Config) { t.Error("subscriber ran for a rejected reload") })
+
+	next := a.Config()
+	next.Server.Addr = ":9000"
+	next.Cache.CharacterTTL = time.Hour
+	_, err := a.ApplyConfig(next)
+
+	var restart *config.RestartRequiredError
+	if !errors.As(err, &restart) || len(restart.Paths) != 1 || restart.Paths[0] != "server.addr" {
+		t.Fatalf("error = %v, want a restart required for server.addr", err)
+	}
+	if a.Config().Cache.CharacterTTL == time.Hour {
+		t.Error("the reloadable part of a rejected reload was applied")
+	}
+}
+
+func TestReload(t *testing.T) {
+	a := New(config.Default())
+	if _, err := a.Reload(); !errors.Is(err, ErrNoConfigLoader) {
+		t.Errorf("reload without a loader = %v, want ErrNoConfigLoader", err)
+	}
+
+	loadErr := errors.New("broken config")
+	a.SetConfigLoader(func() (config.Config, error) { return config.Config{}, loadErr })
+	if _, err := a.Reload(); !errors.Is(err, loadErr) {
+		t.Errorf("reload with a failing loader = %v", err)
+	}
+
+	a.SetConfigLoader(func() (config.Config, error) {
+		cfg := config.Default()
+		cfg.Log.Level = "debug"
+		return cfg, nil
+	})
+	if _, err := a.Reload(); err != nil || a.Config().Log.Level != "debug" {
+		t.Errorf("reload = %v, log level %q; want debug", err, a.Config().Log.Level)
+	}
+}
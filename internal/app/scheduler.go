@@ -0,0 +1,403 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"ss-api/internal/metrics"
+)
+
+var (
+	ErrUnknownJob   = errors.New("unknown job")
+	ErrDuplicateJob = errors.New("job already registered")
+
+	jobRuns = metrics.NewCounterVec(
+		"stella_job_runs_total",
+		"Background job runs by job name and result.",
+		"job", "result",
+	)
+	jobDuration = metrics.NewHistogramVec(
+		"stella_job_duration_seconds",
+		"Background job run duration by job name.",
+		[]float64{0.1, 0.5, 1, 5, 10, 30, 60, 300},
+		"job",
+	)
+)
+
+// Job describes a named unit of background work.
+type Job struct {
+	Name string
+	// Interval between runs. Zero means the job only runs when triggered.
+	Interval time.Duration
+	// Align schedules runs on multiples of Interval (e.g. :00 and :30 for a
+	// 30 minute interval) instead of relative to the scheduler start.
+	Align bool
+	// RunOnStart runs the job once as soon as the scheduler starts.
+	RunOnStart bool
+	// Timeout bounds a single run. Zero leaves the run unbounded.
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// JobStatus is a snapshot of a job's schedule and last outcome.
+type JobStatus struct {
+	Name         string     `json:"name"`
+	Interval     string     `json:"interval,omitempty"`
+	Running      bool       `json:"running"`
+	Runs         int        `json:"runs"`
+	Failures     int        `json:"failures"`
+	LastRun      *time.Time `json:"lastRun,omitempty"`
+	LastDuration float64    `json:"lastDurationMs,omitempty"`
+	LastError    string     `json:"lastError,omitempty"`
+	LastSuccess  *time.Time `json:"lastSuccess,omitempty"`
+	NextRun      *time.Time `json:"nextRun,omitempty"`
+}
+
+// Scheduler runs registered jobs on their intervals. Each job has its own
+// goroutine, so a slow job never delays the others, and a job never overlaps
+// with itself.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    map[string]*scheduledJob
+	order   []string
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started bool
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+type scheduledJob struct {
+	job        Job
+	trigger    chan struct{}
+	reschedule chan struct{}
+	runMu      sync.Mutex
+
+	mu     sync.Mutex
+	status JobStatus
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		jobs: make(map[string]*scheduledJob),
+	}
+}
+
+// Register adds a job. Jobs registered after Start begin running immediately.
+func (s *Scheduler) Register(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return errors.New("job requires a name and a run function")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateJob, job.Name)
+	}
+
+	sj := &scheduledJob{
+		job:        job,
+		trigger:    make(chan struct{}, 1),
+		reschedule: make(chan struct{}, 1),
+		status:     JobStatus{Name: job.Name},
+	}
+	if job.Interval > 0 {
+		sj.status.Interval = job.Interval.String()
+	}
+
+	s.jobs[job.Name] = sj
+	s.order = append(s.order, job.Name)
+
+	if s.started && !s.stopped {
+		s.launch(sj)
+	}
+
+	return nil
+}
+
+// Start launches every registered job. It is a no-op when already started.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	s.started = true
+
+	for _, name := range s.order {
+		s.launch(s.jobs[name])
+	}
+}
+
+// Stop cancels running jobs and waits for them to return or for ctx to end.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started || s.stopped {
+		s.stopped = true
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
+	s.cancel()
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("scheduler: jobs still running: %w", ctx.Err())
+	}
+}
+
+// Trigger asks a job to run as soon as possible without waiting for it. A
+// trigger while the job is already pending is coalesced.
+func (s *Scheduler) Trigger(name string) error {
+	sj, err := s.job(name)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case sj.trigger <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Reschedule changes a job's interval. The next run is recomputed from now;
+// a run in progress is not interrupted. Zero turns the job into a
+// trigger-only job.
+func (s *Scheduler) Reschedule(name string, interval time.Duration) error {
+	sj, err := s.job(name)
+	if err != nil {
+		return err
+	}
+
+	sj.mu.Lock()
+	if sj.job.Interval == interval {
+		sj.mu.Unlock()
+		return nil
+	}
+	sj.job.Interval = interval
+	sj.status.Interval = ""
+	sj.status.NextRun = nil
+	if interval > 0 {
+		sj.status.Interval = interval.String()
+	}
+	sj.mu.Unlock()
+
+	select {
+	case sj.reschedule <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run executes a job synchronously and returns its error. It waits for an
+// in-flight run of the same job to finish first.
+func (s *Scheduler) Run(ctx context.Context, name string) error {
+	sj, err := s.job(name)
+	if err != nil {
+		return err
+	}
+
+	return sj.execute(ctx)
+}
+
+// Jobs reports the status of every job in registration order.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	jobs := make([]*scheduledJob, 0, len(s.order))
+	for _, name := range s.order {
+		jobs = append(jobs, s.jobs[name])
+	}
+	s.mu.Unlock()
+
+	result := make([]JobStatus, 0, len(jobs))
+	for _, sj := range jobs {
+		result = append(result, sj.snapshot())
+	}
+	return result
+}
+
+// Job reports the status of a single job.
+func (s *Scheduler) Job(name string) (JobStatus, error) {
+	sj, err := s.job(name)
+	if err != nil {
+		return JobStatus{}, err
+	}
+	return sj.snapshot(), nil
+}
+
+func (s *Scheduler) job(name string) (*scheduledJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sj, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	return sj, nil
+}
+
+// launch must be called with s.mu held.
+func (s *Scheduler) launch(sj *scheduledJob) {
+	ctx := s.ctx
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		sj.loop(ctx)
+	}()
+}
+
+func (sj *scheduledJob) loop(ctx context.Context) {
+	var timer *time.Timer
+	var timerC <-chan time.Time
+
+	schedule := func(now time.Time) {
+		interval := sj.interval()
+		if interval <= 0 {
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+		next := nextRun(now, interval, sj.job.Align)
+		sj.setNextRun(next)
+		if timer == nil {
+			timer = time.NewTimer(next.Sub(now))
+			timerC = timer.C
+		} else {
+			timer.Reset(next.Sub(now))
+		}
+	}
+
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	if sj.job.RunOnStart {
+		_ = sj.execute(ctx)
+	}
+	schedule(time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timerC:
+			_ = sj.execute(ctx)
+			schedule(time.Now())
+		case <-sj.trigger:
+			_ = sj.execute(ctx)
+		case <-sj.reschedule:
+			schedule(time.Now())
+		}
+	}
+}
+
+func (sj *scheduledJob) execute(ctx context.Context) error {
+	sj.runMu.Lock()
+	defer sj.runMu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	runCtx := ctx
+	if sj.job.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, sj.job.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	sj.mu.Lock()
+	sj.status.Running = true
+	sj.mu.Unlock()
+
+	err := runJob(runCtx, sj.job)
+	elapsed := time.Since(start)
+
+	sj.mu.Lock()
+	sj.status.Running = false
+	sj.status.Runs++
+	sj.status.LastRun = &start
+	sj.status.LastDuration = float64(elapsed) / float64(time.Millisecond)
+	if err != nil {
+		sj.status.Failures++
+		sj.status.LastError = err.Error()
+	} else {
+		sj.status.LastError = ""
+		finished := start.Add(elapsed)
+		sj.status.LastSuccess = &finished
+	}
+	sj.mu.Unlock()
+
+	result := "success"
+	if err != nil {
+		result = "error"
+		slog.Error("scheduler: job failed", "job", sj.job.Name, "duration", elapsed.Round(time.Millisecond).String(), "error", err)
+	}
+	jobRuns.WithLabelValues(sj.job.Name, result).Inc()
+	jobDuration.WithLabelValues(sj.job.Name).Observe(elapsed.Seconds())
+
+	return err
+}
+
+func runJob(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+func (sj *scheduledJob) interval() time.Duration {
+	sj.mu.Lock()
+	defer sj.mu.Unlock()
+	return sj.job.Interval
+}
+
+func (sj *scheduledJob) setNextRun(next time.Time) {
+	sj.mu.Lock()
+	sj.status.NextRun = &next
+	sj.mu.Unlock()
+}
+
+func (sj *scheduledJob) snapshot() JobStatus {
+	sj.mu.Lock()
+	defer sj.mu.Unlock()
+	return sj.status
+}
+
+func nextRun(now time.Time, interval time.Duration, align bool) time.Time {
+	if !align {
+		return now.Add(interval)
+	}
+
+	next := now.Truncate(interval)
+	if !next.After(now) {
+		next = next.Add(interval)
+	}
+	return next
+}
@@ -0,0 +1,77 @@
+// Package buildinfo reports the version of the running binary. Version,
+// Commit and BuildTime can be stamped at link time:
+//
+//	go build -ldflags "-X ss-api/internal/buildinfo.Version=v1.4.0 -X ss-api/internal/buildinfo.Commit=$(git rev-parse HEAD)"
+//
+// Values that are not stamped fall back to the VCS metadata the Go toolchain
+// embeds in the binary.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	Version   = ""
+	Commit    = ""
+	BuildTime = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"buildTime,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build information, resolving it on first use.
+func Get() Info {
+	once.Do(func() {
+		info = Info{
+			Version:   Version,
+			Commit:    Commit,
+			BuildTime: BuildTime,
+			GoVersion: runtime.Version(),
+		}
+
+		build, ok := debug.ReadBuildInfo()
+		if !ok {
+			if info.Version == "" {
+				info.Version = "dev"
+			}
+			return
+		}
+
+		if info.Version == "" {
+			info.Version = build.Main.Version
+		}
+		if info.Version == "" || info.Version == "(devel)" {
+			info.Version = "dev"
+		}
+
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	})
+
+	return info
+}
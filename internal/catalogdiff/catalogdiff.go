@@ -0,0 +1,174 @@
+// Package catalogdiff compares two versions of the entries of one catalog
+// collection and region: which IDs were added or removed, and which fields
+// of the remaining entries changed.
+package catalogdiff
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Change lists the fields that differ between two versions of one entry.
+type Change struct {
+	ID int64 `bson:"id" json:"id"`
+	// Fields are dotted paths such as "skill.params.2" or "stats". An
+	// array whose length changed is reported as a whole.
+	Fields []string `bson:"fields" json:"fields"`
+}
+
+// Diff is the difference between two versions of a region's entries.
+// Entries without a numeric ID are not compared.
+type Diff struct {
+	Added     []int64  `bson:"added" json:"added"`
+	Removed   []int64  `bson:"removed" json:"removed"`
+	Changed   []Change `bson:"changed" json:"changed"`
+	Unchanged int      `bson:"unchanged" json:"unchanged"`
+}
+
+// Empty reports whether no entry was added, removed or changed.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Entries compares two versions of a region's entries by entry ID. Added
+// and removed IDs are sorted; changes follow the order of after.
+func Entries(before, after []bson.Raw) Diff {
+	previous := map[int64]bson.Raw{}
+	for _, entry := range before {
+		if id, ok := EntryID(entry); ok {
+			if _, seen := previous[id]; !seen {
+				previous[id] = entry
+			}
+		}
+	}
+
+	diff := Diff{Added: []int64{}, Removed: []int64{}, Changed: []Change{}}
+	seen := map[int64]bool{}
+	for _, entry := range after {
+		id, ok := EntryID(entry)
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		old, existed := previous[id]
+		if !existed {
+			diff.Added = append(diff.Added, id)
+			continue
+		}
+		if fields := Fields(old, entry); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, Change{ID: id, Fields: fields})
+		} else {
+			diff.Unchanged++
+		}
+	}
+	for id := range previous {
+		if !seen[id] {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+
+	slices.Sort(diff.Added)
+	slices.Sort(diff.Removed)
+	return diff
+}
+
+// EntryID returns the numeric id of an entry. Whole doubles count, since
+// JSON imports and shell scripts store them that way.
+func EntryID(entry bson.Raw) (int64, bool) {
+	if entry == nil {
+		return 0, false
+	}
+	value := entry.Lookup("id")
+	switch value.Type {
+	case bsontype.Int32:
+		return int64(value.Int32()), true
+	case bsontype.Int64:
+		return value.Int64(), true
+	case bsontype.Double:
+		f := value.Double()
+		return int64(f), f == math.Trunc(f)
+	}
+	return 0, false
+}
+
+// Fields returns the dotted paths of the fields that differ between two
+// documents, in the order they appear in after, then the removed ones.
+func Fields(before, after bson.Raw) []string {
+	return compareDocuments(before, after, "")
+}
+
+func compareDocuments(before, after bson.Raw, prefix string) []string {
+	oldElements, _ := before.Elements()
+	newElements, _ := after.Elements()
+
+	var fields []string
+	known := map[string]bool{}
+	for _, elem := range newElements {
+		known[elem.Key()] = true
+		fields = append(fields, compareValues(before.Lookup(elem.Key()), elem.Value(), join(prefix, elem.Key()))...)
+	}
+	for _, elem := range oldElements {
+		if !known[elem.Key()] {
+			fields = append(fields, join(prefix, elem.Key()))
+		}
+	}
+	return fields
+}
+
+func compareValues(before, after bson.RawValue, path string) []string {
+	if before.Type == bsontype.EmbeddedDocument && after.Type == bsontype.EmbeddedDocument {
+		return compareDocuments(before.Document(), after.Document(), path)
+	}
+	if before.Type == bsontype.Array && after.Type == bsontype.Array {
+		oldValues, _ := before.Array().Values()
+		newValues, _ := after.Array().Values()
+		if len(oldValues) != len(newValues) {
+			return []string{path}
+		}
+		var fields []string
+		for i := range newValues {
+			fields = append(fields, compareValues(oldValues[i], newValues[i], join(path, strconv.Itoa(i)))...)
+		}
+		return fields
+	}
+	if equal(before, after) {
+		return nil
+	}
+	return []string{path}
+}
+
+// equal compares scalars, treating numbers of different BSON types as
+// equal when their values are.
+func equal(a, b bson.RawValue) bool {
+	if a.Type == b.Type {
+		return bytes.Equal(a.Value, b.Value)
+	}
+	x, okA := number(a)
+	y, okB := number(b)
+	return okA && okB && x == y
+}
+
+func number(value bson.RawValue) (float64, bool) {
+	switch value.Type {
+	case bsontype.Int32:
+		return float64(value.Int32()), true
+	case bsontype.Int64:
+		return float64(value.Int64()), true
+	case bsontype.Double:
+		return value.Double(), true
+	}
+	return 0, false
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
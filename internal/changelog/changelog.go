@@ -0,0 +1,328 @@
+// Package changelog records what changed in the catalog. After every
+// catalog change, and hourly as a safety net, it compares each region's
+// entries with the snapshot taken last time and stores the difference:
+// the IDs added and removed, and the fields that changed with their old
+// and new values. GET /stella/changelog and
+// GET /stella/character/{identifier}/history read the records.
+package changelog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"ss-api/internal/app"
+	"ss-api/internal/catalogdiff"
+	"ss-api/internal/model"
+	"ss-api/internal/validate"
+)
+
+// Mongo collections holding the last snapshot of every region and the
+// changes found between snapshots.
+const (
+	SnapshotsCollection = "catalog_snapshots"
+	ChangesCollection   = "catalog_changes"
+)
+
+// JobName is the job that takes snapshots and records changes.
+const JobName = "catalog-changelog"
+
+// Actions, as reported in EntryChange.Action.
+const (
+	ActionAdded   = "added"
+	ActionRemoved = "removed"
+	ActionChanged = "changed"
+)
+
+// Change is what changed in one collection and region between two
+// snapshots.
+type Change struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	Collection string             `bson:"collection" json:"type"`
+	Region     string             `bson:"region" json:"region"`
+	DetectedAt time.Time          `bson:"detectedAt" json:"detectedAt"`
+	Added      []Entry            `bson:"added" json:"added"`
+	Removed    []Entry            `bson:"removed" json:"removed"`
+	Changed    []Entry            `bson:"changed" json:"changed"`
+}
+
+// Entry is an added, removed or changed entry. Fields is only set for
+// changed entries.
+type Entry struct {
+	ID     int64         `bson:"id" json:"id"`
+	Name   string        `bson:"name,omitempty" json:"name,omitempty"`
+	Fields []FieldChange `bson:"fields,omitempty" json:"fields,omitempty"`
+}
+
+// FieldChange is one changed field of an entry. Before is missing when the
+// field was added, After when it was removed.
+type FieldChange struct {
+	// Field is a dotted path such as "skill.params.2". An array whose
+	// length changed is reported as a whole.
+	Field  string        `bson:"field" json:"field"`
+	Before bson.RawValue `bson:"before,omitempty" json:"-"`
+	After  bson.RawValue `bson:"after,omitempty" json:"-"`
+}
+
+// MarshalJSON writes the field with its values in stored key order; a
+// missing value is null.
+func (f FieldChange) MarshalJSON() ([]byte, error) {
+	before, err := jsonValue(f.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := jsonValue(f.After)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(model.Document{
+		{Key: "field", Value: f.Field},
+		{Key: "before", Value: before},
+		{Key: "after", Value: after},
+	})
+}
+
+// jsonValue decodes a stored value through model.Document so nested
+// objects keep their key order.
+func jsonValue(value bson.RawValue) (any, error) {
+	if value.IsZero() {
+		return nil, nil
+	}
+	wrapped, err := bson.Marshal(bson.D{{Key: "v", Value: value}})
+	if err != nil {
+		return nil, err
+	}
+	var doc model.Document
+	if err := bson.Unmarshal(wrapped, &doc); err != nil {
+		return nil, err
+	}
+	v, _ := doc.Lookup("v")
+	return v, nil
+}
+
+type snapshot struct {
+	ID         string     `bson:"_id"`
+	Collection string     `bson:"collection"`
+	Region     string     `bson:"region"`
+	Entries    []bson.Raw `bson:"entries"`
+	TakenAt    time.Time  `bson:"takenAt"`
+}
+
+func snapshotID(collection, region string) string {
+	return collection + "/" + region
+}
+
+// snapshotFilter matches s only while nobody has replaced it since it was
+// read.
+func snapshotFilter(s snapshot) bson.D {
+	return bson.D{{Key: "_id", Value: s.ID}, {Key: "takenAt", Value: s.TakenAt}}
+}
+
+// Recorder takes the snapshots.
+type Recorder struct {
+	app     *app.App
+	indexed atomic.Bool
+}
+
+// Register schedules the recorder: once at start, after every catalog
+// change and hourly.
+func Register(appInstance *app.App) *Recorder {
+	r := &Recorder{app: appInstance}
+
+	err := appInstance.Scheduler().Register(app.Job{
+		Name:       JobName,
+		Interval:   time.Hour,
+		RunOnStart: true,
+		Timeout:    2 * time.Minute,
+		Run:        r.Record,
+	})
+	if err != nil {
+		slog.Error("changelog: failed to schedule snapshots", "error", err)
+	}
+	appInstance.OnCatalogChange(func(app.CatalogChange) {
+		_ = appInstance.Scheduler().Trigger(JobName)
+	})
+
+	return r
+}
+
+// Record compares every region with its snapshot, stores the changes and
+// moves the snapshots forward. A region seen for the first time only gets
+// a snapshot; a region that disappeared counts as all entries removed.
+//
+// Every instance runs Record, so a snapshot is only moved forward if it
+// still has the takenAt that was read, and only the instance that moved it
+// stores the change.
+func (r *Recorder) Record(ctx context.Context) error {
+	client := r.app.MongoClient()
+	if client == nil {
+		return errors.New("mongo client not initialised")
+	}
+	db := client.Database(r.app.DatabaseName())
+	r.ensureIndexes(ctx, db)
+
+	regions, _, err := validate.Load(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	snapshots := map[string]snapshot{}
+	cursor, err := db.Collection(SnapshotsCollection).Find(ctx, bson.D{})
+	if err != nil {
+		return err
+	}
+	var stored []snapshot
+	if err := cursor.All(ctx, &stored); err != nil {
+		return err
+	}
+	for _, s := range stored {
+		snapshots[s.ID] = s
+	}
+
+	now := time.Now().UTC()
+	snapshotsColl := db.Collection(SnapshotsCollection)
+	var errs []error
+	for _, region := range regions {
+		id := snapshotID(region.Collection, region.Region)
+		previous, known := snapshots[id]
+		delete(snapshots, id)
+
+		s := snapshot{ID: id, Collection: region.Collection, Region: region.Region, Entries: region.Entries, TakenAt: now}
+		if !known {
+			_, err := snapshotsColl.InsertOne(ctx, s)
+			if err != nil && !mongo.IsDuplicateKeyError(err) {
+				errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			}
+			continue
+		}
+
+		diff := catalogdiff.Entries(previous.Entries, region.Entries)
+		if diff.Empty() {
+			continue
+		}
+		res, err := snapshotsColl.ReplaceOne(ctx, snapshotFilter(previous), s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		if res.MatchedCount == 0 {
+			// Another instance recorded this change.
+			continue
+		}
+		change := newChange(region.Collection, region.Region, now, previous.Entries, region.Entries, diff)
+		if _, err := db.Collection(ChangesCollection).InsertOne(ctx, change); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		slog.Info("catalog changelog recorded", "collection", region.Collection, "region", region.Region,
+			"added", len(change.Added), "removed", len(change.Removed), "changed", len(change.Changed))
+	}
+
+	for id, previous := range snapshots {
+		res, err := snapshotsColl.DeleteOne(ctx, snapshotFilter(previous))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+			continue
+		}
+		diff := catalogdiff.Entries(previous.Entries, nil)
+		if res.DeletedCount == 0 || diff.Empty() {
+			continue
+		}
+		change := newChange(previous.Collection, previous.Region, now, previous.Entries, nil, diff)
+		if _, err := db.Collection(ChangesCollection).InsertOne(ctx, change); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", id, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *Recorder) ensureIndexes(ctx context.Context, db *mongo.Database) {
+	if r.indexed.Load() {
+		return
+	}
+	_, err := db.Collection(ChangesCollection).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "region", Value: 1}, {Key: "detectedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "collection", Value: 1}, {Key: "region", Value: 1}, {Key: "detectedAt", Value: -1}}},
+	})
+	if err != nil {
+		slog.Warn("changelog: failed to create indexes", "error", err)
+		return
+	}
+	r.indexed.Store(true)
+}
+
+// newChange turns a diff into a change record, looking up names and field
+// values in the two versions.
+func newChange(collection, region string, at time.Time, before, after []bson.Raw, diff catalogdiff.Diff) Change {
+	oldByID := byID(before)
+	newByID := byID(after)
+	nameField := nameFieldOf(collection)
+
+	change := Change{
+		ID:         primitive.NewObjectID(),
+		Collection: collection,
+		Region:     region,
+		DetectedAt: at,
+		Added:      []Entry{},
+		Removed:    []Entry{},
+		Changed:    []Entry{},
+	}
+	for _, id := range diff.Added {
+		change.Added = append(change.Added, Entry{ID: id, Name: entryName(newByID[id], nameField)})
+	}
+	for _, id := range diff.Removed {
+		change.Removed = append(change.Removed, Entry{ID: id, Name: entryName(oldByID[id], nameField)})
+	}
+	for _, c := range diff.Changed {
+		old, current := oldByID[c.ID], newByID[c.ID]
+		entry := Entry{ID: c.ID, Name: entryName(current, nameField)}
+		for _, field := range c.Fields {
+			path := strings.Split(field, ".")
+			entry.Fields = append(entry.Fields, FieldChange{
+				Field:  field,
+				Before: old.Lookup(path...),
+				After:  current.Lookup(path...),
+			})
+		}
+		change.Changed = append(change.Changed, entry)
+	}
+	return change
+}
+
+func byID(entries []bson.Raw) map[int64]bson.Raw {
+	result := make(map[int64]bson.Raw, len(entries))
+	for _, entry := range entries {
+		if id, ok := catalogdiff.EntryID(entry); ok {
+			if _, seen := result[id]; !seen {
+				result[id] = entry
+			}
+		}
+	}
+	return result
+}
+
+// nameFieldOf returns the field naming an entry; events have a title.
+func nameFieldOf(collection string) string {
+	if collection == "events" {
+		return "title"
+	}
+	return "name"
+}
+
+func entryName(entry bson.Raw, field string) string {
+	if entry == nil {
+		return ""
+	}
+	name, _ := entry.Lookup(field).StringValueOK()
+	return name
+}
@@ -0,0 +1,161 @@
+package changelog
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ss-api/internal/catalogdiff"
+)
+
+// Query selects changes. Zero fields do not filter.
+type Query struct {
+	Region      string
+	Collections []string
+	// Since keeps changes detected at or after it.
+	Since time.Time
+	Limit int
+}
+
+// Changes returns the changes matching q, newest first.
+func Changes(ctx context.Context, db *mongo.Database, q Query) ([]Change, error) {
+	filter := bson.D{}
+	if q.Region != "" {
+		filter = append(filter, bson.E{Key: "region", Value: q.Region})
+	}
+	if len(q.Collections) > 0 {
+		filter = append(filter, bson.E{Key: "collection", Value: bson.D{{Key: "$in", Value: q.Collections}}})
+	}
+	if !q.Since.IsZero() {
+		filter = append(filter, bson.E{Key: "detectedAt", Value: bson.D{{Key: "$gte", Value: q.Since}}})
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "detectedAt", Value: -1}, {Key: "_id", Value: -1}})
+	if q.Limit > 0 {
+		opts.SetLimit(int64(q.Limit))
+	}
+	cursor, err := db.Collection(ChangesCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	changes := []Change{}
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// EntryChange is one change of a single entry.
+type EntryChange struct {
+	ChangeID   primitive.ObjectID `json:"changeId"`
+	DetectedAt time.Time          `json:"detectedAt"`
+	// Action is added, removed or changed.
+	Action string        `json:"action"`
+	Name   string        `json:"name,omitempty"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// History returns the changes of one entry, newest first.
+func History(ctx context.Context, db *mongo.Database, collection, region string, id int64, limit int) ([]EntryChange, error) {
+	filter := bson.D{
+		{Key: "collection", Value: collection},
+		{Key: "region", Value: region},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "added.id", Value: id}},
+			bson.D{{Key: "removed.id", Value: id}},
+			bson.D{{Key: "changed.id", Value: id}},
+		}},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "detectedAt", Value: -1}, {Key: "_id", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cursor, err := db.Collection(ChangesCollection).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	history := []EntryChange{}
+	for _, change := range changes {
+		for _, group := range []struct {
+			action  string
+			entries []Entry
+		}{
+			{ActionAdded, change.Added},
+			{ActionRemoved, change.Removed},
+			{ActionChanged, change.Changed},
+		} {
+			for _, entry := range group.entries {
+				if entry.ID != id {
+					continue
+				}
+				history = append(history, EntryChange{
+					ChangeID:   change.ID,
+					DetectedAt: change.DetectedAt,
+					Action:     group.action,
+					Name:       entry.Name,
+					Fields:     entry.Fields,
+				})
+			}
+		}
+	}
+	return history, nil
+}
+
+// Resolve finds the ID of an entry by name in the latest snapshot of a
+// region, then in recorded changes so removed entries are found too.
+func Resolve(ctx context.Context, db *mongo.Database, collection, region, name string) (int64, bool, error) {
+	var s snapshot
+	err := db.Collection(SnapshotsCollection).FindOne(ctx, bson.D{{Key: "_id", Value: snapshotID(collection, region)}}).Decode(&s)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, false, err
+	}
+	field := nameFieldOf(collection)
+	for _, entry := range s.Entries {
+		if strings.EqualFold(strings.TrimSpace(entryName(entry, field)), name) {
+			if id, ok := catalogdiff.EntryID(entry); ok {
+				return id, true, nil
+			}
+		}
+	}
+
+	pattern := primitive.Regex{Pattern: "^" + regexp.QuoteMeta(name) + "$", Options: "i"}
+	filter := bson.D{
+		{Key: "collection", Value: collection},
+		{Key: "region", Value: region},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "added.name", Value: pattern}},
+			bson.D{{Key: "removed.name", Value: pattern}},
+			bson.D{{Key: "changed.name", Value: pattern}},
+		}},
+	}
+	var change Change
+	err = db.Collection(ChangesCollection).FindOne(ctx, filter,
+		options.FindOne().SetSort(bson.D{{Key: "detectedAt", Value: -1}, {Key: "_id", Value: -1}}),
+	).Decode(&change)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	for _, entries := range [][]Entry{change.Added, change.Removed, change.Changed} {
+		for _, entry := range entries {
+			if strings.EqualFold(strings.TrimSpace(entry.Name), name) {
+				return entry.ID, true, nil
+			}
+		}
+	}
+	return 0, false, nil
+}
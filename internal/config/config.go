@@ -3,50 +3,546 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"ss-api/internal/apiversion"
+	"ss-api/internal/locale"
 )
 
 const (
-	defaultServerAddr = ":8080"
-	defaultMongoURI   = "mongodb://localhost:27017"
-	defaultMongoDB    = "stella-sora"
+	defaultServerAddr        = ":8080"
+	defaultRequestTimeout    = 10 * time.Second
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultShutdownTimeout   = 15 * time.Second
+
+	defaultCompressionMinSize     = 1024
+	defaultCompressionGzipLevel   = 6
+	defaultCompressionBrotliLevel = 5
+
+	defaultCORSMaxAge = 10 * time.Minute
+
+	defaultRateLimitRequestsPerMinute = 60
+	defaultRateLimitBurst             = 30
+	defaultRateLimitKeyHeader         = "X-API-Key"
+	defaultRateLimitKeyRefresh        = time.Minute
+
+	defaultLocale = "EN"
+
+	defaultReferrerPolicy        = "no-referrer"
+	defaultContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+	defaultMongoURI            = "mongodb://localhost:27017"
+	defaultMongoDB             = "stella-sora"
+	defaultMongoConnectTimeout = 10 * time.Second
+	defaultMongoMaxPoolSize    = 100
+	defaultMongoMaxConnIdle    = 5 * time.Minute
+
+	defaultCharacterTTL = 30 * time.Minute
+	defaultCatalogTTL   = 30 * time.Minute
+	defaultScheduleTTL  = 5 * time.Minute
+	defaultMaxEntries   = 10000
+	defaultStaleTTL     = time.Hour
+	defaultThumbnailTTL = 10 * time.Minute
+	defaultStatusTTL    = 30 * time.Second
+
+	defaultAssetsDir             = "assets"
+	defaultAssetsRebuildInterval = time.Hour
+
+	defaultWatchMode         = "auto"
+	defaultWatchPollInterval = time.Minute
+	defaultWatchDebounce     = 2 * time.Second
+
+	defaultNewsSyncInterval   = 30 * time.Minute
+	defaultNewsConcurrency    = 4
+	defaultNewsRequestTimeout = 10 * time.Second
+	defaultNewsImageTimeout   = 30 * time.Second
+
+	defaultLogFormat = "text"
+	defaultLogLevel  = "info"
+	defaultLogColor  = "auto"
+	defaultLogOutput = "stdout"
+)
+
+var (
+	// DefaultNewsUpstreams maps each news region to the official site that
+	// serves its news API.
+	DefaultNewsUpstreams = map[string]string{
+		"global": "https://stellasora.global",
+		"jp":     "https://stellasora.jp",
+		"tw":     "https://stellasora.stargazer-games.com",
+		"cn":     "https://stellasora.yostar.cn",
+	}
+
+	// Already compressed formats gain nothing from another pass.
+	defaultCompressionSkipTypes = []string{"image/png", "image/jpeg", "image/webp", "image/gif", "application/zip"}
+
+	defaultCORSOrigins        = []string{"*"}
+	defaultCORSMethods        = []string{"GET", "HEAD", "OPTIONS"}
+	defaultCORSHeaders        = []string{"Accept", "Accept-Encoding", "Accept-Language", "Content-Type", "If-Modified-Since", "If-None-Match", "X-API-Key", "X-Request-ID"}
+	defaultCORSExposedHeaders = []string{"ETag", "Last-Modified", "X-Cache", "X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", "Deprecation", "Sunset", "Link"}
+
+	// Health checks, metrics scrapes and the assets a page embeds by the
+	// dozen are not what the limiter is for. Admin routes stay limited so
+	// bearer tokens cannot be guessed at full speed.
+	defaultRateLimitExemptPrefixes = []string{"/stella/healthz", "/stella/readyz", "/stella/assets/", "/metrics"}
+
+	// Traditional Chinese falls back to Simplified before English; every
+	// other region goes straight to English.
+	defaultLocaleFallbacks = map[string]string{"TW": "CN", "CN": "EN", "JP": "EN", "KR": "EN"}
+
+	defaultAccessSkipPrefixes     = []string{"/stella/assets/", "/assets/", "/metrics"}
+	defaultAccessNotFoundPrefixes = []string{"/stella"}
 )
 
+// Config is the full runtime configuration. Fields tagged reload:"true" can
+// change while the process runs; see Changes. Fields tagged secret:"true" are
+// masked by Redacted.
 type Config struct {
-	Server ServerConfig `yaml:"server"`
-	Mongo  MongoConfig  `yaml:"mongo"`
+	Server      ServerConfig      `yaml:"server"`
+	Compression CompressionConfig `yaml:"compression"`
+	CORS        CORSConfig        `yaml:"cors"`
+	Security    SecurityConfig    `yaml:"security"`
+	RateLimit   RateLimitConfig   `yaml:"ratelimit"`
+	Mongo       MongoConfig       `yaml:"mongo"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Locale      LocaleConfig      `yaml:"locale"`
+	Versions    VersionsConfig    `yaml:"versions"`
+	Assets      AssetsConfig      `yaml:"assets"`
+	News        NewsConfig        `yaml:"news"`
+	Watch       WatchConfig       `yaml:"watch"`
+	Admin       AdminConfig       `yaml:"admin"`
+	Log         LogConfig         `yaml:"log"`
 }
 
 type ServerConfig struct {
 	Addr string `yaml:"addr"`
+	// RequestTimeout bounds the Mongo work done for a single API request.
+	RequestTimeout    time.Duration `yaml:"request_timeout" reload:"true"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	// WriteTimeout of zero leaves response writes unbounded.
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// Metrics exposes GET /metrics.
+	Metrics bool `yaml:"metrics"`
+	// Docs serves a Redoc page for the OpenAPI document at /stella/docs.
+	Docs bool `yaml:"docs"`
+}
+
+// CompressionConfig controls gzip and Brotli response compression.
+type CompressionConfig struct {
+	// Enabled negotiates Accept-Encoding for every response.
+	Enabled bool `yaml:"enabled"`
+	// MinSize is the smallest body, in bytes, worth compressing.
+	MinSize int `yaml:"min_size" reload:"true"`
+	// GzipLevel is 1 (fastest) to 9 (smallest).
+	GzipLevel int `yaml:"gzip_level" reload:"true"`
+	// BrotliLevel is 0 (fastest) to 11 (smallest).
+	BrotliLevel int `yaml:"brotli_level" reload:"true"`
+	// SkipTypes lists media types sent as they are, such as PNG assets.
+	SkipTypes []string `yaml:"skip_types" reload:"true"`
+}
+
+// CORSConfig controls which browser origins may call the API.
+type CORSConfig struct {
+	// AllowedOrigins lists origins such as "https://stella.ennead.cc". A
+	// leading "*." in the host matches any subdomain and "*" matches every
+	// origin. An empty list disables CORS.
+	AllowedOrigins []string `yaml:"allowed_origins" reload:"true"`
+	AllowedMethods []string `yaml:"allowed_methods" reload:"true"`
+	AllowedHeaders []string `yaml:"allowed_headers" reload:"true"`
+	// ExposedHeaders are response headers scripts may read.
+	ExposedHeaders []string `yaml:"exposed_headers" reload:"true"`
+	// MaxAge is how long browsers may cache a preflight answer.
+	MaxAge time.Duration `yaml:"max_age" reload:"true"`
+	// AllowCredentials lets browsers send cookies and HTTP auth. It cannot
+	// be combined with the "*" origin.
+	AllowCredentials bool `yaml:"allow_credentials" reload:"true"`
+}
+
+// SecurityConfig controls the security headers sent with every response.
+type SecurityConfig struct {
+	// Headers adds X-Content-Type-Options, X-Frame-Options, Referrer-Policy
+	// and Content-Security-Policy.
+	Headers               bool   `yaml:"headers"`
+	ReferrerPolicy        string `yaml:"referrer_policy" reload:"true"`
+	ContentSecurityPolicy string `yaml:"content_security_policy" reload:"true"`
+	// HSTSMaxAge sends Strict-Transport-Security when positive. Only enable
+	// it when every client reaches the API over HTTPS.
+	HSTSMaxAge time.Duration `yaml:"hsts_max_age" reload:"true"`
+}
+
+// RateLimitConfig throttles the public API per client IP and per API key.
+// Keys and their tiers live in Mongo and are managed through the admin API.
+type RateLimitConfig struct {
+	// Enabled applies the limits. Off by default: behind a reverse proxy,
+	// set TrustedProxies first or every client shares the proxy's bucket.
+	Enabled bool `yaml:"enabled" reload:"true"`
+	// RequestsPerMinute and Burst size the token bucket of each client IP
+	// that sends no API key.
+	RequestsPerMinute int `yaml:"requests_per_minute" reload:"true"`
+	Burst             int `yaml:"burst" reload:"true"`
+	// KeyHeader is the request header that carries an API key.
+	KeyHeader string `yaml:"key_header" reload:"true"`
+	// TrustedProxies lists the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For header is believed.
+	TrustedProxies []string `yaml:"trusted_proxies" reload:"true"`
+	// ExemptPrefixes lists path prefixes that are never limited.
+	ExemptPrefixes []string `yaml:"exempt_prefixes" reload:"true"`
+	// KeyRefreshInterval is how often keys and tiers are re-read from Mongo
+	// to pick up changes made through other instances.
+	KeyRefreshInterval time.Duration `yaml:"key_refresh_interval" reload:"true"`
 }
 
 type MongoConfig struct {
-	URI      string `yaml:"uri"`
-	Database string `yaml:"database"`
+	URI string `yaml:"uri" secret:"true"`
+	// URIFile reads the URI from a file (e.g. a mounted secret) instead.
+	URIFile        string        `yaml:"uri_file"`
+	Database       string        `yaml:"database"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	MaxPoolSize    int           `yaml:"max_pool_size"`
+	MinPoolSize    int           `yaml:"min_pool_size"`
+	// MaxConnIdleTime closes pooled connections idle for longer than this.
+	MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time"`
+}
+
+type CacheConfig struct {
+	// CharacterTTL is how long character list and detail payloads are
+	// reused. The warmup job refreshes the list cache on the same interval.
+	CharacterTTL time.Duration `yaml:"character_ttl" reload:"true"`
+	// CatalogTTL is how long disc list and detail payloads are reused.
+	CatalogTTL time.Duration `yaml:"catalog_ttl" reload:"true"`
+	// ScheduleTTL is how long banner and event payloads are reused. Keep it
+	// short: banners move between current/upcoming/ended as time passes.
+	ScheduleTTL time.Duration `yaml:"schedule_ttl" reload:"true"`
+	// StaleTTL is how long an expired response keeps being served while a
+	// single background render refreshes it. Zero always waits for a fresh
+	// render.
+	StaleTTL time.Duration `yaml:"stale_ttl" reload:"true"`
+	// MaxEntries bounds the number of responses held in memory.
+	MaxEntries int `yaml:"max_entries"`
+	// ThumbnailTTL is how long resolved news hero images are reused.
+	ThumbnailTTL time.Duration `yaml:"thumbnail_ttl" reload:"true"`
+	// StatusTTL bounds how often the status endpoint queries Mongo.
+	StatusTTL time.Duration `yaml:"status_ttl" reload:"true"`
+	// Warmup enables the background job that prefills the character list.
+	Warmup bool `yaml:"warmup"`
+}
+
+// LocaleConfig controls how catalog routes pick a region when lang is
+// absent and which region they try next when one has no data.
+type LocaleConfig struct {
+	// Default is the region used when neither lang nor Accept-Language
+	// names a supported one.
+	Default string `yaml:"default" reload:"true"`
+	// AcceptLanguage negotiates the region from the Accept-Language header
+	// of requests without lang.
+	AcceptLanguage bool `yaml:"accept_language" reload:"true"`
+	// Fallbacks maps a region to the one tried when it has no data, e.g.
+	// TW: CN. Links are followed, so TW → CN → EN. An empty map disables
+	// fallback.
+	Fallbacks map[string]string `yaml:"fallbacks" reload:"true"`
+}
+
+// VersionsConfig schedules the retirement of API versions. Dates are
+// YYYY-MM-DD or RFC 3339.
+type VersionsConfig struct {
+	// Deprecated maps a version to the date it is deprecated, sent in the
+	// Deprecation header of its responses.
+	Deprecated map[string]string `yaml:"deprecated" reload:"true"`
+	// Sunset maps a version to the date it stops being served, sent in the
+	// Sunset header.
+	Sunset map[string]string `yaml:"sunset" reload:"true"`
+	// Link is the migration guide sent with deprecated versions as a Link
+	// with rel="deprecation".
+	Link string `yaml:"link" reload:"true"`
+}
+
+// Lifecycle returns the schedule of a version. Dates are validated on
+// load, so an unparsable one is simply left out.
+func (c VersionsConfig) Lifecycle(version string) apiversion.Lifecycle {
+	var l apiversion.Lifecycle
+	if value, ok := c.Deprecated[version]; ok {
+		l.Deprecated, _ = apiversion.ParseDate(value)
+	}
+	if value, ok := c.Sunset[version]; ok {
+		l.Sunset, _ = apiversion.ParseDate(value)
+	}
+	if !l.Deprecated.IsZero() || !l.Sunset.IsZero() {
+		l.Link = c.Link
+	}
+	return l
+}
+
+// Negotiator returns the locale negotiator for these settings. Values are
+// validated on load, so unknown regions are simply skipped.
+func (c LocaleConfig) Negotiator() locale.Negotiator {
+	n := locale.Negotiator{AcceptLanguage: c.AcceptLanguage, Fallbacks: make(map[locale.Locale]locale.Locale, len(c.Fallbacks))}
+	if l, ok := locale.Parse(c.Default); ok {
+		n.Default = l
+	}
+	for from, to := range c.Fallbacks {
+		fromLocale, okFrom := locale.Parse(from)
+		toLocale, okTo := locale.Parse(to)
+		if okFrom && okTo {
+			n.Fallbacks[fromLocale] = toLocale
+		}
+	}
+	return n
+}
+
+type AssetsConfig struct {
+	// Dir is the directory served under /stella/assets/.
+	Dir             string        `yaml:"dir"`
+	RebuildInterval time.Duration `yaml:"rebuild_interval" reload:"true"`
+}
+
+type NewsConfig struct {
+	// Sync enables the scheduled refresh of every news category.
+	Sync         bool          `yaml:"sync"`
+	SyncInterval time.Duration `yaml:"sync_interval" reload:"true"`
+	// Concurrency limits parallel upstream requests per sync or page.
+	Concurrency    int           `yaml:"concurrency" reload:"true"`
+	RequestTimeout time.Duration `yaml:"request_timeout" reload:"true"`
+	ImageTimeout   time.Duration `yaml:"image_timeout" reload:"true"`
+	// MirrorImages copies hero images into the assets directory.
+	MirrorImages bool `yaml:"mirror_images"`
+	// Upstreams overrides the base URL of individual news regions (global,
+	// jp, tw, cn). Regions left out keep their default.
+	Upstreams map[string]string `yaml:"upstreams" reload:"true"`
+}
+
+// WatchConfig controls how catalog changes in Mongo invalidate caches.
+type WatchConfig struct {
+	// Mode is "auto" (change streams when the deployment supports them,
+	// polling otherwise), "change_stream", "poll" or "off".
+	Mode string `yaml:"mode"`
+	// PollInterval is how often the polling fallback compares each
+	// collection's per-region updatedAt/version fingerprint.
+	PollInterval time.Duration `yaml:"poll_interval" reload:"true"`
+	// Debounce groups bursts of changes, such as a bulk import, into one
+	// invalidation.
+	Debounce time.Duration `yaml:"debounce" reload:"true"`
+}
+
+// AdminConfig protects the /stella/admin/ routes.
+type AdminConfig struct {
+	// Tokens are accepted as "Authorization: Bearer <token>". With no
+	// tokens the admin API is disabled.
+	Tokens []string `yaml:"tokens" reload:"true" secret:"true"`
+	// TokensFile reads the tokens from a file instead, one per line.
+	TokensFile string `yaml:"tokens_file" reload:"true"`
+}
+
+type LogConfig struct {
+	// Format is "text" (human readable, optionally colored access lines) or
+	// "json" (one slog JSON object per line).
+	Format string `yaml:"format"`
+	// Level is one of debug, info, warn or error.
+	Level string `yaml:"level" reload:"true"`
+	// Color is "auto" (only when the output is a terminal), "always" or
+	// "never". It only affects the text format.
+	Color string `yaml:"color"`
+	// Output is "stdout", "stderr" or a file path to append to.
+	Output string          `yaml:"output"`
+	Access AccessLogConfig `yaml:"access"`
+}
+
+type AccessLogConfig struct {
+	// SkipPrefixes lists path prefixes that are never access-logged.
+	SkipPrefixes []string `yaml:"skip_prefixes" reload:"true"`
+	// NotFoundPrefixes limits 404 logging to paths under these prefixes so
+	// scanners probing random URLs do not flood the log. An empty list logs
+	// every 404.
+	NotFoundPrefixes []string `yaml:"not_found_prefixes" reload:"true"`
 }
 
+// Load reads the YAML file at path without environment or flag overrides.
+// Use Resolve for the full precedence chain.
 func Load(path string) (Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return Config{}, fmt.Errorf("read config: %w", err)
 	}
 
-	var cfg Config
+	cfg := seed()
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return Config{}, fmt.Errorf("parse config: %w", err)
 	}
 
-	if cfg.Server.Addr == "" {
-		cfg.Server.Addr = defaultServerAddr
+	if err := resolveSecretFiles(configFields(&cfg), nil); err != nil {
+		return Config{}, err
 	}
-	if cfg.Mongo.URI == "" {
-		cfg.Mongo.URI = defaultMongoURI
-	}
-	if cfg.Mongo.Database == "" {
-		cfg.Mongo.Database = defaultMongoDB
+
+	cfg.applyDefaults()
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
 	}
 
 	return cfg, nil
 }
+
+// Default returns the configuration used when no file overrides anything.
+func Default() Config {
+	cfg := seed()
+	cfg.applyDefaults()
+	return cfg
+}
+
+// seed returns the starting point that the file, environment and flags are
+// applied on top of. Toggles and durations whose zero value is a valid
+// setting get their defaults here; values that may come from a _file secret
+// stay empty until applyDefaults runs.
+func seed() Config {
+	return Config{
+		Server: ServerConfig{Metrics: true},
+		Compression: CompressionConfig{
+			Enabled:     true,
+			MinSize:     defaultCompressionMinSize,
+			BrotliLevel: defaultCompressionBrotliLevel,
+		},
+		Security: SecurityConfig{Headers: true},
+		Cache:    CacheConfig{Warmup: true, StaleTTL: defaultStaleTTL},
+		Locale:   LocaleConfig{AcceptLanguage: true},
+		News:     NewsConfig{Sync: true, MirrorImages: true},
+	}
+}
+
+// applyDefaults fills every field that is still unset.
+func (c *Config) applyDefaults() {
+	if c.Server.Addr == "" {
+		c.Server.Addr = defaultServerAddr
+	}
+	setDuration(&c.Server.RequestTimeout, defaultRequestTimeout)
+	setDuration(&c.Server.ReadHeaderTimeout, defaultReadHeaderTimeout)
+	setDuration(&c.Server.IdleTimeout, defaultIdleTimeout)
+	setDuration(&c.Server.ShutdownTimeout, defaultShutdownTimeout)
+
+	if c.Compression.GzipLevel == 0 {
+		c.Compression.GzipLevel = defaultCompressionGzipLevel
+	}
+	if c.Compression.SkipTypes == nil {
+		c.Compression.SkipTypes = append([]string(nil), defaultCompressionSkipTypes...)
+	}
+
+	if c.CORS.AllowedOrigins == nil {
+		c.CORS.AllowedOrigins = append([]string(nil), defaultCORSOrigins...)
+	}
+	if c.CORS.AllowedMethods == nil {
+		c.CORS.AllowedMethods = append([]string(nil), defaultCORSMethods...)
+	}
+	if c.CORS.AllowedHeaders == nil {
+		c.CORS.AllowedHeaders = append([]string(nil), defaultCORSHeaders...)
+	}
+	if c.CORS.ExposedHeaders == nil {
+		c.CORS.ExposedHeaders = append([]string(nil), defaultCORSExposedHeaders...)
+	}
+	setDuration(&c.CORS.MaxAge, defaultCORSMaxAge)
+
+	if c.Security.ReferrerPolicy == "" {
+		c.Security.ReferrerPolicy = defaultReferrerPolicy
+	}
+	if c.Security.ContentSecurityPolicy == "" {
+		c.Security.ContentSecurityPolicy = defaultContentSecurityPolicy
+	}
+
+	if c.RateLimit.RequestsPerMinute == 0 {
+		c.RateLimit.RequestsPerMinute = defaultRateLimitRequestsPerMinute
+	}
+	if c.RateLimit.Burst == 0 {
+		c.RateLimit.Burst = defaultRateLimitBurst
+	}
+	if c.RateLimit.KeyHeader == "" {
+		c.RateLimit.KeyHeader = defaultRateLimitKeyHeader
+	}
+	if c.RateLimit.ExemptPrefixes == nil {
+		c.RateLimit.ExemptPrefixes = append([]string(nil), defaultRateLimitExemptPrefixes...)
+	}
+	setDuration(&c.RateLimit.KeyRefreshInterval, defaultRateLimitKeyRefresh)
+
+	if c.Mongo.URI == "" {
+		c.Mongo.URI = defaultMongoURI
+	}
+	if c.Mongo.Database == "" {
+		c.Mongo.Database = defaultMongoDB
+	}
+	setDuration(&c.Mongo.ConnectTimeout, defaultMongoConnectTimeout)
+	if c.Mongo.MaxPoolSize == 0 {
+		c.Mongo.MaxPoolSize = defaultMongoMaxPoolSize
+	}
+	setDuration(&c.Mongo.MaxConnIdleTime, defaultMongoMaxConnIdle)
+
+	setDuration(&c.Cache.CharacterTTL, defaultCharacterTTL)
+	setDuration(&c.Cache.CatalogTTL, defaultCatalogTTL)
+	setDuration(&c.Cache.ScheduleTTL, defaultScheduleTTL)
+	if c.Cache.MaxEntries == 0 {
+		c.Cache.MaxEntries = defaultMaxEntries
+	}
+	setDuration(&c.Cache.ThumbnailTTL, defaultThumbnailTTL)
+	setDuration(&c.Cache.StatusTTL, defaultStatusTTL)
+
+	if c.Locale.Default == "" {
+		c.Locale.Default = defaultLocale
+	}
+	if c.Locale.Fallbacks == nil {
+		c.Locale.Fallbacks = make(map[string]string, len(defaultLocaleFallbacks))
+		for from, to := range defaultLocaleFallbacks {
+			c.Locale.Fallbacks[from] = to
+		}
+	}
+
+	if c.Assets.Dir == "" {
+		c.Assets.Dir = defaultAssetsDir
+	}
+	setDuration(&c.Assets.RebuildInterval, defaultAssetsRebuildInterval)
+
+	setDuration(&c.News.SyncInterval, defaultNewsSyncInterval)
+	if c.News.Concurrency == 0 {
+		c.News.Concurrency = defaultNewsConcurrency
+	}
+	setDuration(&c.News.RequestTimeout, defaultNewsRequestTimeout)
+	setDuration(&c.News.ImageTimeout, defaultNewsImageTimeout)
+	if c.News.Upstreams == nil {
+		c.News.Upstreams = make(map[string]string, len(DefaultNewsUpstreams))
+	}
+	for region, base := range DefaultNewsUpstreams {
+		if c.News.Upstreams[region] == "" {
+			c.News.Upstreams[region] = base
+		}
+	}
+
+	if c.Watch.Mode == "" {
+		c.Watch.Mode = defaultWatchMode
+	}
+	setDuration(&c.Watch.PollInterval, defaultWatchPollInterval)
+	setDuration(&c.Watch.Debounce, defaultWatchDebounce)
+
+	if c.Log.Format == "" {
+		c.Log.Format = defaultLogFormat
+	}
+	if c.Log.Level == "" {
+		c.Log.Level = defaultLogLevel
+	}
+	if c.Log.Color == "" {
+		c.Log.Color = defaultLogColor
+	}
+	if c.Log.Output == "" {
+		c.Log.Output = defaultLogOutput
+	}
+	if c.Log.Access.SkipPrefixes == nil {
+		c.Log.Access.SkipPrefixes = append([]string(nil), defaultAccessSkipPrefixes...)
+	}
+	if c.Log.Access.NotFoundPrefixes == nil {
+		c.Log.Access.NotFoundPrefixes = append([]string(nil), defaultAccessNotFoundPrefixes...)
+	}
+}
+
+func setDuration(value *time.Duration, fallback time.Duration) {
+	if *value == 0 {
+		*value = fallback
+	}
+}
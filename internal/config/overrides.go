@@ -0,0 +1,353 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvPrefix starts every environment override, e.g. STELLA_MONGO_URI.
+	EnvPrefix = "STELLA_"
+
+	defaultConfigPath = "config.yaml"
+	fileSuffix        = "_file"
+)
+
+// Sources of a setting, lowest precedence first.
+const (
+	sourceDefault = iota
+	sourceFile
+	sourceEnv
+	sourceFlag
+)
+
+// LookupEnv matches os.LookupEnv so tests and callers can inject an
+// environment.
+type LookupEnv func(key string) (string, bool)
+
+// Resolve builds the configuration from every source. Later sources win:
+//
+//  1. built-in defaults
+//  2. the YAML file (-config, STELLA_CONFIG, or ./config.yaml when present)
+//  3. STELLA_* environment variables
+//  4. command-line flags
+//
+// Fields ending in _file (e.g. mongo.uri_file) name a file whose trimmed
+// contents replace the matching field, so secrets can be mounted instead of
+// written into YAML. The field and its _file sibling follow the same
+// precedence; only setting both in one source is an error. The result is validated and every problem is reported
+// in a single error.
+func Resolve(name string, args []string, lookup LookupEnv) (Config, error) {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	cfg := seed()
+	fields := configFields(&cfg)
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	configPath := fs.String("config", "", "path to the YAML configuration file (env "+EnvPrefix+"CONFIG)")
+
+	type pendingFlag struct {
+		field field
+		value string
+	}
+	var flagged []pendingFlag
+
+	for _, f := range fields {
+		f := f
+		fs.Func(f.path, f.usage(), func(value string) error {
+			flagged = append(flagged, pendingFlag{field: f, value: value})
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stderr)
+			fs.PrintDefaults()
+		}
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	path, explicit := *configPath, *configPath != ""
+	if !explicit {
+		if envPath, ok := lookup(EnvPrefix + "CONFIG"); ok && envPath != "" {
+			path, explicit = envPath, true
+		} else {
+			path = defaultConfigPath
+		}
+	}
+
+	defaults := make([]any, len(fields))
+	for i, f := range fields {
+		defaults[i] = f.value.Interface()
+	}
+	if err := readFile(path, explicit, &cfg); err != nil {
+		return Config{}, err
+	}
+
+	// sources records where each field was last set, by path.
+	sources := make(map[string]int, len(fields))
+	for i, f := range fields {
+		if !reflect.DeepEqual(defaults[i], f.value.Interface()) {
+			sources[f.path] = sourceFile
+		}
+	}
+
+	var errs []error
+
+	for _, f := range fields {
+		if value, ok := lookup(f.env); ok {
+			if err := f.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+			}
+			sources[f.path] = sourceEnv
+		}
+	}
+
+	for _, p := range flagged {
+		if err := p.field.set(p.value); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", p.field.path, err))
+		}
+		sources[p.field.path] = sourceFlag
+	}
+
+	if err := resolveSecretFiles(fields, sources); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+
+	cfg.applyDefaults()
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return cfg, nil
+}
+
+// readFile unmarshals the YAML file at path into cfg. A missing file is only
+// an error when the path was given explicitly.
+func readFile(path string, explicit bool, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+
+	return nil
+}
+
+// field is one leaf setting of Config, addressed by its dotted YAML path.
+type field struct {
+	path   string
+	env    string
+	reload bool
+	secret bool
+	value  reflect.Value
+}
+
+func (f field) usage() string {
+	return fmt.Sprintf("override %s (env %s)", f.path, f.env)
+}
+
+// configFields lists every leaf field of cfg in declaration order.
+func configFields(cfg *Config) []field {
+	var fields []field
+	collectFields(reflect.ValueOf(cfg).Elem(), "", &fields)
+	return fields
+}
+
+func collectFields(v reflect.Value, prefix string, fields *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			collectFields(fv, path, fields)
+			continue
+		}
+
+		*fields = append(*fields, field{
+			path:   path,
+			env:    EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(path)),
+			reload: sf.Tag.Get("reload") == "true",
+			secret: sf.Tag.Get("secret") == "true",
+			value:  fv,
+		})
+	}
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	stringsType  = reflect.TypeOf([]string(nil))
+)
+
+// set parses raw into the field according to its Go type. Lists are comma
+// separated and maps are comma separated key=value pairs; an empty string
+// sets an empty list or map.
+func (f field) set(raw string) error {
+	v := f.value
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", v.Type())
+		}
+		items := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map type %s", v.Type())
+		}
+		items := map[string]string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("invalid key=value pair %q", item)
+			}
+			items[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// resolveSecretFiles loads every "<name>_file" field into its "<name>"
+// sibling. A list sibling takes one entry per non-empty line; lines starting
+// with # are ignored. When both are set, the one from the later source in
+// sources wins; setting both in the same source is an error so it is always
+// clear which one is used.
+func resolveSecretFiles(fields []field, sources map[string]int) error {
+	byPath := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byPath[f.path] = f
+	}
+
+	var errs []error
+	for _, f := range fields {
+		target, ok := strings.CutSuffix(f.path, fileSuffix)
+		if !ok || f.value.Kind() != reflect.String {
+			continue
+		}
+
+		filePath := f.value.String()
+		if filePath == "" {
+			continue
+		}
+
+		dest, ok := byPath[target]
+		if !ok || (dest.value.Kind() != reflect.String && dest.value.Type() != stringsType) {
+			continue
+		}
+
+		if dest.value.Len() > 0 {
+			switch {
+			case sources[target] == sources[f.path]:
+				errs = append(errs, fmt.Errorf("%s and %s are mutually exclusive", target, f.path))
+				continue
+			case sources[target] > sources[f.path]:
+				continue
+			}
+		}
+
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.path, err))
+			continue
+		}
+
+		if dest.value.Kind() == reflect.String {
+			dest.value.SetString(strings.TrimSpace(string(data)))
+			continue
+		}
+
+		lines := []string{}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+		dest.value.Set(reflect.ValueOf(lines))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile writes content to name in a temporary directory and returns
+// its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// env returns a LookupEnv over vars.
+func env(vars map[string]string) LookupEnv {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func TestResolveSecretFilePrecedence(t *testing.T) {
+	secret := writeFile(t, "mongo-uri", "mongodb://secret:27017\n")
+
+	for name, tc := range map[string]struct {
+		yaml    string
+		env     map[string]string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		"file in env overrides value in YAML": {
+			yaml: "mongo:\n  uri: mongodb://yaml:27017\n",
+			env:  map[string]string{"STELLA_MONGO_URI_FILE": secret},
+			want: "mongodb://secret:27017",
+		},
+		"value in env overrides file in YAML": {
+			yaml: "mongo:\n  uri_file: " + secret + "\n",
+			env:  map[string]string{"STELLA_MONGO_URI": "mongodb://env:27017"},
+			want: "mongodb://env:27017",
+		},
+		"flag overrides file in env": {
+			env:  map[string]string{"STELLA_MONGO_URI_FILE": secret},
+			args: []string{"-mongo.uri", "mongodb://flag:27017"},
+			want: "mongodb://flag:27017",
+		},
+		"file flag overrides value in env": {
+			env:  map[string]string{"STELLA_MONGO_URI": "mongodb://env:27017"},
+			args: []string{"-mongo.uri_file", secret},
+			want: "mongodb://secret:27017",
+		},
+		"both in YAML": {
+			yaml:    "mongo:\n  uri: mongodb://yaml:27017\n  uri_file: " + secret + "\n",
+			wantErr: "mongo.uri and mongo.uri_file are mutually exclusive",
+		},
+		"both in env": {
+			env:     map[string]string{"STELLA_MONGO_URI": "mongodb://env:27017", "STELLA_MONGO_URI_FILE": secret},
+			wantErr: "mongo.uri and mongo.uri_file are mutually exclusive",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			args := append([]string{"-config", writeFile(t, "config.yaml", tc.yaml)}, tc.args...)
+			cfg, err := Resolve("test", args, env(tc.env))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Mongo.URI != tc.want {
+				t.Errorf("mongo.uri = %q, want %q", cfg.Mongo.URI, tc.want)
+			}
+		})
+	}
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	yaml := "server:\n  addr: \":7000\"\n  request_timeout: 7s\nnews:\n  concurrency: 7\nlog:\n  level: debug\n"
+
+	cfg, err := Resolve("test",
+		[]string{"-config", writeFile(t, "config.yaml", yaml), "-server.addr", ":9000"},
+		env(map[string]string{
+			"STELLA_SERVER_ADDR":               ":8000",
+			"STELLA_SERVER_REQUEST_TIMEOUT":    "8s",
+			"STELLA_RATELIMIT_TRUSTED_PROXIES": "10.0.0.0/8, 192.168.0.1",
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		got, want any
+	}{
+		{"flag over env and YAML", cfg.Server.Addr, ":9000"},
+		{"env over YAML", cfg.Server.RequestTimeout, 8 * time.Second},
+		{"YAML over default", cfg.News.Concurrency, 7},
+		{"YAML string", cfg.Log.Level, "debug"},
+		{"default", cfg.Cache.CharacterTTL, defaultCharacterTTL},
+		{"env list", strings.Join(cfg.RateLimit.TrustedProxies, "|"), "10.0.0.0/8|192.168.0.1"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestResolveReportsEveryProblem(t *testing.T) {
+	_, err := Resolve("test",
+		[]string{"-config", writeFile(t, "config.yaml", ""), "-news.concurrency", "many"},
+		env(map[string]string{"STELLA_SERVER_REQUEST_TIMEOUT": "soon"}))
+	if err == nil {
+		t.Fatal("Resolve accepted invalid overrides")
+	}
+	for _, want := range []string{"STELLA_SERVER_REQUEST_TIMEOUT", "-news.concurrency"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not name %s", err, want)
+		}
+	}
+}
+
+func TestResolveMissingExplicitFile(t *testing.T) {
+	if _, err := Resolve("test", []string{"-config", filepath.Join(t.TempDir(), "missing.yaml")}, env(nil)); err == nil {
+		t.Error("a missing -config file was ignored")
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// redacted replaces a secret value, or the credentials of a secret URI.
+const redacted = "***"
+
+// Redacted returns the configuration as nested maps keyed by YAML names, the
+// same shape as the config file, with every secret:"true" field masked. For
+// connection strings only the credentials are masked so the host stays
+// visible. Durations are rendered as strings such as "30m0s".
+func (c Config) Redacted() map[string]any {
+	out := make(map[string]any)
+	for _, f := range configFields(&c) {
+		parts := strings.Split(f.path, ".")
+		section := out
+		for _, name := range parts[:len(parts)-1] {
+			next, ok := section[name].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				section[name] = next
+			}
+			section = next
+		}
+		section[parts[len(parts)-1]] = f.display()
+	}
+	return out
+}
+
+func (f field) display() any {
+	switch v := f.value.Interface().(type) {
+	case time.Duration:
+		return v.String()
+	case string:
+		if f.secret && v != "" {
+			return redactSecret(v)
+		}
+		return v
+	case []string:
+		items := slices.Clone(v)
+		if f.secret {
+			for i := range items {
+				items[i] = redacted
+			}
+		}
+		if items == nil {
+			items = []string{}
+		}
+		return items
+	case map[string]string:
+		if v == nil {
+			return map[string]string{}
+		}
+		return maps.Clone(v)
+	default:
+		return v
+	}
+}
+
+// redactSecret masks the user info of a URI and any other value entirely.
+func redactSecret(value string) string {
+	scheme := strings.Index(value, "://")
+	if scheme < 0 {
+		return redacted
+	}
+	at := strings.LastIndex(value, "@")
+	if at < scheme+3 {
+		return value
+	}
+	return value[:scheme+3] + redacted + value[at:]
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Change describes one setting that differs between two configurations.
+type Change struct {
+	Path string `json:"path"`
+	// Reloadable is false when the new value only takes effect after a
+	// restart.
+	Reloadable bool `json:"reloadable"`
+}
+
+// Changes lists the settings that differ between old and next in
+// declaration order. Secret files are compared by the value they resolved to.
+func Changes(old, next Config) []Change {
+	oldFields := configFields(&old)
+	nextFields := configFields(&next)
+
+	var changes []Change
+	for i, f := range oldFields {
+		if reflect.DeepEqual(f.value.Interface(), nextFields[i].value.Interface()) {
+			continue
+		}
+		changes = append(changes, Change{Path: f.path, Reloadable: f.reload})
+	}
+	return changes
+}
+
+// RestartRequired returns the paths in changes that cannot be applied live.
+func RestartRequired(changes []Change) []string {
+	var paths []string
+	for _, c := range changes {
+		if !c.Reloadable {
+			paths = append(paths, c.Path)
+		}
+	}
+	return paths
+}
+
+// ReloadablePaths lists every setting that can change without a restart.
+func ReloadablePaths() []string {
+	var cfg Config
+	var paths []string
+	for _, f := range configFields(&cfg) {
+		if f.reload {
+			paths = append(paths, f.path)
+		}
+	}
+	return paths
+}
+
+// RestartRequiredError rejects a reload that touches restart-only settings.
+type RestartRequiredError struct {
+	Paths []string
+}
+
+func (e *RestartRequiredError) Error() string {
+	return "restart required to change " + strings.Join(e.Paths, ", ")
+}
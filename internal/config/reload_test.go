@@ -0,0 +1,58 @@
+package config
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestChanges(t *testing.T) {
+	old := Default()
+	next := Default()
+	next.Cache.CharacterTTL = time.Hour
+	next.Server.Addr = ":9000"
+	next.Admin.Tokens = []string{"0123456789abcdef"}
+
+	changes := Changes(old, next)
+	want := []Change{
+		{Path: "server.addr", Reloadable: false},
+		{Path: "cache.character_ttl", Reloadable: true},
+		{Path: "admin.tokens", Reloadable: true},
+	}
+	for _, c := range want {
+		if !slices.Contains(changes, c) {
+			t.Errorf("changes = %+v, missing %+v", changes, c)
+		}
+	}
+	if len(changes) != len(want) {
+		t.Errorf("changes = %+v, want %d", changes, len(want))
+	}
+
+	if got := RestartRequired(changes); !slices.Equal(got, []string{"server.addr"}) {
+		t.Errorf("restart required = %v, want server.addr", got)
+	}
+	if changes := Changes(old, Default()); len(changes) != 0 {
+		t.Errorf("identical configurations differ in %+v", changes)
+	}
+}
+
+func TestReloadablePaths(t *testing.T) {
+	paths := ReloadablePaths()
+	for _, path := range []string{"cache.character_ttl", "log.level", "admin.tokens", "news.sync_interval"} {
+		if !slices.Contains(paths, path) {
+			t.Errorf("%s is not reloadable", path)
+		}
+	}
+	for _, path := range []string{"server.addr", "mongo.uri", "mongo.database"} {
+		if slices.Contains(paths, path) {
+			t.Errorf("%s is reloadable", path)
+		}
+	}
+}
+
+func TestRestartRequiredError(t *testing.T) {
+	err := &RestartRequiredError{Paths: []string{"server.addr", "mongo.uri"}}
+	if got, want := err.Error(), "restart required to change server.addr, mongo.uri"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,317 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+
+	"ss-api/internal/apiversion"
+	"ss-api/internal/locale"
+)
+
+// minAdminTokenLength keeps admin tokens out of reach of guessing.
+const minAdminTokenLength = 16
+
+// Validate checks every field and reports all problems at once.
+func (c Config) Validate() error {
+	var errs []error
+	add := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if err := validateAddr(c.Server.Addr); err != nil {
+		add("server.addr", "%v", err)
+	}
+
+	positive := func(path string, value time.Duration) {
+		if value <= 0 {
+			add(path, "must be a positive duration, got %s", value)
+		}
+	}
+
+	positive("server.request_timeout", c.Server.RequestTimeout)
+	positive("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	if c.Server.WriteTimeout < 0 {
+		add("server.write_timeout", "must not be negative, got %s", c.Server.WriteTimeout)
+	}
+	positive("server.idle_timeout", c.Server.IdleTimeout)
+	positive("server.shutdown_timeout", c.Server.ShutdownTimeout)
+
+	if c.Compression.MinSize < 0 {
+		add("compression.min_size", "must not be negative, got %d", c.Compression.MinSize)
+	}
+	if c.Compression.GzipLevel < 1 || c.Compression.GzipLevel > 9 {
+		add("compression.gzip_level", "must be between 1 and 9, got %d", c.Compression.GzipLevel)
+	}
+	if c.Compression.BrotliLevel < 0 || c.Compression.BrotliLevel > 11 {
+		add("compression.brotli_level", "must be between 0 and 11, got %d", c.Compression.BrotliLevel)
+	}
+	for i, mediaType := range c.Compression.SkipTypes {
+		if _, _, err := mime.ParseMediaType(mediaType); err != nil {
+			add(fmt.Sprintf("compression.skip_types[%d]", i), "invalid media type %q", mediaType)
+		}
+	}
+
+	for i, origin := range c.CORS.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			add(fmt.Sprintf("cors.allowed_origins[%d]", i), "%v", err)
+		}
+	}
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		add("cors.allow_credentials", `cannot be combined with the "*" origin; list the origins instead`)
+	}
+	for i, method := range c.CORS.AllowedMethods {
+		if method == "" || strings.ToUpper(method) != method || strings.ContainsAny(method, " ,") {
+			add(fmt.Sprintf("cors.allowed_methods[%d]", i), "must be an upper-case HTTP method, got %q", method)
+		}
+	}
+	positive("cors.max_age", c.CORS.MaxAge)
+
+	if c.Security.HSTSMaxAge < 0 {
+		add("security.hsts_max_age", "must not be negative, got %s", c.Security.HSTSMaxAge)
+	}
+
+	if c.RateLimit.RequestsPerMinute < 1 {
+		add("ratelimit.requests_per_minute", "must be at least 1, got %d", c.RateLimit.RequestsPerMinute)
+	}
+	if c.RateLimit.Burst < 1 {
+		add("ratelimit.burst", "must be at least 1, got %d", c.RateLimit.Burst)
+	}
+	if !validHeaderName(c.RateLimit.KeyHeader) {
+		add("ratelimit.key_header", "must be a valid header name, got %q", c.RateLimit.KeyHeader)
+	}
+	for i, proxy := range c.RateLimit.TrustedProxies {
+		if _, err := ParseNetwork(proxy); err != nil {
+			add(fmt.Sprintf("ratelimit.trusted_proxies[%d]", i), "%v", err)
+		}
+	}
+	positive("ratelimit.key_refresh_interval", c.RateLimit.KeyRefreshInterval)
+
+	if _, err := connstring.ParseAndValidate(c.Mongo.URI); err != nil {
+		add("mongo.uri", "%v", redactURIError(err, c.Mongo.URI))
+	}
+
+	if c.Mongo.Database == "" {
+		add("mongo.database", "must not be empty")
+	} else if strings.ContainsAny(c.Mongo.Database, `/\. "$`) {
+		add("mongo.database", "must not contain any of / \\ . \" $ or spaces")
+	}
+
+	positive("mongo.connect_timeout", c.Mongo.ConnectTimeout)
+	positive("mongo.max_conn_idle_time", c.Mongo.MaxConnIdleTime)
+	if c.Mongo.MaxPoolSize < 1 {
+		add("mongo.max_pool_size", "must be at least 1, got %d", c.Mongo.MaxPoolSize)
+	}
+	if c.Mongo.MinPoolSize < 0 || c.Mongo.MinPoolSize > c.Mongo.MaxPoolSize {
+		add("mongo.min_pool_size", "must be between 0 and max_pool_size (%d), got %d", c.Mongo.MaxPoolSize, c.Mongo.MinPoolSize)
+	}
+
+	positive("cache.character_ttl", c.Cache.CharacterTTL)
+	positive("cache.catalog_ttl", c.Cache.CatalogTTL)
+	positive("cache.schedule_ttl", c.Cache.ScheduleTTL)
+	if c.Cache.StaleTTL < 0 {
+		add("cache.stale_ttl", "must not be negative, got %s", c.Cache.StaleTTL)
+	}
+	if c.Cache.MaxEntries < 1 {
+		add("cache.max_entries", "must be at least 1, got %d", c.Cache.MaxEntries)
+	}
+	positive("cache.thumbnail_ttl", c.Cache.ThumbnailTTL)
+	positive("cache.status_ttl", c.Cache.StatusTTL)
+
+	if _, ok := locale.Parse(c.Locale.Default); !ok {
+		add("locale.default", "unsupported region %q, expected one of %s", c.Locale.Default, strings.Join(locale.Names(), ", "))
+	}
+	for _, from := range sortedKeys(c.Locale.Fallbacks) {
+		path := "locale.fallbacks." + from
+		fromLocale, ok := locale.Parse(from)
+		if !ok {
+			add(path, "unsupported region %q, expected one of %s", from, strings.Join(locale.Names(), ", "))
+			continue
+		}
+		toLocale, ok := locale.Parse(c.Locale.Fallbacks[from])
+		if !ok {
+			add(path, "unsupported region %q, expected one of %s", c.Locale.Fallbacks[from], strings.Join(locale.Names(), ", "))
+		} else if toLocale == fromLocale {
+			add(path, "must not fall back to itself")
+		}
+	}
+
+	for _, schedule := range []struct {
+		name  string
+		dates map[string]string
+	}{{"deprecated", c.Versions.Deprecated}, {"sunset", c.Versions.Sunset}} {
+		for _, version := range sortedKeys(schedule.dates) {
+			path := "versions." + schedule.name + "." + version
+			if _, ok := apiversion.Lookup(version); !ok {
+				add(path, "unknown version %q, expected one of %s", version, strings.Join(apiversion.Names(), ", "))
+			} else if _, err := apiversion.ParseDate(schedule.dates[version]); err != nil {
+				add(path, "%v", err)
+			}
+		}
+	}
+	if c.Versions.Link != "" {
+		if err := validateUpstream(c.Versions.Link); err != nil {
+			add("versions.link", "%v", err)
+		}
+	}
+
+	if strings.TrimSpace(c.Assets.Dir) == "" {
+		add("assets.dir", "must not be empty")
+	}
+	positive("assets.rebuild_interval", c.Assets.RebuildInterval)
+
+	positive("news.sync_interval", c.News.SyncInterval)
+	if c.News.Concurrency < 1 {
+		add("news.concurrency", "must be at least 1, got %d", c.News.Concurrency)
+	}
+	positive("news.request_timeout", c.News.RequestTimeout)
+	positive("news.image_timeout", c.News.ImageTimeout)
+	for _, region := range sortedKeys(c.News.Upstreams) {
+		path := "news.upstreams." + region
+		if _, known := DefaultNewsUpstreams[region]; !known {
+			add(path, "unknown news region, expected one of %s", strings.Join(sortedKeys(DefaultNewsUpstreams), ", "))
+			continue
+		}
+		if err := validateUpstream(c.News.Upstreams[region]); err != nil {
+			add(path, "%v", err)
+		}
+	}
+
+	switch c.Watch.Mode {
+	case "auto", "change_stream", "poll", "off":
+	default:
+		add("watch.mode", "must be auto, change_stream, poll or off, got %q", c.Watch.Mode)
+	}
+	positive("watch.poll_interval", c.Watch.PollInterval)
+	positive("watch.debounce", c.Watch.Debounce)
+
+	for i, token := range c.Admin.Tokens {
+		// The token itself never goes into the message.
+		if len(token) < minAdminTokenLength || strings.ContainsAny(token, " \t\r\n") {
+			add(fmt.Sprintf("admin.tokens[%d]", i), "must be at least %d characters without whitespace", minAdminTokenLength)
+		}
+	}
+
+	switch strings.ToLower(c.Log.Format) {
+	case "text", "json":
+	default:
+		add("log.format", "must be text or json, got %q", c.Log.Format)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(c.Log.Level)) {
+	case "debug", "info", "warn", "warning", "error":
+	default:
+		add("log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+
+	switch strings.ToLower(c.Log.Color) {
+	case "auto", "always", "never":
+	default:
+		add("log.color", "must be auto, always or never, got %q", c.Log.Color)
+	}
+
+	if strings.TrimSpace(c.Log.Output) == "" {
+		add("log.output", "must not be empty")
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("must be host:port or :port, got %q", addr)
+	}
+
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("port must be a number between 0 and 65535, got %q", port)
+	}
+
+	if host != "" && net.ParseIP(host) == nil && strings.ContainsAny(host, " /") {
+		return fmt.Errorf("invalid host %q", host)
+	}
+
+	return nil
+}
+
+// ParseNetwork parses an IP address or CIDR range. A bare address becomes a
+// single-host network.
+func ParseNetwork(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return network, nil
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("must be an IP address or CIDR range, got %q", value)
+	}
+	bits := 128
+	if v4 := ip.To4(); v4 != nil {
+		ip, bits = v4, 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// validHeaderName reports whether name is a non-empty HTTP token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateOrigin accepts "*" or scheme://host[:port], where the host may
+// start with "*." to match subdomains.
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return fmt.Errorf(`must be "*" or an origin such as https://example.com, got %q`, origin)
+	}
+	return nil
+}
+
+func validateUpstream(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an absolute http(s) URL, got %q", raw)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// redactURIError keeps credentials embedded in the URI out of error messages.
+func redactURIError(err error, uri string) error {
+	msg := err.Error()
+	if at := strings.LastIndex(uri, "@"); at > 0 {
+		if scheme := strings.Index(uri, "://"); scheme >= 0 && scheme+3 < at {
+			msg = strings.ReplaceAll(msg, uri[scheme+3:at], "***")
+		}
+	}
+	return errors.New(msg)
+}
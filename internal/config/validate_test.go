@@ -0,0 +1,68 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultIsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("default configuration is invalid: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		edit func(*Config)
+		want string
+	}{
+		"addr":              {func(c *Config) { c.Server.Addr = "8080" }, "server.addr"},
+		"timeout":           {func(c *Config) { c.Server.RequestTimeout = 0 }, "server.request_timeout"},
+		"gzip level":        {func(c *Config) { c.Compression.GzipLevel = 10 }, "compression.gzip_level"},
+		"origin":            {func(c *Config) { c.CORS.AllowedOrigins = []string{"example.com"} }, "cors.allowed_origins[0]"},
+		"credentials":       {func(c *Config) { c.CORS.AllowedOrigins, c.CORS.AllowCredentials = []string{"*"}, true }, "cors.allow_credentials"},
+		"trusted proxy":     {func(c *Config) { c.RateLimit.TrustedProxies = []string{"proxy"} }, "ratelimit.trusted_proxies[0]"},
+		"mongo uri":         {func(c *Config) { c.Mongo.URI = "localhost" }, "mongo.uri"},
+		"database":          {func(c *Config) { c.Mongo.Database = "stella.db" }, "mongo.database"},
+		"pool":              {func(c *Config) { c.Mongo.MinPoolSize = c.Mongo.MaxPoolSize + 1 }, "mongo.min_pool_size"},
+		"stale ttl":         {func(c *Config) { c.Cache.StaleTTL = -time.Second }, "cache.stale_ttl"},
+		"locale":            {func(c *Config) { c.Locale.Default = "FR" }, "locale.default"},
+		"self fallback":     {func(c *Config) { c.Locale.Fallbacks = map[string]string{"JP": "JP"} }, "locale.fallbacks.JP"},
+		"unknown version":   {func(c *Config) { c.Versions.Sunset = map[string]string{"v9": "2027-01-01"} }, "versions.sunset.v9"},
+		"version date":      {func(c *Config) { c.Versions.Deprecated = map[string]string{"v1": "soon"} }, "versions.deprecated.v1"},
+		"news upstream":     {func(c *Config) { c.News.Upstreams = map[string]string{"XX": "https://example.com"} }, "news.upstreams.XX"},
+		"watch mode":        {func(c *Config) { c.Watch.Mode = "always" }, "watch.mode"},
+		"short admin token": {func(c *Config) { c.Admin.Tokens = []string{"short"} }, "admin.tokens[0]"},
+		"log format":        {func(c *Config) { c.Log.Format = "xml" }, "log.format"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := Default()
+			tc.edit(&cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tc.want+":") {
+				t.Fatalf("error = %v, want one for %s", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Addr = "8080"
+	cfg.Watch.Mode = "always"
+	cfg.Admin.Tokens = []string{"tok3n!"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("invalid configuration accepted")
+	}
+	for _, want := range []string{"server.addr:", "watch.mode:", "admin.tokens[0]:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not report %s", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "tok3n!") {
+		t.Error("the admin token leaked into the error")
+	}
+}
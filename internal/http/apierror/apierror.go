@@ -0,0 +1,130 @@
+// Package apierror writes the JSON error body shared by every route:
+//
+//	{"error": {"code": "character_not_found", "message": "character not found", "details": {"identifier": "Amber"}, "requestId": "..."}}
+//
+// code is stable and meant for programs; message is for people and may
+// change. details holds the values the error is about and is always an
+// object, empty when there is nothing to add.
+package apierror
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"ss-api/internal/locale"
+	"ss-api/internal/logging"
+)
+
+// Codes used across the API. Route-specific codes live next to the
+// generic one they refine.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeInvalidBody      = "invalid_body"
+	CodeUnsupportedLang  = "unsupported_lang"
+
+	CodeUnauthorized   = "unauthorized"
+	CodeInvalidAPIKey  = "invalid_api_key"
+	CodeForbidden      = "forbidden"
+	CodeAdminDisabled  = "admin_disabled"
+	CodeAPIKeyDisabled = "api_key_disabled"
+	CodeOriginDenied   = "origin_not_allowed"
+
+	CodeNotFound             = "not_found"
+	CodeRouteNotFound        = "route_not_found"
+	CodeCharacterNotFound    = "character_not_found"
+	CodeDiscNotFound         = "disc_not_found"
+	CodeNoData               = "no_data"
+	CodeAssetNotFound        = "asset_not_found"
+	CodeNewsCategoryNotFound = "news_category_not_found"
+	CodeNewsRegionNotFound   = "news_region_not_found"
+	CodeJobNotFound          = "job_not_found"
+	CodeAPIKeyNotFound       = "api_key_not_found"
+	CodeTierNotFound         = "tier_not_found"
+	CodeVersionNotFound      = "version_not_found"
+
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeRestartRequired  = "restart_required"
+	CodeTierInUse        = "tier_in_use"
+	CodeRateLimited      = "rate_limited"
+
+	CodeInternal       = "internal_error"
+	CodeUnavailable    = "service_unavailable"
+	CodeUpstreamFailed = "upstream_failed"
+)
+
+// Details carries the values an error refers to, e.g. the identifier that
+// was not found.
+type Details map[string]any
+
+type envelope struct {
+	Error body `json:"error"`
+}
+
+type body struct {
+	Code      string  `json:"code"`
+	Message   string  `json:"message"`
+	Details   Details `json:"details"`
+	RequestID string  `json:"requestId"`
+}
+
+// Write sends status with the error envelope.
+func Write(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	WriteDetails(w, r, status, code, message, nil)
+}
+
+// WriteDetails sends status with the error envelope and details.
+func WriteDetails(w http.ResponseWriter, r *http.Request, status int, code, message string, details Details) {
+	if details == nil {
+		details = Details{}
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("Cache-Control", "no-store")
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(envelope{Error: body{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: logging.RequestID(r.Context()),
+	}})
+	if err != nil {
+		slog.WarnContext(r.Context(), "failed to write error response", "error", err)
+	}
+}
+
+// MethodNotAllowed answers 405 and lists the allowed methods in Allow.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+	} else {
+		allowed = []string{}
+	}
+	WriteDetails(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed", Details{
+		"method":  r.Method,
+		"allowed": allowed,
+	})
+}
+
+// UnsupportedLang answers 400 for a lang value that names no supported
+// region and lists the ones that are.
+func UnsupportedLang(w http.ResponseWriter, r *http.Request, lang string) {
+	WriteDetails(w, r, http.StatusBadRequest, CodeUnsupportedLang, fmt.Sprintf("unsupported lang %q", lang), Details{
+		"lang":      lang,
+		"supported": locale.Names(),
+	})
+}
+
+// InternalError logs err and answers 500 without exposing it.
+func InternalError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "internal server error", "path", r.URL.Path, "error", err)
+	Write(w, r, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"path"
@@ -17,34 +16,43 @@ import (
 
 	"ss-api/internal/alias"
 	"ss-api/internal/app"
+	"ss-api/internal/config"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/http/handlers/news"
+	"ss-api/internal/locale"
+	"ss-api/internal/logging"
+	"ss-api/internal/metrics"
 )
 
 const (
-	defaultAssetsDir = "assets"
-	defaultRegion    = "en"
+	defaultRegion = "en"
+	newsAssetsDir = "news"
 )
 
 var (
 	errAssetNotFound = errors.New("asset not found")
+
+	assetRebuilds = metrics.NewCounterVec(
+		"stella_asset_resolver_rebuilds_total",
+		"Rebuilds of the friendly asset alias table by result.",
+		"result",
+	)
 )
 
 type assetHandler struct {
 	assetsDir string
 	resolver  *assetResolver
-	logger    *log.Logger
+	logger    *logging.Logger
 
 	mu         sync.RWMutex
 	dirCache   map[string]map[string]string // subdir → lower(name) → name
 	loadedDirs map[string]bool
 }
 
-func newAssetHandler(appInstance *app.App, logger *log.Logger) *assetHandler {
-	dir := defaultAssetsDir
-	if abs, err := filepath.Abs(defaultAssetsDir); err == nil {
-		dir = abs
-	}
+func newAssetHandler(appInstance *app.App, logger *logging.Logger) *assetHandler {
+	dir := appInstance.AssetsDir()
 
-	return &assetHandler{
+	h := &assetHandler{
 		assetsDir: dir,
 		resolver: &assetResolver{
 			app:       appInstance,
@@ -55,27 +63,80 @@ func newAssetHandler(appInstance *app.App, logger *log.Logger) *assetHandler {
 		dirCache:   make(map[string]map[string]string),
 		loadedDirs: make(map[string]bool),
 	}
+
+	appInstance.RegisterCache("assets.aliases", h.resolver)
+
+	err := appInstance.Scheduler().Register(app.Job{
+		Name:     app.AssetRebuildJob,
+		Interval: appInstance.Config().Assets.RebuildInterval,
+		Timeout:  30 * time.Second,
+		Run:      h.rebuild,
+	})
+	if err != nil {
+		logger.Error("assets: failed to schedule cache rebuild", "error", err)
+	}
+
+	appInstance.OnReload(func(old, next config.Config) {
+		if old.Assets.RebuildInterval != next.Assets.RebuildInterval {
+			_ = appInstance.Scheduler().Reschedule(app.AssetRebuildJob, next.Assets.RebuildInterval)
+		}
+	})
+
+	// The alias table is built from the characters collection only.
+	appInstance.OnCatalogChange(func(change app.CatalogChange) {
+		if change.Collection == "characters" {
+			_ = appInstance.Scheduler().Trigger(app.AssetRebuildJob)
+		}
+	})
+
+	return h
+}
+
+// rebuild drops the directory listings and reloads the alias table so files
+// added to the assets directory or renamed characters are picked up.
+func (h *assetHandler) rebuild(ctx context.Context) error {
+	h.mu.Lock()
+	h.dirCache = make(map[string]map[string]string)
+	h.loadedDirs = make(map[string]bool)
+	h.mu.Unlock()
+
+	return h.resolver.Rebuild(ctx)
 }
 
 func (h *assetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet && r.Method != http.MethodHead {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		apierror.MethodNotAllowed(w, r, http.MethodGet, http.MethodHead)
 		return
 	}
 
 	requested := strings.TrimSpace(r.PathValue("path"))
 	if requested == "" {
-		http.NotFound(w, r)
+		writeAssetNotFound(w, r)
 		return
 	}
 
 	normalized := normalizeRequestPath(requested)
 	if normalized == "" {
-		http.NotFound(w, r)
+		writeAssetNotFound(w, r)
+		return
+	}
+
+	if rest, ok := strings.CutPrefix(normalized, newsAssetsDir+"/"); ok {
+		h.serveNewsImage(w, r, rest)
 		return
 	}
 
-	region := langToAssetRegion(r.URL.Query().Get("lang"))
+	// Assets are embedded by URL, so only an explicit lang picks a regional
+	// variant; Accept-Language is not negotiated here.
+	region := defaultRegion
+	if lang := strings.TrimSpace(r.URL.Query().Get("lang")); lang != "" {
+		l, ok := locale.Parse(lang)
+		if !ok {
+			apierror.UnsupportedLang(w, r, lang)
+			return
+		}
+		region = l.Dir()
+	}
 
 	if h.tryServePhysical(w, r, normalized, region) {
 		return
@@ -84,18 +145,42 @@ func (h *assetHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	target, err := h.resolver.Resolve(r.Context(), normalized, region)
 	if err != nil {
 		if errors.Is(err, errAssetNotFound) {
-			http.NotFound(w, r)
+			writeAssetNotFound(w, r)
 			return
 		}
 
-		h.logger.Printf("asset resolve error: %v", err)
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		h.logger.ErrorContext(r.Context(), "asset resolve error", "path", normalized, "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "internal server error")
 		return
 	}
 
 	http.ServeFile(w, r, target)
 }
 
+// serveNewsImage serves images mirrored by the news synchronizer. Their names
+// are content hashes, so they can be cached indefinitely.
+func (h *assetHandler) serveNewsImage(w http.ResponseWriter, r *http.Request, name string) {
+	base := path.Base(name)
+	if base != name || base == "." || strings.HasPrefix(base, ".") || !news.IsMirroredImage(base) {
+		writeAssetNotFound(w, r)
+		return
+	}
+
+	target := filepath.Join(h.assetsDir, newsAssetsDir, base)
+	info, err := os.Stat(target)
+	if err != nil || info.IsDir() {
+		writeAssetNotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeFile(w, r, target)
+}
+
+func writeAssetNotFound(w http.ResponseWriter, r *http.Request) {
+	apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeAssetNotFound, "asset not found", apierror.Details{"path": r.PathValue("path")})
+}
+
 func (h *assetHandler) tryServePhysical(w http.ResponseWriter, r *http.Request, name, region string) bool {
 	candidates := candidateFilenames(name)
 	for _, candidate := range candidates {
@@ -196,23 +281,6 @@ func candidateFilenames(value string) []string {
 	return []string{base + ".png", base}
 }
 
-// langToAssetRegion maps a ?lang= query value to one of the canonical
-// regional asset subdirectory names: en, jp, tw, cn, kr.
-func langToAssetRegion(lang string) string {
-	switch strings.ToLower(strings.TrimSpace(lang)) {
-	case "jp", "ja":
-		return "jp"
-	case "tw", "zh-tw":
-		return "tw"
-	case "cn", "zh-cn", "zh":
-		return "cn"
-	case "kr", "ko":
-		return "kr"
-	default:
-		return defaultRegion
-	}
-}
-
 // regionToDBKey converts a lowercase region token to the uppercase value
 // used in the MongoDB "region" field (e.g. "en" → "EN").
 func regionToDBKey(region string) string {
@@ -224,6 +292,7 @@ type assetResolver struct {
 	dbName    string
 	assetsDir string
 
+	buildMu   sync.Mutex
 	mu        sync.RWMutex
 	cache     map[string]string // alias key → root physical path (always built from EN)
 	lastBuilt time.Time
@@ -280,15 +349,43 @@ func (r *assetResolver) ensureCache(ctx context.Context) error {
 	}
 	r.mu.RUnlock()
 
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	r.buildMu.Lock()
+	defer r.buildMu.Unlock()
 
-	if r.cache != nil {
+	if r.lookupReady() {
 		return nil
 	}
 
+	return r.rebuildLocked(ctx)
+}
+
+// Rebuild reloads the alias table from Mongo and swaps it in. Lookups keep
+// using the previous table until the new one is complete.
+func (r *assetResolver) Rebuild(ctx context.Context) error {
+	r.buildMu.Lock()
+	defer r.buildMu.Unlock()
+
+	return r.rebuildLocked(ctx)
+}
+
+// Len reports the number of friendly aliases currently resolvable.
+func (r *assetResolver) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.cache)
+}
+
+func (r *assetResolver) lookupReady() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cache != nil
+}
+
+// rebuildLocked must be called with buildMu held.
+func (r *assetResolver) rebuildLocked(ctx context.Context) error {
 	entries, err := r.fetchCharacterTextures(ctx, regionToDBKey(defaultRegion))
 	if err != nil {
+		assetRebuilds.WithLabelValues("error").Inc()
 		return err
 	}
 
@@ -310,8 +407,12 @@ func (r *assetResolver) ensureCache(ctx context.Context) error {
 		}
 	}
 
+	r.mu.Lock()
 	r.cache = inner
 	r.lastBuilt = time.Now()
+	r.mu.Unlock()
+
+	assetRebuilds.WithLabelValues("success").Inc()
 	return nil
 }
 
@@ -0,0 +1,192 @@
+// Package compress negotiates Accept-Encoding and compresses responses with
+// Brotli or gzip. Handler compresses whatever the wrapped handler writes;
+// Encode lets the response cache store ready-made variants so cache hits do
+// not compress again.
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+
+	"ss-api/internal/config"
+	"ss-api/internal/metrics"
+)
+
+// Content codings produced by this package.
+const (
+	Brotli = "br"
+	Gzip   = "gzip"
+)
+
+// preference breaks ties between equally weighted codings: Brotli produces
+// smaller JSON at comparable cost.
+var preference = []string{Brotli, Gzip}
+
+var compressed = metrics.NewCounterVec(
+	"stella_http_compressed_responses_total",
+	"Compressed responses by encoding and source (cache for stored variants, live otherwise).",
+	"encoding", "source",
+)
+
+// Observe counts a compressed response. source is "cache" or "live".
+func Observe(encoding, source string) {
+	compressed.WithLabelValues(encoding, source).Inc()
+}
+
+// Negotiate picks the coding for an Accept-Encoding header: "br", "gzip", or
+// "" when the client accepts neither. q-values are honoured and "*" stands
+// for any coding not listed.
+func Negotiate(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	weights := make(map[string]float64, 2)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		switch name {
+		case "*":
+			wildcard = q
+		case "x-gzip":
+			weights[Gzip] = q
+		default:
+			weights[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range preference {
+		q, ok := weights[coding]
+		if !ok {
+			q = max(wildcard, 0)
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// Eligible reports whether a body of contentType and size is worth
+// compressing under cfg. An unknown size (-1) is eligible.
+func Eligible(cfg config.CompressionConfig, contentType string, size int) bool {
+	if !cfg.Enabled || (size >= 0 && size < cfg.MinSize) {
+		return false
+	}
+	return !Skipped(cfg, contentType)
+}
+
+// Skipped reports whether contentType is listed in compression.skip_types.
+func Skipped(cfg config.CompressionConfig, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return slices.ContainsFunc(cfg.SkipTypes, func(skip string) bool {
+		return strings.EqualFold(skip, mediaType)
+	})
+}
+
+// Encode compresses body with encoding at the level configured in cfg.
+func Encode(cfg config.CompressionConfig, encoding string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(body) / 4)
+
+	w := newWriter(cfg, encoding, &buf)
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// VariantETag derives the entity tag of an encoded representation. Strong
+// tags must differ between encodings of the same resource.
+func VariantETag(etag, encoding string) string {
+	if encoding == "" || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	suffix := "-" + encoding
+	if encoding == Gzip {
+		suffix = "-gz"
+	}
+	return etag[:len(etag)-1] + suffix + `"`
+}
+
+// writers pools encoders per coding and level; Brotli writers in particular
+// are expensive to allocate.
+var writers sync.Map // writerKey → *sync.Pool
+
+type writerKey struct {
+	encoding string
+	level    int
+}
+
+type pooledWriter struct {
+	io.WriteCloser
+	reset func(io.Writer)
+	pool  *sync.Pool
+}
+
+func (w *pooledWriter) Flush() error {
+	if flusher, ok := w.WriteCloser.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+func (w *pooledWriter) Close() error {
+	err := w.WriteCloser.Close()
+	w.reset(io.Discard)
+	w.pool.Put(w)
+	return err
+}
+
+func newWriter(cfg config.CompressionConfig, encoding string, dst io.Writer) io.WriteCloser {
+	key := writerKey{encoding: encoding, level: cfg.GzipLevel}
+	if encoding == Brotli {
+		key.level = cfg.BrotliLevel
+	}
+
+	value, _ := writers.LoadOrStore(key, &sync.Pool{})
+	pool := value.(*sync.Pool)
+
+	if w, ok := pool.Get().(*pooledWriter); ok {
+		w.reset(dst)
+		return w
+	}
+
+	w := &pooledWriter{pool: pool}
+	if encoding == Brotli {
+		bw := brotli.NewWriterLevel(dst, key.level)
+		w.WriteCloser, w.reset = bw, bw.Reset
+	} else {
+		gw, err := gzip.NewWriterLevel(dst, key.level)
+		if err != nil {
+			gw = gzip.NewWriter(dst)
+		}
+		w.WriteCloser, w.reset = gw, gw.Reset
+	}
+	return w
+}
@@ -0,0 +1,28 @@
+package compress
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", Gzip},
+		{"x-gzip", Gzip},
+		{"br", Brotli},
+		{"gzip, br", Brotli},
+		{"GZIP;Q=0.5, br;q=0.4", Gzip},
+		{"br;q=0, gzip", Gzip},
+		{"br;q=0, gzip;q=0", ""},
+		{"*", Brotli},
+		{"*;q=0.3, gzip;q=0.5", Gzip},
+		{"*;q=0, gzip", Gzip},
+		{"deflate, gzip;q=bogus", Gzip},
+	}
+	for _, tc := range tests {
+		if got := Negotiate(tc.header); got != tc.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tc.header, got, tc.want)
+		}
+	}
+}
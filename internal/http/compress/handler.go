@@ -0,0 +1,190 @@
+package compress
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"ss-api/internal/app"
+	"ss-api/internal/config"
+)
+
+// Handler compresses the responses of next for clients that accept Brotli
+// or gzip. The body is buffered until compression.min_size bytes are known,
+// so small responses go out unchanged. Responses that already carry a
+// Content-Encoding, such as precompressed cache hits, pass through.
+func Handler(appInstance *app.App, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := appInstance.Config().Compression
+		if !cfg.Enabled || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &responseWriter{
+			ResponseWriter: w,
+			request:        r,
+			encoding:       Negotiate(r.Header.Get("Accept-Encoding")),
+			cfg:            cfg,
+		}
+		defer cw.finish()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// AddVary adds Accept-Encoding to the Vary header unless it is present.
+func AddVary(header http.Header) {
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name == "*" || strings.EqualFold(name, "Accept-Encoding") {
+				return
+			}
+		}
+	}
+	header.Add("Vary", "Accept-Encoding")
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	request  *http.Request
+	encoding string
+	cfg      config.CompressionConfig
+
+	status  int
+	buf     []byte
+	decided bool
+	encoder io.WriteCloser
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// Informational responses are sent straight away and do not count as
+	// the final status.
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.cfg.MinSize {
+			return len(b), nil
+		}
+		pending := w.buf
+		w.buf = nil
+		if err := w.decide(pending, true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends what has been buffered so far; the response is compressed if
+// it was already decided, and sent as is otherwise.
+func (w *responseWriter) Flush() {
+	if !w.decided {
+		pending := w.buf
+		w.buf = nil
+		if err := w.decide(pending, false); err != nil {
+			return
+		}
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = flusher.Flush()
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide sends the headers and the buffered body, compressing when the
+// response qualifies. large reports whether min_size was reached.
+func (w *responseWriter) decide(pending []byte, large bool) error {
+	w.decided = true
+	header := w.Header()
+
+	compressible := header.Get("Content-Encoding") == "" && bodyAllowed(w.status)
+	if compressible {
+		contentType := header.Get("Content-Type")
+		if contentType == "" && len(pending) > 0 {
+			// Sniff the plain bytes now; net/http would otherwise sniff the
+			// compressed ones.
+			contentType = http.DetectContentType(pending)
+			header.Set("Content-Type", contentType)
+		}
+		compressible = !Skipped(w.cfg, contentType)
+		if compressible {
+			AddVary(header)
+		}
+	}
+
+	if !compressible || !large || w.encoding == "" {
+		w.ResponseWriter.WriteHeader(w.status)
+		if len(pending) == 0 {
+			return nil
+		}
+		_, err := w.ResponseWriter.Write(pending)
+		return err
+	}
+
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", w.encoding)
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		header.Set("ETag", "W/"+etag)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.encoder = newWriter(w.cfg, w.encoding, w.ResponseWriter)
+	Observe(w.encoding, "live")
+	_, err := w.encoder.Write(pending)
+	return err
+}
+
+func (w *responseWriter) finish() {
+	if !w.decided {
+		if w.status == 0 && len(w.buf) == 0 {
+			// The handler wrote nothing; let net/http send its default 200.
+			return
+		}
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		pending := w.buf
+		w.buf = nil
+		if err := w.decide(pending, len(pending) >= w.cfg.MinSize && len(pending) > 0); err != nil {
+			slog.WarnContext(w.request.Context(), "failed to write response", "error", err)
+		}
+	}
+	if w.encoder != nil {
+		if err := w.encoder.Close(); err != nil {
+			slog.WarnContext(w.request.Context(), "failed to finish compressed response", "error", err)
+		}
+	}
+}
+
+// bodyAllowed reports whether a status carries a body worth compressing.
+func bodyAllowed(status int) bool {
+	switch status {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return false
+	default:
+		return status >= 200
+	}
+}
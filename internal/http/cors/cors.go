@@ -0,0 +1,125 @@
+// Package cors answers CORS preflights and adds Access-Control-* headers for
+// the origins listed in the cors config section.
+package cors
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"ss-api/internal/app"
+	"ss-api/internal/config"
+	"ss-api/internal/http/apierror"
+)
+
+// Handler applies the CORS policy in front of next. Every OPTIONS request is
+// answered here, before the mux, so routes registered for GET only do not
+// reply 405 to preflights. Settings are read per request and follow reloads.
+func Handler(appInstance *app.App, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := appInstance.Config().CORS
+		origin := r.Header.Get("Origin")
+		header := w.Header()
+
+		if r.Method == http.MethodOptions {
+			preflight(w, r, cfg, origin)
+			return
+		}
+
+		if len(cfg.AllowedOrigins) > 0 {
+			if !anyOrigin(cfg) {
+				// Shared caches must not hand one origin's answer to another.
+				header.Add("Vary", "Origin")
+			}
+			if origin != "" && allowOrigin(header, cfg, origin) && len(cfg.ExposedHeaders) > 0 {
+				header.Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflight answers OPTIONS. A plain OPTIONS gets 204 with Allow; a CORS
+// preflight from an unknown origin or for a method that is not allowed gets
+// 403 so the reason is visible outside the browser too.
+func preflight(w http.ResponseWriter, r *http.Request, cfg config.CORSConfig, origin string) {
+	header := w.Header()
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	header.Set("Allow", methods)
+
+	requested := r.Header.Get("Access-Control-Request-Method")
+	if origin == "" || requested == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if !anyOrigin(cfg) {
+		header.Add("Vary", "Origin")
+	}
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	if !allowOrigin(header, cfg, origin) {
+		apierror.WriteDetails(w, r, http.StatusForbidden, apierror.CodeOriginDenied, "origin not allowed", apierror.Details{"origin": origin})
+		return
+	}
+	if !slices.Contains(cfg.AllowedMethods, requested) {
+		header.Del("Access-Control-Allow-Origin")
+		header.Del("Access-Control-Allow-Credentials")
+		apierror.WriteDetails(w, r, http.StatusForbidden, apierror.CodeMethodNotAllowed, "method not allowed for cross-origin requests", apierror.Details{"method": requested, "allowed": cfg.AllowedMethods})
+		return
+	}
+
+	header.Set("Access-Control-Allow-Methods", methods)
+	if len(cfg.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+	}
+	header.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowOrigin sets Access-Control-Allow-Origin when origin is allowed and
+// reports whether it did.
+func allowOrigin(header http.Header, cfg config.CORSConfig, origin string) bool {
+	if anyOrigin(cfg) {
+		header.Set("Access-Control-Allow-Origin", "*")
+		return true
+	}
+	if !Allowed(cfg.AllowedOrigins, origin) {
+		return false
+	}
+	header.Set("Access-Control-Allow-Origin", origin)
+	if cfg.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// anyOrigin reports whether every origin gets the same "*" answer.
+func anyOrigin(cfg config.CORSConfig) bool {
+	return slices.Contains(cfg.AllowedOrigins, "*") && !cfg.AllowCredentials
+}
+
+// Allowed reports whether origin matches one of patterns. Scheme and host
+// compare case-insensitively; "https://*.example.com" matches every
+// subdomain of example.com but not example.com itself.
+func Allowed(patterns []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSuffix(pattern, "/"))
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		prefix, suffix, ok := strings.Cut(pattern, "://*.")
+		if !ok {
+			continue
+		}
+		rest, found := strings.CutPrefix(origin, prefix+"://")
+		if found && strings.HasSuffix(rest, "."+suffix) && len(rest) > len(suffix)+1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,411 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"ss-api/internal/apiversion"
+	"ss-api/internal/buildinfo"
+	"ss-api/internal/http/handlers/admin"
+	"ss-api/internal/http/handlers/banner"
+	"ss-api/internal/http/handlers/characters"
+	"ss-api/internal/http/handlers/discs"
+	"ss-api/internal/http/handlers/events"
+	"ss-api/internal/http/handlers/history"
+	"ss-api/internal/http/handlers/news"
+	"ss-api/internal/http/handlers/status"
+	"ss-api/internal/http/handlers/versions"
+	"ss-api/internal/http/routes"
+	"ss-api/internal/metrics"
+)
+
+const (
+	openAPIPath = "/stella/openapi.json"
+	apiTitle    = "Stella Sora API"
+)
+
+var (
+	langParam  = routes.Query("lang", "Region (EN, JP, KR, CN, TW) or a language tag that maps to one. Defaults to Accept-Language, then locale.default.", routes.String())
+	idParam    = routes.Path("identifier", "Numeric ID or name, case-insensitive.")
+	limitParam = routes.Query("limit", "How many to list, up to 100.", routes.Integer().AtLeast(1).WithDefault(20))
+	// formatParam and exportTypes apply to the list routes, which also
+	// answer Accept: text/csv and application/x-ndjson.
+	formatParam = routes.Query("format", "json, csv (nested fields flattened into dotted columns) or ndjson. Overrides Accept.", routes.String().OneOf("json", "csv", "ndjson").WithDefault("json"))
+	exportTypes = []string{"text/csv", "application/x-ndjson"}
+)
+
+// registry declares every route. The mux, the endpoint list of the status
+// payload and the OpenAPI document are all built from it.
+func (s *Server) registry() *routes.Registry {
+	reg := routes.New(func(version string) apiversion.Lifecycle {
+		return s.app.Config().Versions.Lifecycle(version)
+	})
+	h := s.handlers
+	reg.GuardAdmin(h.AdminAuth)
+
+	reg.Add(
+		routes.Route{Pattern: "GET /stella", Handler: h.Status, Hidden: true},
+		routes.Route{
+			Pattern:     "GET /stella/",
+			Handler:     h.Status,
+			OperationID: "getStatus",
+			Summary:     "Status, build, document counts and endpoints",
+			Tag:         "status",
+			Response:    status.Schema(),
+		},
+		routes.Route{
+			Pattern:     "GET /stella/healthz",
+			Handler:     h.Health,
+			OperationID: "getHealth",
+			Summary:     "Liveness probe",
+			Tag:         "status",
+			Response:    status.HealthSchema(),
+		},
+		routes.Route{
+			Pattern:     "GET /stella/readyz",
+			Handler:     h.Ready,
+			OperationID: "getReadiness",
+			Summary:     "Readiness probe",
+			Description: "Answers 503 with the same body when a critical check fails.",
+			Tag:         "status",
+			Response:    status.ReadySchema(),
+		},
+		routes.Route{
+			Pattern:     "GET " + openAPIPath,
+			Handler:     reg.Handler(s.openAPIOptions),
+			OperationID: "getOpenAPI",
+			Summary:     "This OpenAPI document",
+			Tag:         "status",
+			Response:    routes.Map(routes.Any()),
+			Since:       "v1",
+		},
+		routes.Route{
+			Pattern:     "GET /stella/versions",
+			Handler:     h.Versions,
+			OperationID: "listVersions",
+			Summary:     "API versions and their retirement schedule",
+			Tag:         "status",
+			Response:    versions.ListSchema(),
+		},
+		routes.Route{
+			Pattern:     "GET /stella/versions/{version}/changelog",
+			Handler:     h.Changelog,
+			OperationID: "getChangelog",
+			Summary:     "Changes made in one API version",
+			Tag:         "status",
+			Params:      []routes.Param{routes.Path("version", "Version name, e.g. v1.")},
+			Response:    versions.ChangelogSchema(),
+		},
+	)
+
+	if s.app.Config().Server.Docs {
+		reg.Add(routes.Route{
+			Pattern:     "GET /stella/docs",
+			Handler:     routes.DocsHandler(apiTitle, openAPIPath),
+			OperationID: "getDocs",
+			Summary:     "Rendered API reference",
+			Tag:         "status",
+			ContentType: "text/html",
+		})
+	}
+
+	reg.Add(
+		routes.Route{
+			Pattern:     "GET /stella/characters",
+			Handler:     h.Characters,
+			OperationID: "listCharacters",
+			Summary:     "Character summaries",
+			Tag:         "catalog",
+			Params:      []routes.Param{langParam, formatParam},
+			Alternates:  exportTypes,
+			Response:    characters.ListSchema(),
+			Since:       "v1",
+		},
+		routes.Route{
+			Pattern:     "GET /stella/character/{identifier}",
+			Handler:     h.CharacterDetail,
+			OperationID: "getCharacter",
+			Summary:     "Full character document",
+			Tag:         "catalog",
+			Params:      []routes.Param{idParam, langParam},
+			Response:    characters.DetailSchema(),
+			Since:       "v1",
+		},
+		routes.Route{
+			Pattern:     "GET /stella/discs",
+			Handler:     h.Discs,
+			OperationID: "listDiscs",
+			Summary:     "Disc summaries",
+			Tag:         "catalog",
+			Params:      []routes.Param{langParam, formatParam},
+			Alternates:  exportTypes,
+			Response:    discs.ListSchema(),
+			Since:       "v1",
+		},
+		routes.Route{
+			Pattern:     "GET /stella/disc/{identifier}",
+			Handler:     h.DiscDetail,
+			OperationID: "getDisc",
+			Summary:     "Full disc record",
+			Tag:         "catalog",
+			Params:      []routes.Param{idParam, langParam},
+			Response:    discs.DetailSchema(),
+			Since:       "v1",
+		},
+		routes.Route{
+			Pattern:     "GET /stella/banners",
+			Handler:     h.Banner,
+			OperationID: "listBanners",
+			Summary:     "Banners grouped by schedule",
+			Tag:         "catalog",
+			Params:      []routes.Param{langParam, formatParam},
+			Alternates:  exportTypes,
+			Response:    banner.Schema(),
+			Since:       "v1",
+		},
+		routes.Route{
+			Pattern:     "GET /stella/events",
+			Handler:     h.Events,
+			OperationID: "listEvents",
+			Summary:     "Events grouped by schedule",
+			Tag:         "catalog",
+			Params:      []routes.Param{langParam, formatParam},
+			Alternates:  exportTypes,
+			Response:    events.Schema(),
+			Since:       "v1",
+		},
+		routes.Route{
+			Pattern:     "GET /stella/export",
+			Handler:     h.Export,
+			OperationID: "exportCatalog",
+			Summary:     "Zip bundle of a region's catalog",
+			Description: "Characters and discs in full, banners and events as listed; each as JSON and flattened CSV, with a manifest.json.",
+			Tag:         "catalog",
+			Params:      []routes.Param{langParam},
+			ContentType: "application/zip",
+			Since:       "v1",
+		},
+		routes.Route{
+			Pattern:     "GET /stella/changelog",
+			Handler:     h.CatalogLog,
+			OperationID: "listCatalogChanges",
+			Summary:     "What changed in the catalog between data updates",
+			Description: "Newest first. Each change lists the IDs added and removed and, per changed entry, the fields with their old and new values.",
+			Tag:         "catalog",
+			Params: []routes.Param{
+				langParam,
+				routes.Query("type", "characters, discs, banners or events; comma-separated for several.", routes.String()),
+				routes.Query("since", "Earliest detection time: YYYY-MM-DD or RFC 3339.", routes.String()),
+				limitParam,
+			},
+			Response: history.ChangelogSchema(),
+			Since:    "v1",
+		},
+		routes.Route{
+			Pattern:     "GET /stella/character/{identifier}/history",
+			Handler:     h.CharacterLog,
+			OperationID: "getCharacterHistory",
+			Summary:     "Changes of one character, newest first",
+			Tag:         "catalog",
+			Params:      []routes.Param{idParam, langParam, limitParam},
+			Response:    history.HistorySchema(),
+			Since:       "v1",
+		},
+	)
+
+	newsParams := []routes.Param{
+		{Name: "category", In: "path", Required: true, Schema: news.CategorySchema()},
+		langParam,
+		routes.Query("index", "Page number.", routes.Integer().AtLeast(1).WithDefault(1)),
+		routes.Query("size", "Page size.", routes.Integer().AtLeast(1).WithDefault(6)),
+		routes.Query("before", "Cursor from nextCursor, or a publish time in Unix milliseconds. Takes precedence over index.", routes.String()),
+		routes.Query("from", "Earliest publish time: YYYY-MM-DD, RFC 3339 or Unix milliseconds.", routes.String()),
+		routes.Query("to", "Latest publish time; a date covers the whole day.", routes.String()),
+	}
+	reg.Add(
+		routes.Route{
+			Pattern:     "GET /stella/news/{category}",
+			Handler:     h.News,
+			OperationID: "listNews",
+			Summary:     "Official news",
+			Tag:         "news",
+			Params:      newsParams,
+			Response:    news.ListSchema(),
+			Since:       "v1",
+			Aliases:     []string{"/news/{category}"},
+		},
+		routes.Route{
+			Pattern:     "GET /stella/assets/{path...}",
+			Handler:     s.assets,
+			OperationID: "getAsset",
+			Summary:     "Texture by friendly name, or a mirrored news image under news/",
+			Tag:         "assets",
+			Params: []routes.Param{
+				routes.Path("path", "Friendly name such as Amber_portrait.png, or news/{file}."),
+				routes.Query("lang", "Serve the regional variant when one exists. Never negotiated.", routes.String()),
+			},
+			ContentType: "image/*",
+		},
+	)
+
+	if s.app.Config().Server.Metrics {
+		reg.Add(routes.Route{
+			Pattern:     "GET /metrics",
+			Handler:     metrics.Handler(),
+			OperationID: "getMetrics",
+			Summary:     "Prometheus metrics",
+			Tag:         "metrics",
+			ContentType: "text/plain",
+		})
+	}
+
+	// Every admin route requires a bearer token from admin.tokens.
+	adminRoute := func(pattern string, handler http.HandlerFunc, route routes.Route) routes.Route {
+		route.Pattern = pattern
+		route.Handler = handler
+		route.Tag = "admin"
+		route.Admin = true
+		return route
+	}
+	cacheFilter := []routes.Param{
+		routes.Query("route", "Route pattern, e.g. /stella/characters.", routes.String()),
+		routes.Query("region", "Region or lang value.", routes.String()),
+		routes.Query("key", "Exact cache key.", routes.String()),
+	}
+	reg.Add(
+		adminRoute("GET /stella/admin/jobs", h.AdminJobs, routes.Route{
+			OperationID: "listJobs",
+			Summary:     "Background job status",
+			Response:    admin.JobsSchema(),
+		}),
+		adminRoute("POST /stella/admin/jobs/{name}", h.AdminRunJob, routes.Route{
+			OperationID: "runJob",
+			Summary:     "Trigger a background job",
+			Description: "Answers 202 right away, or 200 once the run finished with wait=true.",
+			Params: []routes.Param{
+				routes.Query("wait", "Wait for the run to finish.", routes.Boolean()),
+			},
+			Status:   http.StatusAccepted,
+			Response: admin.JobSchema(),
+		}),
+		adminRoute("POST /stella/admin/reload", h.AdminReload, routes.Route{
+			OperationID: "reloadConfig",
+			Summary:     "Re-read the configuration",
+			Response:    admin.ReloadSchema(),
+		}),
+		adminRoute("GET /stella/admin/config", h.AdminConfig, routes.Route{
+			OperationID: "getConfig",
+			Summary:     "Effective configuration, secrets redacted",
+			Response:    admin.ConfigSchema(),
+		}),
+		adminRoute("GET /stella/admin/cache", h.AdminCache, routes.Route{
+			OperationID: "listCache",
+			Summary:     "Cached responses",
+			Params:      cacheFilter,
+			Response:    admin.CacheSchema(),
+		}),
+		adminRoute("POST /stella/admin/cache/purge", h.AdminCachePurge, routes.Route{
+			OperationID: "purgeCache",
+			Summary:     "Drop cached responses",
+			Params:      append(cacheFilter, routes.Query("all", "Purge everything when no filter is given.", routes.Boolean())),
+			Response:    admin.PurgeSchema(),
+		}),
+		adminRoute("POST /stella/admin/aliases/reload", h.AdminAliasReload, routes.Route{
+			OperationID: "reloadAliases",
+			Summary:     "Reload the character name map",
+			Response:    admin.JobSchema(),
+		}),
+		adminRoute("POST /stella/admin/assets/reload", h.AdminAssetReload, routes.Route{
+			OperationID: "reloadAssets",
+			Summary:     "Rebuild the friendly asset alias table",
+			Response:    admin.JobSchema(),
+		}),
+		adminRoute("POST /stella/admin/news/{region}/{category}/refresh", h.AdminNewsRefresh, routes.Route{
+			OperationID: "refreshNews",
+			Summary:     "Re-fetch one news category from upstream",
+			Params: []routes.Param{
+				routes.Path("region", "News region (global, jp, tw, cn) or a lang value."),
+				{Name: "category", In: "path", Required: true, Schema: news.CategorySchema()},
+			},
+			Response: news.RefreshSchema(),
+		}),
+		adminRoute("GET /stella/admin/keys", h.AdminKeys, routes.Route{
+			OperationID: "listKeys",
+			Summary:     "API keys with usage",
+			Response:    admin.KeysSchema(),
+		}),
+		adminRoute("POST /stella/admin/keys", h.AdminKeyCreate, routes.Route{
+			OperationID: "createKey",
+			Summary:     "Issue an API key",
+			Body:        admin.KeySpecSchema(),
+			Status:      http.StatusCreated,
+			Response:    admin.SecretSchema(),
+		}),
+		adminRoute("GET /stella/admin/keys/{id}", h.AdminKey, routes.Route{
+			OperationID: "getKey",
+			Summary:     "One API key",
+			Response:    admin.KeySchema(),
+		}),
+		adminRoute("PATCH /stella/admin/keys/{id}", h.AdminKeyUpdate, routes.Route{
+			OperationID: "updateKey",
+			Summary:     "Change an API key",
+			Body:        admin.KeyUpdateSchema(),
+			Response:    admin.KeySchema(),
+		}),
+		adminRoute("DELETE /stella/admin/keys/{id}", h.AdminKeyDelete, routes.Route{
+			OperationID: "deleteKey",
+			Summary:     "Revoke an API key",
+			Status:      http.StatusNoContent,
+		}),
+		adminRoute("POST /stella/admin/keys/{id}/rotate", h.AdminKeyRotate, routes.Route{
+			OperationID: "rotateKey",
+			Summary:     "Replace the secret of an API key",
+			Response:    admin.SecretSchema(),
+		}),
+		adminRoute("GET /stella/admin/tiers", h.AdminTiers, routes.Route{
+			OperationID: "listTiers",
+			Summary:     "Rate limit tiers",
+			Response:    admin.TiersSchema(),
+		}),
+		adminRoute("PUT /stella/admin/tiers/{name}", h.AdminTierPut, routes.Route{
+			OperationID: "putTier",
+			Summary:     "Create or replace a rate limit tier",
+			Body:        admin.TierBodySchema(),
+			Response:    admin.TierSchema(),
+		}),
+		adminRoute("DELETE /stella/admin/tiers/{name}", h.AdminTierDelete, routes.Route{
+			OperationID: "deleteTier",
+			Summary:     "Remove a rate limit tier no key uses",
+			Status:      http.StatusNoContent,
+		}),
+		adminRoute("GET /stella/admin/validate", h.AdminValidate, routes.Route{
+			OperationID: "validateCatalog",
+			Summary:     "Check the catalog collections for data problems",
+			Description: "Answers 200 with the report; ok is false when any issue is an error.",
+			Response:    admin.ValidateSchema(),
+		}),
+		adminRoute("GET /stella/admin/imports", h.AdminImports, routes.Route{
+			OperationID: "listImports",
+			Summary:     "List the latest catalog imports",
+			Description: "Newest first, as recorded by stella-import. Dry runs are not recorded.",
+			Params: []routes.Param{
+				routes.Query("limit", "How many imports to list, up to 100.", routes.Integer().AtLeast(1).WithDefault(20)),
+			},
+			Response: admin.ImportsSchema(),
+		}),
+	)
+
+	return reg
+}
+
+// openAPIOptions is read for every document so the key header follows
+// reloads.
+func (s *Server) openAPIOptions() routes.Options {
+	return routes.Options{
+		Info: routes.Info{
+			Title: apiTitle,
+			Description: "Game data for Stella Sora, build " + buildinfo.Get().Version + ". " +
+				"Catalog routes accept lang and Accept-Language; errors share one envelope with a machine-readable code.",
+		},
+		KeyHeader: s.app.Config().RateLimit.KeyHeader,
+	}
+}
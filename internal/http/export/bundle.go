@@ -0,0 +1,213 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"ss-api/internal/app"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/http/respcache"
+	"ss-api/internal/locale"
+)
+
+// Source is one part of the bundle, e.g. "characters". Render returns the
+// JSON list payload of a region; found is false when the region has none.
+// Collections are the Mongo collections it reads; a change to any of them
+// drops the cached bundles.
+type Source struct {
+	Name        string
+	Collections []string
+	Render      func(ctx context.Context, lang locale.Locale) (payload []byte, found bool, err error)
+}
+
+// FromHandler renders a source through a list handler by asking it for
+// path?lang=<region>. A response served from a fallback region counts as
+// not found, so a bundle never mixes regions.
+func FromHandler(name, path string, next http.HandlerFunc, collections ...string) Source {
+	return Source{
+		Name:        name,
+		Collections: collections,
+		Render: func(ctx context.Context, lang locale.Locale) ([]byte, bool, error) {
+			target := path + "?" + url.Values{"lang": {string(lang)}}.Encode()
+			r, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+			if err != nil {
+				return nil, false, err
+			}
+
+			rec := render(next, r)
+			switch {
+			case rec.status == http.StatusNotFound:
+				return nil, false, nil
+			case rec.status != http.StatusOK:
+				return nil, false, fmt.Errorf("%s: status %d", path, rec.status)
+			}
+			if served, ok := locale.Parse(rec.header.Get("Content-Language")); ok && served != lang {
+				return nil, false, nil
+			}
+			return rec.body.Bytes(), true, nil
+		},
+	}
+}
+
+// manifest describes the files of a bundle.
+type manifest struct {
+	Region locale.Locale  `json:"region"`
+	Files  []manifestFile `json:"files"`
+}
+
+type manifestFile struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Format  string `json:"format"`
+	Entries int    `json:"entries"`
+}
+
+type bundleHandler struct {
+	app     *app.App
+	sources []Source
+}
+
+// NewBundle serves the catalog of a region as a zip file: <name>.json and
+// <name>.csv for every source with data, and manifest.json listing them.
+// Regions without any data fall back like the list routes do. The archive
+// carries no timestamps, so unchanged data keeps its ETag. Bundles are
+// stored for the schedule TTL, since they hold the banners and events.
+func NewBundle(appInstance *app.App, cache *respcache.Store, sources ...Source) http.HandlerFunc {
+	h := bundleHandler{app: appInstance, sources: sources}
+
+	var collections []string
+	for _, source := range sources {
+		for _, collection := range source.Collections {
+			if !slices.Contains(collections, collection) {
+				collections = append(collections, collection)
+			}
+		}
+	}
+
+	return cache.Handler(respcache.Policy{
+		Name:        "export.bundle",
+		Route:       "/stella/export",
+		Collections: collections,
+		TTL: func() time.Duration {
+			return appInstance.Config().Cache.ScheduleTTL
+		},
+		CacheControl: respcache.Public(5 * time.Minute),
+	}, h.handle)
+}
+
+func (h bundleHandler) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	sel, ok := h.app.Config().Locale.Negotiator().Resolve(r)
+	if !ok {
+		apierror.UnsupportedLang(w, r, r.URL.Query().Get("lang"))
+		return
+	}
+
+	if h.app.MongoClient() == nil {
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeUnavailable, "service unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.app.Config().Server.RequestTimeout)
+	defer cancel()
+
+	for _, lang := range sel.Chain {
+		body, found, err := h.build(ctx, lang)
+		if err != nil {
+			apierror.InternalError(w, r, err)
+			return
+		}
+		if !found {
+			continue
+		}
+
+		locale.SetHeaders(w.Header(), lang)
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "stella-" + string(lang) + ".zip"}))
+		if _, err := w.Write(body); err != nil {
+			slog.WarnContext(r.Context(), "failed to write response", "error", err)
+		}
+		return
+	}
+
+	apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeNoData, "no catalog data found", apierror.Details{"lang": sel.Locale})
+}
+
+// build writes the bundle of one region. found is false when no source has
+// data for it.
+func (h bundleHandler) build(ctx context.Context, lang locale.Locale) ([]byte, bool, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	index := manifest{Region: lang, Files: []manifestFile{}}
+
+	for _, source := range h.sources {
+		payload, found, err := source.Render(ctx, lang)
+		if err != nil {
+			return nil, false, fmt.Errorf("export %s: %w", source.Name, err)
+		}
+		if !found {
+			continue
+		}
+
+		rows, err := Rows(payload)
+		if err != nil {
+			return nil, false, fmt.Errorf("export %s: %w", source.Name, err)
+		}
+		table, err := encodeCSV(rows)
+		if err != nil {
+			return nil, false, fmt.Errorf("export %s: %w", source.Name, err)
+		}
+
+		for _, file := range []struct {
+			format string
+			body   []byte
+		}{
+			{JSON, payload},
+			{CSV, table},
+		} {
+			name := source.Name + "." + file.format
+			if err := addFile(archive, name, file.body); err != nil {
+				return nil, false, err
+			}
+			index.Files = append(index.Files, manifestFile{Name: name, Type: source.Name, Format: file.format, Entries: len(rows)})
+		}
+	}
+
+	if len(index.Files) == 0 {
+		return nil, false, nil
+	}
+
+	body, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, false, err
+	}
+	if err := addFile(archive, "manifest.json", append(body, '\n')); err != nil {
+		return nil, false, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
+
+func addFile(archive *zip.Writer, name string, body []byte) error {
+	f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(body)
+	return err
+}
@@ -0,0 +1,477 @@
+// Package export renders the list endpoints in tabular formats and bundles
+// a region's catalog into a zip file. Lists are rendered by their JSON
+// handler, through the response cache, and converted per request:
+//
+//   - csv: one row per entry, nested objects flattened into dotted column
+//     names such as "skill.name". Arrays of plain values are joined with
+//     "; "; arrays holding objects are flattened by position, e.g.
+//     "rateUp.fiveStar.entries.0.name". Cells that a spreadsheet would
+//     run as a formula are prefixed with a quote.
+//   - ndjson: one JSON entry per line.
+//
+// Grouped payloads, such as banners under current, permanent, upcoming
+// and ended, get a leading "group" column or field.
+package export
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/locale"
+	"ss-api/internal/model"
+)
+
+// Formats, as accepted by the format parameter.
+const (
+	JSON   = "json"
+	CSV    = "csv"
+	NDJSON = "ndjson"
+)
+
+// Names lists the formats, for parameter docs and errors.
+var Names = []string{JSON, CSV, NDJSON}
+
+var contentTypes = map[string]string{
+	CSV:    "text/csv; charset=utf-8",
+	NDJSON: "application/x-ndjson",
+}
+
+// Negotiate picks the format of a request: the format parameter when
+// given, otherwise text/csv or application/x-ndjson in Accept, otherwise
+// JSON. ok is false for an unknown format parameter.
+func Negotiate(r *http.Request) (format string, ok bool) {
+	if value := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))); value != "" {
+		switch value {
+		case JSON, CSV, NDJSON:
+			return value, true
+		}
+		return "", false
+	}
+
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch strings.ToLower(mediaType) {
+		case "text/csv":
+			return CSV, true
+		case "application/x-ndjson", "application/ndjson":
+			return NDJSON, true
+		case "application/json":
+			return JSON, true
+		}
+	}
+	return JSON, true
+}
+
+// Handler serves next as JSON, or converts its JSON list to the negotiated
+// format. name is the base of the suggested file name, e.g. "characters".
+func Handler(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := Negotiate(r)
+		if !ok {
+			value := r.URL.Query().Get("format")
+			apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeInvalidParameter,
+				"unsupported format "+strconv.Quote(value), apierror.Details{"parameter": "format", "value": value, "supported": Names})
+			return
+		}
+		if format == JSON {
+			next(&varyWriter{ResponseWriter: w}, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		rec := render(next, r)
+		if rec.status != http.StatusOK {
+			rec.flush(w)
+			return
+		}
+
+		rows, err := Rows(rec.body.Bytes())
+		if err != nil {
+			apierror.InternalError(w, r, fmt.Errorf("export %s as %s: %w", name, format, err))
+			return
+		}
+		var table []byte
+		if format == CSV {
+			if table, err = encodeCSV(rows); err != nil {
+				apierror.InternalError(w, r, fmt.Errorf("export %s as %s: %w", name, format, err))
+				return
+			}
+		}
+
+		header := w.Header()
+		for _, key := range []string{"Content-Language", "Cache-Control", "Last-Modified"} {
+			if values := rec.header.Values(key); len(values) > 0 {
+				header[key] = values
+			}
+		}
+		mergeVary(header, rec.header.Values("Vary"))
+		addVary(header)
+		header.Set("Content-Type", contentTypes[format])
+		if format == CSV {
+			filename := name
+			if served := rec.header.Get("Content-Language"); served != "" {
+				if l, ok := locale.Parse(served); ok {
+					filename += "-" + string(l)
+				}
+			}
+			header.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filename + ".csv"}))
+		}
+
+		// The conversion is deterministic, so the ETag hashes the format
+		// and the JSON it was converted from.
+		hash := sha256.New()
+		hash.Write([]byte(format + "\n"))
+		hash.Write(rec.body.Bytes())
+		etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+		header.Set("ETag", etag)
+		if match := r.Header.Get("If-None-Match"); match != "" && etagListed(match, etag) {
+			header.Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		if format == NDJSON {
+			// NDJSON is streamed line by line, so it has no Content-Length.
+			w.WriteHeader(http.StatusOK)
+			if r.Method == http.MethodHead {
+				return
+			}
+			rc := http.NewResponseController(w)
+			if err := writeNDJSON(w, rows, func() { _ = rc.Flush() }); err != nil {
+				slog.WarnContext(r.Context(), "failed to write response", "error", err)
+			}
+			return
+		}
+
+		header.Set("Content-Length", strconv.Itoa(len(table)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodHead {
+			return
+		}
+		if _, err := w.Write(table); err != nil {
+			slog.WarnContext(r.Context(), "failed to write response", "error", err)
+		}
+	}
+}
+
+// addVary adds Accept to the Vary header, since the format is negotiated.
+func addVary(header http.Header) {
+	mergeVary(header, []string{"Accept"})
+}
+
+// mergeVary adds the names in values that the Vary header does not list
+// yet. Names set before the handler ran, such as Origin from CORS, are
+// kept.
+func mergeVary(header http.Header, values []string) {
+	listed := map[string]bool{}
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			listed[strings.ToLower(strings.TrimSpace(name))] = true
+		}
+	}
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" || listed["*"] || listed[strings.ToLower(name)] {
+				continue
+			}
+			listed[strings.ToLower(name)] = true
+			header.Add("Vary", name)
+		}
+	}
+}
+
+// varyWriter adds Accept to Vary once next has set its own headers.
+type varyWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *varyWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		addVary(w.Header())
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *varyWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *varyWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func etagListed(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Convert turns a JSON list payload, an array of objects or an object of
+// such arrays, into format.
+func Convert(format string, payload []byte) ([]byte, error) {
+	rows, err := Rows(payload)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case CSV:
+		return encodeCSV(rows)
+	case NDJSON:
+		return encodeNDJSON(rows)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// Rows reads the entries of a list payload in order. Entries of a grouped
+// payload get their group name as a leading "group" field.
+func Rows(payload []byte) ([]model.Document, error) {
+	trimmed := bytes.TrimSpace(payload)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var rows []model.Document
+		if err := json.Unmarshal(trimmed, &rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+
+	var groups model.Document
+	if err := json.Unmarshal(trimmed, &groups); err != nil {
+		return nil, err
+	}
+	rows := []model.Document{}
+	for _, group := range groups {
+		if group.Value == nil {
+			continue
+		}
+		entries, ok := group.Value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected an array of entries", group.Key)
+		}
+		for _, entry := range entries {
+			doc, ok := entry.(model.Document)
+			if !ok {
+				return nil, fmt.Errorf("%s: expected objects", group.Key)
+			}
+			rows = append(rows, append(model.Document{{Key: "group", Value: group.Key}}, doc...))
+		}
+	}
+	return rows, nil
+}
+
+// ndjsonFlushRows is how many NDJSON lines are written between flushes.
+const ndjsonFlushRows = 100
+
+func encodeNDJSON(rows []model.Document) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeNDJSON(&buf, rows, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeNDJSON writes one line per row as it is encoded and calls flush,
+// when set, every ndjsonFlushRows lines.
+func writeNDJSON(w io.Writer, rows []model.Document, flush func()) error {
+	for i, row := range rows {
+		line, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		if flush != nil && (i+1)%ndjsonFlushRows == 0 {
+			flush()
+		}
+	}
+	return nil
+}
+
+// encodeCSV writes a header row with every column in first-seen order and
+// one row per entry; missing cells are empty.
+func encodeCSV(rows []model.Document) ([]byte, error) {
+	var columns []string
+	index := map[string]int{}
+	flat := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		cells := map[string]string{}
+		flatten("", row, func(column, value string) {
+			if _, known := index[column]; !known {
+				index[column] = len(columns)
+				columns = append(columns, column)
+			}
+			cells[column] = value
+		})
+		flat[i] = cells
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	record := make([]string, len(columns))
+	for i, column := range columns {
+		record[i] = neutralise(column)
+	}
+	if err := writer.Write(record); err != nil {
+		return nil, err
+	}
+	for _, cells := range flat {
+		for i, column := range columns {
+			record[i] = neutralise(cells[column])
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// flatten emits the cells of value under prefix.
+func flatten(prefix string, value any, emit func(column, value string)) {
+	switch v := value.(type) {
+	case model.Document:
+		for _, f := range v {
+			flatten(join(prefix, f.Key), f.Value, emit)
+		}
+	case []any:
+		if !nested(v) {
+			parts := make([]string, len(v))
+			for i, item := range v {
+				parts[i] = scalar(item)
+			}
+			emit(prefix, strings.Join(parts, "; "))
+			return
+		}
+		for i, item := range v {
+			flatten(join(prefix, strconv.Itoa(i)), item, emit)
+		}
+	default:
+		emit(prefix, scalar(v))
+	}
+}
+
+func nested(values []any) bool {
+	for _, v := range values {
+		switch v.(type) {
+		case model.Document, []any:
+			return true
+		}
+	}
+	return false
+}
+
+func scalar(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
+
+// formulaPrefixes start a formula when a spreadsheet opens the CSV.
+const formulaPrefixes = "=+-@\t\r"
+
+// neutralise keeps a cell from being run as a formula by prefixing it with
+// a quote. Numbers such as -5 are left alone.
+func neutralise(cell string) string {
+	if cell == "" || !strings.ContainsRune(formulaPrefixes, rune(cell[0])) {
+		return cell
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil {
+		return cell
+	}
+	return "'" + cell
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// recorder captures a response so it can be converted.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func render(next http.HandlerFunc, r *http.Request) *recorder {
+	// The JSON is converted, so ask for it uncompressed and unconditional.
+	inner := r.Clone(r.Context())
+	inner.Header.Del("Accept-Encoding")
+	inner.Header.Del("If-None-Match")
+	inner.Header.Del("If-Modified-Since")
+	inner.Method = http.MethodGet
+
+	rec := &recorder{header: http.Header{}}
+	next(rec, inner)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec
+}
+
+func (rec *recorder) Header() http.Header { return rec.header }
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
+
+// flush copies an unconverted response, such as an error, to w.
+func (rec *recorder) flush(w http.ResponseWriter) {
+	header := w.Header()
+	for key, values := range rec.header {
+		if key == "Vary" {
+			mergeVary(header, values)
+			continue
+		}
+		header[key] = values
+	}
+	w.WriteHeader(rec.status)
+	if _, err := w.Write(rec.body.Bytes()); err != nil && !errors.Is(err, http.ErrBodyNotAllowed) {
+		slog.Warn("failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,111 @@
+package export
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHandlerKeepsOuterVary(t *testing.T) {
+	list := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		if r.URL.Query().Get("lang") == "XX" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `[{"id":1}]`)
+	}
+
+	for name, target := range map[string]string{
+		"converted": "/characters?format=csv",
+		"error":     "/characters?format=csv&lang=XX",
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			// CORS sets Origin before the handler runs.
+			rec.Header().Add("Vary", "Origin")
+			Handler("characters", list)(rec, httptest.NewRequest(http.MethodGet, target, nil))
+
+			var got []string
+			for _, value := range rec.Header().Values("Vary") {
+				for _, name := range strings.Split(value, ",") {
+					got = append(got, strings.TrimSpace(name))
+				}
+			}
+			for _, want := range []string{"Origin", "Accept-Language"} {
+				if !slices.Contains(got, want) {
+					t.Errorf("Vary = %q, missing %s", got, want)
+				}
+			}
+			if n := len(got); n != len(slices.Compact(slices.Sorted(slices.Values(got)))) {
+				t.Errorf("Vary = %q, has duplicates", got)
+			}
+		})
+	}
+}
+
+func TestEncodeCSVNeutralisesFormulas(t *testing.T) {
+	rows, err := Rows([]byte(`[
+		{"name": "=HYPERLINK(\"http://evil\")", "tags": ["@SUM(A1)", "Fire"], "note": "+1", "hp": -5, "skill": "-cmd", "plain": "Amber"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := encodeCSV(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "name,tags,note,hp,skill,plain\n" +
+		`"'=HYPERLINK(""http://evil"")",'@SUM(A1); Fire,+1,-5,'-cmd,Amber` + "\n"
+	if got := string(table); got != want {
+		t.Errorf("csv =\n%s\nwant\n%s", got, want)
+	}
+	if strings.Contains(string(table), ",=") {
+		t.Error("a cell still starts with =")
+	}
+}
+
+func TestHandlerStreamsNDJSON(t *testing.T) {
+	var payload strings.Builder
+	payload.WriteString("[")
+	for i := range ndjsonFlushRows + 1 {
+		if i > 0 {
+			payload.WriteString(",")
+		}
+		payload.WriteString(`{"id":1}`)
+	}
+	payload.WriteString("]")
+	list := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, payload.String())
+	}
+
+	rec := httptest.NewRecorder()
+	Handler("characters", list)(rec, httptest.NewRequest(http.MethodGet, "/characters?format=ndjson", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if !rec.Flushed {
+		t.Error("the response was not flushed while streaming")
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Errorf("Content-Length = %s, want none on a stream", rec.Header().Get("Content-Length"))
+	}
+	if n := strings.Count(rec.Body.String(), "\n"); n != ndjsonFlushRows+1 {
+		t.Errorf("lines = %d, want %d", n, ndjsonFlushRows+1)
+	}
+	etag := rec.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/characters?format=ndjson", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	Handler("characters", list)(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("revalidation status = %d, want 304", rec.Code)
+	}
+}
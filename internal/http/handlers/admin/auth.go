@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"ss-api/internal/app"
+	"ss-api/internal/http/apierror"
+)
+
+// NewAuth returns middleware that only lets requests through with one of the
+// admin.tokens as a bearer token. Tokens are read on every request so a
+// reload rotates them without a restart. With no tokens configured every
+// admin route answers 403.
+func NewAuth(appInstance *app.App) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			tokens := appInstance.Config().Admin.Tokens
+			if len(tokens) == 0 {
+				apierror.Write(w, r, http.StatusForbidden, apierror.CodeAdminDisabled, "admin API disabled: no admin.tokens configured")
+				return
+			}
+
+			presented, ok := bearerToken(r)
+			if !ok || !tokenAllowed(presented, tokens) {
+				if ok {
+					slog.WarnContext(r.Context(), "admin: rejected token", "path", r.URL.Path, "remote_addr", r.RemoteAddr)
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer realm="stella-admin"`)
+				apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeUnauthorized, "missing or invalid bearer token")
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// tokenAllowed compares hashes in constant time so neither the content nor
+// the length of a configured token leaks through timing.
+func tokenAllowed(presented string, tokens []string) bool {
+	sum := sha256.Sum256([]byte(presented))
+	allowed := 0
+	for _, token := range tokens {
+		expected := sha256.Sum256([]byte(token))
+		allowed |= subtle.ConstantTimeCompare(sum[:], expected[:])
+	}
+	return allowed == 1
+}
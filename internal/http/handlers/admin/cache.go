@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/http/respcache"
+	"ss-api/internal/locale"
+)
+
+type CacheHandler struct {
+	cache *respcache.Store
+}
+
+// NewCache lists cached responses. route, region and key narrow the list the
+// same way they narrow a purge.
+func NewCache(cache *respcache.Store) http.HandlerFunc {
+	h := CacheHandler{cache: cache}
+	return h.handleList
+}
+
+// NewCachePurge drops cached responses by route, region or key. At least one
+// filter, or all=true, is required so an empty request cannot flush the
+// whole cache by accident.
+func NewCachePurge(cache *respcache.Store) http.HandlerFunc {
+	h := CacheHandler{cache: cache}
+	return h.handlePurge
+}
+
+func (h CacheHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	entries := h.cache.Entries(cacheFilter(r))
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total":   len(entries),
+		"entries": entries,
+	})
+}
+
+func (h CacheHandler) handlePurge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.MethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	filter := cacheFilter(r)
+	if filter == (respcache.Filter{}) && !isTruthy(r.URL.Query().Get("all")) {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, "route, region or key is required; pass all=true to purge everything")
+		return
+	}
+
+	purged := h.cache.Purge(filter)
+	slog.InfoContext(r.Context(), "admin: cache purged",
+		"route", filter.Route, "region", filter.Region, "key", filter.Key, "entries", purged)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"purged": purged,
+	})
+}
+
+func cacheFilter(r *http.Request) respcache.Filter {
+	query := r.URL.Query()
+	region := strings.TrimSpace(query.Get("region"))
+	if region == "" {
+		region = strings.TrimSpace(query.Get("lang"))
+	}
+	if l, ok := locale.Parse(region); ok {
+		region = string(l)
+	}
+	return respcache.Filter{
+		Route:  strings.TrimSpace(query.Get("route")),
+		Region: region,
+		Key:    query.Get("key"),
+	}
+}
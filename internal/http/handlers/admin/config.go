@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"net/http"
+
+	"ss-api/internal/app"
+	"ss-api/internal/config"
+	"ss-api/internal/http/apierror"
+)
+
+type ConfigHandler struct {
+	app *app.App
+}
+
+// NewConfig dumps the running configuration with secrets redacted, in the
+// same shape as the config file, along with the keys a reload can change.
+func NewConfig(appInstance *app.App) http.HandlerFunc {
+	h := ConfigHandler{app: appInstance}
+	return h.handle
+}
+
+func (h ConfigHandler) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"config":     h.app.Config().Redacted(),
+		"reloadable": config.ReloadablePaths(),
+	})
+}
@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ss-api/internal/app"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/importer"
+)
+
+// Import history page sizes.
+const (
+	defaultImportLimit = 20
+	maxImportLimit     = 100
+)
+
+type ImportsHandler struct {
+	app *app.App
+}
+
+// NewImports lists the latest catalog imports recorded by stella-import,
+// newest first. limit picks how many, up to 100.
+func NewImports(appInstance *app.App) http.HandlerFunc {
+	h := ImportsHandler{app: appInstance}
+	return h.handle
+}
+
+func (h ImportsHandler) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	limit := defaultImportLimit
+	if value := strings.TrimSpace(r.URL.Query().Get("limit")); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxImportLimit {
+			apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeInvalidParameter,
+				"limit must be an integer from 1 to 100", apierror.Details{"parameter": "limit", "value": value})
+			return
+		}
+		limit = n
+	}
+
+	client := h.app.MongoClient()
+	if client == nil {
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeUnavailable, "service unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.app.Config().Server.RequestTimeout)
+	defer cancel()
+
+	imports, err := importer.History(ctx, client.Database(h.app.DatabaseName()), limit)
+	if err != nil {
+		apierror.InternalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total":   len(imports),
+		"imports": imports,
+	})
+}
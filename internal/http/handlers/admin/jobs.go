@@ -0,0 +1,124 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"ss-api/internal/app"
+	"ss-api/internal/http/apierror"
+)
+
+type JobsHandler struct {
+	app *app.App
+	// job fixes the job to run instead of taking it from the path.
+	job string
+}
+
+// NewJobs lists every background job with its last outcome and next run.
+func NewJobs(appInstance *app.App) http.HandlerFunc {
+	h := JobsHandler{app: appInstance}
+	return h.handleList
+}
+
+// NewRunJob triggers a job by name. By default the job is queued and the
+// handler answers 202; with ?wait=true it runs synchronously and the response
+// carries the resulting status.
+func NewRunJob(appInstance *app.App) http.HandlerFunc {
+	h := JobsHandler{app: appInstance}
+	return h.handleRun
+}
+
+// NewRebuild runs one job synchronously, e.g. the alias or asset rebuild, so
+// the response only arrives once the new data is live.
+func NewRebuild(appInstance *app.App, job string) http.HandlerFunc {
+	h := JobsHandler{app: appInstance, job: job}
+	return h.handleRun
+}
+
+func (h JobsHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"jobs": h.app.Scheduler().Jobs(),
+	})
+}
+
+func (h JobsHandler) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.MethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	name := h.job
+	if name == "" {
+		name = strings.TrimSpace(r.PathValue("name"))
+	}
+	scheduler := h.app.Scheduler()
+
+	if h.job == "" && !isTruthy(r.URL.Query().Get("wait")) {
+		if err := scheduler.Trigger(name); err != nil {
+			writeJobError(w, r, name, err)
+			return
+		}
+
+		status, _ := scheduler.Job(name)
+		writeJSON(w, http.StatusAccepted, status)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+
+	runErr := scheduler.Run(ctx, name)
+	if errors.Is(runErr, app.ErrUnknownJob) {
+		writeJobError(w, r, name, runErr)
+		return
+	}
+
+	status, err := scheduler.Job(name)
+	if err != nil {
+		writeJobError(w, r, name, err)
+		return
+	}
+
+	code := http.StatusOK
+	if runErr != nil {
+		code = http.StatusInternalServerError
+	}
+	writeJSON(w, code, status)
+}
+
+func writeJobError(w http.ResponseWriter, r *http.Request, name string, err error) {
+	if errors.Is(err, app.ErrUnknownJob) {
+		apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeJobNotFound, err.Error(), apierror.Details{"job": name})
+		return
+	}
+
+	slog.ErrorContext(r.Context(), "admin: job error", "job", name, "error", err)
+	apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "internal server error")
+}
+
+func isTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		slog.Warn("failed to write response", "error", err)
+	}
+}
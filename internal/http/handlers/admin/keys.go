@@ -0,0 +1,301 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"ss-api/internal/apikeys"
+	"ss-api/internal/app"
+	"ss-api/internal/http/apierror"
+)
+
+// maxBodyBytes bounds the JSON bodies accepted by the key and tier routes.
+const maxBodyBytes = 64 << 10
+
+type KeysHandler struct {
+	app  *app.App
+	keys *apikeys.Store
+}
+
+// keyView is a key as listed by the admin API, with its usage since start.
+type keyView struct {
+	apikeys.Key
+	Usage apikeys.Usage `json:"usage"`
+}
+
+// NewKeys lists every API key with its request counters. Secrets are never
+// listed; prefix identifies a key.
+func NewKeys(appInstance *app.App, keys *apikeys.Store) http.HandlerFunc {
+	h := KeysHandler{app: appInstance, keys: keys}
+	return h.handleList
+}
+
+// NewKey shows one key by ID.
+func NewKey(appInstance *app.App, keys *apikeys.Store) http.HandlerFunc {
+	h := KeysHandler{app: appInstance, keys: keys}
+	return h.handleGet
+}
+
+// NewKeyCreate issues a key from {"name", "tier"} and optional per-key
+// requestsPerMinute and burst. The secret is only in this response.
+func NewKeyCreate(appInstance *app.App, keys *apikeys.Store) http.HandlerFunc {
+	h := KeysHandler{app: appInstance, keys: keys}
+	return h.handleCreate
+}
+
+// NewKeyUpdate changes the name, tier, overrides or disabled flag of a key.
+func NewKeyUpdate(appInstance *app.App, keys *apikeys.Store) http.HandlerFunc {
+	h := KeysHandler{app: appInstance, keys: keys}
+	return h.handleUpdate
+}
+
+// NewKeyRotate replaces the secret of a key and returns the new one.
+func NewKeyRotate(appInstance *app.App, keys *apikeys.Store) http.HandlerFunc {
+	h := KeysHandler{app: appInstance, keys: keys}
+	return h.handleRotate
+}
+
+// NewKeyDelete revokes a key.
+func NewKeyDelete(appInstance *app.App, keys *apikeys.Store) http.HandlerFunc {
+	h := KeysHandler{app: appInstance, keys: keys}
+	return h.handleDelete
+}
+
+// NewTiers lists the rate limit tiers.
+func NewTiers(appInstance *app.App, keys *apikeys.Store) http.HandlerFunc {
+	h := KeysHandler{app: appInstance, keys: keys}
+	return h.handleTiers
+}
+
+// NewTierPut creates or replaces the tier named in the path.
+func NewTierPut(appInstance *app.App, keys *apikeys.Store) http.HandlerFunc {
+	h := KeysHandler{app: appInstance, keys: keys}
+	return h.handleTierPut
+}
+
+// NewTierDelete removes a tier no key is assigned to.
+func NewTierDelete(appInstance *app.App, keys *apikeys.Store) http.HandlerFunc {
+	h := KeysHandler{app: appInstance, keys: keys}
+	return h.handleTierDelete
+}
+
+func (h KeysHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	keys := h.keys.Keys()
+	views := make([]keyView, 0, len(keys))
+	for _, key := range keys {
+		views = append(views, h.view(key))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"total": len(views),
+		"keys":  views,
+	})
+}
+
+func (h KeysHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	key, ok := h.keys.Key(r.PathValue("id"))
+	if !ok {
+		apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeAPIKeyNotFound, fmt.Sprintf("unknown key %q", r.PathValue("id")), apierror.Details{"id": r.PathValue("id")})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.view(key))
+}
+
+func (h KeysHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.MethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	var spec apikeys.KeySpec
+	if !decodeBody(w, r, &spec) {
+		return
+	}
+
+	ctx, cancel := h.context(r)
+	defer cancel()
+
+	key, secret, err := h.keys.CreateKey(ctx, spec)
+	if err != nil {
+		writeKeyError(w, r, apierror.CodeAPIKeyNotFound, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "admin: api key created", "key", key.ID, "name", key.Name, "tier", key.Tier)
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"key":    h.view(key),
+		"secret": secret,
+	})
+}
+
+func (h KeysHandler) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		apierror.MethodNotAllowed(w, r, http.MethodPatch)
+		return
+	}
+
+	var update apikeys.KeyUpdate
+	if !decodeBody(w, r, &update) {
+		return
+	}
+
+	ctx, cancel := h.context(r)
+	defer cancel()
+
+	key, err := h.keys.UpdateKey(ctx, r.PathValue("id"), update)
+	if err != nil {
+		writeKeyError(w, r, apierror.CodeAPIKeyNotFound, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "admin: api key updated", "key", key.ID, "tier", key.Tier, "disabled", key.Disabled)
+	writeJSON(w, http.StatusOK, h.view(key))
+}
+
+func (h KeysHandler) handleRotate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.MethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	ctx, cancel := h.context(r)
+	defer cancel()
+
+	key, secret, err := h.keys.RotateKey(ctx, r.PathValue("id"))
+	if err != nil {
+		writeKeyError(w, r, apierror.CodeAPIKeyNotFound, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "admin: api key rotated", "key", key.ID)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"key":    h.view(key),
+		"secret": secret,
+	})
+}
+
+func (h KeysHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		apierror.MethodNotAllowed(w, r, http.MethodDelete)
+		return
+	}
+
+	ctx, cancel := h.context(r)
+	defer cancel()
+
+	id := r.PathValue("id")
+	if err := h.keys.DeleteKey(ctx, id); err != nil {
+		writeKeyError(w, r, apierror.CodeAPIKeyNotFound, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "admin: api key deleted", "key", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h KeysHandler) handleTiers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"anonymous": apikeys.Anonymous(h.app.Config().RateLimit),
+		"tiers":     h.keys.Tiers(),
+	})
+}
+
+func (h KeysHandler) handleTierPut(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		apierror.MethodNotAllowed(w, r, http.MethodPut)
+		return
+	}
+
+	var tier apikeys.Tier
+	if !decodeBody(w, r, &tier) {
+		return
+	}
+	tier.Name = strings.TrimSpace(r.PathValue("name"))
+
+	ctx, cancel := h.context(r)
+	defer cancel()
+
+	tier, err := h.keys.PutTier(ctx, tier)
+	if err != nil {
+		writeKeyError(w, r, apierror.CodeTierNotFound, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "admin: rate limit tier saved", "tier", tier.Name,
+		"requestsPerMinute", tier.RequestsPerMinute, "burst", tier.Burst, "unlimited", tier.Unlimited)
+	writeJSON(w, http.StatusOK, tier)
+}
+
+func (h KeysHandler) handleTierDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		apierror.MethodNotAllowed(w, r, http.MethodDelete)
+		return
+	}
+
+	ctx, cancel := h.context(r)
+	defer cancel()
+
+	name := r.PathValue("name")
+	if err := h.keys.DeleteTier(ctx, name); err != nil {
+		writeKeyError(w, r, apierror.CodeTierNotFound, err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "admin: rate limit tier deleted", "tier", name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h KeysHandler) view(key apikeys.Key) keyView {
+	return keyView{Key: key, Usage: h.keys.Usage(key.ID)}
+}
+
+func (h KeysHandler) context(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.Context(), h.app.Config().Server.RequestTimeout)
+}
+
+// decodeBody reads a JSON request body into v, answering 400 when it is
+// malformed or has unknown fields.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeInvalidBody, fmt.Sprintf("invalid JSON body: %v", err))
+		return false
+	}
+	return true
+}
+
+// writeKeyError maps store errors to responses. notFound is the code for
+// an unknown key or tier, depending on the route.
+func writeKeyError(w http.ResponseWriter, r *http.Request, notFound string, err error) {
+	switch {
+	case errors.Is(err, apikeys.ErrNotFound):
+		apierror.Write(w, r, http.StatusNotFound, notFound, "not found")
+	case errors.Is(err, apikeys.ErrInvalid):
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+	case errors.Is(err, apikeys.ErrTierInUse):
+		apierror.Write(w, r, http.StatusConflict, apierror.CodeTierInUse, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "admin: api key store error", "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "internal server error")
+	}
+}
@@ -0,0 +1,68 @@
+package admin
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"ss-api/internal/app"
+	"ss-api/internal/config"
+	"ss-api/internal/http/apierror"
+)
+
+type ReloadHandler struct {
+	app *app.App
+}
+
+// NewReload re-reads the configuration the same way SIGHUP does and applies
+// the reloadable settings. A change to a restart-only setting rejects the
+// whole reload with 409 and the offending paths.
+func NewReload(appInstance *app.App) http.HandlerFunc {
+	h := ReloadHandler{app: appInstance}
+	return h.handle
+}
+
+type reloadResponse struct {
+	Status     string   `json:"status"`
+	Changed    []string `json:"changed"`
+	Reloadable []string `json:"reloadable"`
+}
+
+func (h ReloadHandler) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.MethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	changes, err := h.app.Reload()
+
+	var restartErr *config.RestartRequiredError
+	switch {
+	case errors.As(err, &restartErr):
+		apierror.WriteDetails(w, r, http.StatusConflict, apierror.CodeRestartRequired, restartErr.Error(), apierror.Details{
+			"restartRequired": restartErr.Paths,
+		})
+		return
+	case errors.Is(err, app.ErrNoConfigLoader):
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeUnavailable, err.Error())
+		return
+	case err != nil:
+		slog.WarnContext(r.Context(), "admin: config reload failed", "error", err)
+		apierror.Write(w, r, http.StatusBadRequest, apierror.CodeBadRequest, err.Error())
+		return
+	}
+
+	response := reloadResponse{
+		Status:     "unchanged",
+		Changed:    make([]string, 0, len(changes)),
+		Reloadable: config.ReloadablePaths(),
+	}
+	for _, change := range changes {
+		response.Changed = append(response.Changed, change.Path)
+	}
+	if len(response.Changed) > 0 {
+		response.Status = "reloaded"
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
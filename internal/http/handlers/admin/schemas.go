@@ -0,0 +1,123 @@
+package admin
+
+import (
+	"ss-api/internal/apikeys"
+	"ss-api/internal/app"
+	"ss-api/internal/http/respcache"
+	"ss-api/internal/http/routes"
+	"ss-api/internal/importer"
+	"ss-api/internal/validate"
+)
+
+// Schemas of the admin payloads, for the OpenAPI document.
+
+// JobsSchema describes the job list.
+func JobsSchema() *routes.Schema {
+	return routes.Object(map[string]*routes.Schema{
+		"jobs": routes.Array(JobSchema()),
+	}, "jobs")
+}
+
+// JobSchema describes the status of one job.
+func JobSchema() *routes.Schema {
+	return routes.SchemaOf(app.JobStatus{}).Named("Job")
+}
+
+// ReloadSchema describes the result of a configuration reload.
+func ReloadSchema() *routes.Schema {
+	return routes.SchemaOf(reloadResponse{}).Named("Reload")
+}
+
+// ConfigSchema describes the redacted configuration dump.
+func ConfigSchema() *routes.Schema {
+	return routes.Object(map[string]*routes.Schema{
+		"config":     routes.Map(routes.Any()).Describe("The configuration in the shape of the config file."),
+		"reloadable": routes.Array(routes.String()),
+	}, "config", "reloadable")
+}
+
+// CacheSchema describes the cached response list.
+func CacheSchema() *routes.Schema {
+	return routes.Object(map[string]*routes.Schema{
+		"total":   routes.Integer(),
+		"entries": routes.Array(routes.SchemaOf(respcache.EntryInfo{}).Named("CacheEntry")),
+	}, "total", "entries")
+}
+
+// PurgeSchema describes the result of a cache purge.
+func PurgeSchema() *routes.Schema {
+	return routes.Object(map[string]*routes.Schema{
+		"purged": routes.Integer(),
+	}, "purged")
+}
+
+// KeysSchema describes the key list.
+func KeysSchema() *routes.Schema {
+	return routes.Object(map[string]*routes.Schema{
+		"total": routes.Integer(),
+		"keys":  routes.Array(KeySchema()),
+	}, "total", "keys")
+}
+
+// KeySchema describes one key with its usage.
+func KeySchema() *routes.Schema {
+	return routes.SchemaOf(keyView{}).Named("APIKey")
+}
+
+// SecretSchema describes a key together with its new secret, as returned
+// when a key is issued or rotated.
+func SecretSchema() *routes.Schema {
+	return routes.Object(map[string]*routes.Schema{
+		"key":    KeySchema(),
+		"secret": routes.String().Describe("Only returned here; store it now."),
+	}, "key", "secret")
+}
+
+// KeySpecSchema describes the body that issues a key.
+func KeySpecSchema() *routes.Schema {
+	return routes.SchemaOf(apikeys.KeySpec{}).Named("APIKeySpec")
+}
+
+// KeyUpdateSchema describes the body that changes a key. Omitted fields
+// are left unchanged.
+func KeyUpdateSchema() *routes.Schema {
+	s := routes.SchemaOf(apikeys.KeyUpdate{}).Named("APIKeyUpdate")
+	s.Required = nil
+	return s
+}
+
+// TiersSchema describes the tier list.
+func TiersSchema() *routes.Schema {
+	return routes.Object(map[string]*routes.Schema{
+		"anonymous": routes.SchemaOf(apikeys.Quota{}).Named("Quota"),
+		"tiers":     routes.Array(TierSchema()),
+	}, "anonymous", "tiers")
+}
+
+// TierSchema describes a rate limit tier.
+func TierSchema() *routes.Schema {
+	return routes.SchemaOf(apikeys.Tier{}).Named("Tier")
+}
+
+// TierBodySchema describes the body that saves a tier. The name comes from
+// the path and updatedAt is set by the server.
+func TierBodySchema() *routes.Schema {
+	s := routes.SchemaOf(apikeys.Tier{})
+	delete(s.Properties, "name")
+	delete(s.Properties, "updatedAt")
+	s.Required = nil
+	return s.Named("TierSpec")
+}
+
+// ValidateSchema describes the validation report.
+func ValidateSchema() *routes.Schema {
+	return routes.SchemaOf(validate.Report{}).Named("ValidationReport")
+}
+
+// ImportsSchema describes the import history.
+func ImportsSchema() *routes.Schema {
+	return routes.Object(map[string]*routes.Schema{
+		"total":   routes.Integer(),
+		"imports": routes.Array(routes.SchemaOf(importer.Result{}).Named("Import")),
+	}, "total", "imports")
+}
@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"context"
+	"net/http"
+
+	"ss-api/internal/app"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/validate"
+)
+
+type ValidateHandler struct {
+	app *app.App
+}
+
+// NewValidate checks the catalog collections and the assets directory and
+// answers with the report. A report with errors is still a 200; its ok
+// field tells the outcomes apart.
+func NewValidate(appInstance *app.App) http.HandlerFunc {
+	h := ValidateHandler{app: appInstance}
+	return h.handle
+}
+
+func (h ValidateHandler) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	client := h.app.MongoClient()
+	if client == nil {
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeUnavailable, "service unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.app.Config().Server.RequestTimeout)
+	defer cancel()
+
+	report, err := validate.Run(ctx, client.Database(h.app.DatabaseName()), validate.NewAssets(h.app.AssetsDir()))
+	if err != nil {
+		apierror.InternalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
@@ -3,7 +3,8 @@ package banner
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -12,6 +13,10 @@ import (
 
 	"ss-api/internal/alias"
 	"ss-api/internal/app"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/http/respcache"
+	"ss-api/internal/http/routes"
+	"ss-api/internal/locale"
 )
 
 type Handler struct {
@@ -71,42 +76,85 @@ type groupedBanners struct {
 	Ended     []bannerEntry `json:"ended"`
 }
 
-func New(appInstance *app.App) http.HandlerFunc {
+// Schema describes the grouped banners payload.
+func Schema() *routes.Schema {
+	return routes.SchemaOf(groupedBanners{}).Named("Banners")
+}
+
+func New(appInstance *app.App, cache *respcache.Store) http.HandlerFunc {
 	h := Handler{
 		app:    appInstance,
 		dbName: appInstance.DatabaseName(),
 	}
 
-	return h.handle
+	return cache.Handler(respcache.Policy{
+		Name:        "banners",
+		Route:       "/stella/banners",
+		Collections: []string{"gacha", "characters", "discs"},
+		TTL: func() time.Duration {
+			return appInstance.Config().Cache.ScheduleTTL
+		},
+		CacheControl: respcache.Public(time.Minute),
+	}, h.handle)
 }
 
 func (h Handler) handle(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	sel, ok := h.app.Config().Locale.Negotiator().Resolve(r)
+	if !ok {
+		apierror.UnsupportedLang(w, r, r.URL.Query().Get("lang"))
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), h.app.Config().Server.RequestTimeout)
 	defer cancel()
 
-	client := h.app.MongoClient()
-	if client == nil {
-		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+	if h.app.MongoClient() == nil {
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeUnavailable, "service unavailable")
 		return
 	}
 
-	collection := client.Database(h.dbName).Collection("gacha")
+	for _, lang := range sel.Chain {
+		results, err := h.listBanners(ctx, lang)
+		if err != nil {
+			apierror.InternalError(w, r, err)
+			return
+		}
+		if len(results) == 0 {
+			continue
+		}
+
+		h.enrichBanners(ctx, results, string(lang))
+
+		response := categorizeBanners(results, time.Now().UTC())
 
-	lang := strings.TrimSpace(r.URL.Query().Get("lang"))
-	if lang == "" {
-		lang = "EN"
+		locale.SetHeaders(w.Header(), lang)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			slog.WarnContext(r.Context(), "failed to write response", "error", err)
+		}
+		return
 	}
-	lang = strings.ToUpper(lang)
+
+	apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeNoData, "no banner data found", apierror.Details{"lang": sel.Locale})
+}
+
+// listBanners returns the banners of one region.
+func (h Handler) listBanners(ctx context.Context, lang locale.Locale) ([]bannerEntry, error) {
+	client := h.app.MongoClient()
+	if client == nil {
+		return nil, errors.New("mongo client not initialised")
+	}
+
+	collection := client.Database(h.dbName).Collection("gacha")
 
 	cursor, err := collection.Find(ctx, bson.D{{Key: "region", Value: lang}})
 	if err != nil {
-		writeServerError(w, err)
-		return
+		return nil, err
 	}
 	defer cursor.Close(ctx)
 
@@ -115,8 +163,7 @@ func (h Handler) handle(w http.ResponseWriter, r *http.Request) {
 	for cursor.Next(ctx) {
 		var doc bannerDocument
 		if err := cursor.Decode(&doc); err != nil {
-			writeServerError(w, err)
-			return
+			return nil, err
 		}
 
 		for _, entry := range doc.Entries {
@@ -126,24 +173,7 @@ func (h Handler) handle(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := cursor.Err(); err != nil {
-		writeServerError(w, err)
-		return
-	}
-
-	if len(results) == 0 {
-		writeNotFound(w, "no banner data found")
-		return
-	}
-
-	h.enrichBanners(ctx, results, lang)
-
-	response := categorizeBanners(results, time.Now().UTC())
-
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("failed to write response: %v", err)
-	}
+	return results, cursor.Err()
 }
 
 func categorizeBanners(entries []bannerEntry, reference time.Time) groupedBanners {
@@ -221,35 +251,22 @@ func parseTimestamp(raw *string) *time.Time {
 
 	parsed, err := time.Parse(time.RFC3339, trimmed)
 	if err != nil {
-		log.Printf("banner: failed to parse time %q: %v", trimmed, err)
+		slog.Warn("banner: failed to parse time", "value", trimmed, "error", err)
 		return nil
 	}
 
 	return &parsed
 }
 
-func writeServerError(w http.ResponseWriter, err error) {
-	log.Printf("internal server error: %v", err)
-	http.Error(w, "internal server error", http.StatusInternalServerError)
-}
-
-func writeNotFound(w http.ResponseWriter, message string) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusNotFound)
-	_ = json.NewEncoder(w).Encode(map[string]string{
-		"error": message,
-	})
-}
-
 func (h Handler) enrichBanners(ctx context.Context, entries []bannerEntry, lang string) {
 	characterElements, err := h.fetchCharacterElements(ctx, lang)
 	if err != nil {
-		log.Printf("banner: failed to fetch character elements: %v", err)
+		slog.WarnContext(ctx, "banner: failed to fetch character elements", "error", err)
 	}
 
 	discElements, err := h.fetchDiscElements(ctx, lang)
 	if err != nil {
-		log.Printf("banner: failed to fetch disc elements: %v", err)
+		slog.WarnContext(ctx, "banner: failed to fetch disc elements", "error", err)
 	}
 
 	for i := range entries {
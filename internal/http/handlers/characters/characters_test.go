@@ -0,0 +1,32 @@
+package characters
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"ss-api/internal/locale"
+)
+
+func TestSanitizeEntriesSkipsUndecodableEntries(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{{Key: "entries", Value: bson.A{
+		bson.D{{Key: "id", Value: 1}, {Key: "name", Value: "Amber"}},
+		bson.D{{Key: "id", Value: 2}, {Key: "name", Value: bson.A{"not", "a", "name"}}},
+		bson.D{{Key: "id", Value: 3}, {Key: "name", Value: "Chitose"}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Handler{}.sanitizeEntries(locale.EN, bson.Raw(doc).Lookup("entries"))
+	if err != nil {
+		t.Fatalf("sanitizeEntries: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != 1 || entries[1].ID != 3 {
+		t.Fatalf("entries = %+v, want IDs 1 and 3", entries)
+	}
+
+	if _, _, err := (Handler{}).findEntry(bson.Raw(doc).Lookup("entries"), "2"); err == nil {
+		t.Error("findEntry decoded the malformed entry without an error")
+	}
+}
@@ -3,7 +3,8 @@ package events
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -12,6 +13,10 @@ import (
 
 	"ss-api/internal/alias"
 	"ss-api/internal/app"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/http/respcache"
+	"ss-api/internal/http/routes"
+	"ss-api/internal/locale"
 )
 
 type Handler struct {
@@ -123,42 +128,83 @@ type groupedEvents struct {
 	Ended    []eventEntry `json:"ended"`
 }
 
-func New(appInstance *app.App) http.HandlerFunc {
+// Schema describes the grouped events payload.
+func Schema() *routes.Schema {
+	return routes.SchemaOf(groupedEvents{}).Named("Events")
+}
+
+func New(appInstance *app.App, cache *respcache.Store) http.HandlerFunc {
 	h := Handler{
 		app:    appInstance,
 		dbName: appInstance.DatabaseName(),
 	}
 
-	return h.handle
+	return cache.Handler(respcache.Policy{
+		Name:        "events",
+		Route:       "/stella/events",
+		Collections: []string{"events"},
+		TTL: func() time.Duration {
+			return appInstance.Config().Cache.ScheduleTTL
+		},
+		CacheControl: respcache.Public(time.Minute),
+	}, h.handle)
 }
 
 func (h Handler) handle(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	sel, ok := h.app.Config().Locale.Negotiator().Resolve(r)
+	if !ok {
+		apierror.UnsupportedLang(w, r, r.URL.Query().Get("lang"))
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(r.Context(), h.app.Config().Server.RequestTimeout)
 	defer cancel()
 
-	client := h.app.MongoClient()
-	if client == nil {
-		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+	if h.app.MongoClient() == nil {
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeUnavailable, "service unavailable")
 		return
 	}
 
-	collection := client.Database(h.dbName).Collection("events")
+	for _, lang := range sel.Chain {
+		results, err := h.listEvents(ctx, lang)
+		if err != nil {
+			apierror.InternalError(w, r, err)
+			return
+		}
+		if len(results) == 0 {
+			continue
+		}
+
+		grouped := categorizeEvents(results, time.Now().UTC())
+
+		locale.SetHeaders(w.Header(), lang)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(grouped); err != nil {
+			slog.WarnContext(r.Context(), "failed to write response", "error", err)
+		}
+		return
+	}
 
-	lang := strings.TrimSpace(r.URL.Query().Get("lang"))
-	if lang == "" {
-		lang = "EN"
+	apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeNoData, "no event data found", apierror.Details{"lang": sel.Locale})
+}
+
+// listEvents returns the events of one region.
+func (h Handler) listEvents(ctx context.Context, lang locale.Locale) ([]eventEntry, error) {
+	client := h.app.MongoClient()
+	if client == nil {
+		return nil, errors.New("mongo client not initialised")
 	}
-	lang = strings.ToUpper(lang)
+
+	collection := client.Database(h.dbName).Collection("events")
 
 	cursor, err := collection.Find(ctx, bson.D{{Key: "region", Value: lang}})
 	if err != nil {
-		writeServerError(w, err)
-		return
+		return nil, err
 	}
 	defer cursor.Close(ctx)
 
@@ -167,12 +213,7 @@ func (h Handler) handle(w http.ResponseWriter, r *http.Request) {
 	for cursor.Next(ctx) {
 		var doc eventDocument
 		if err := cursor.Decode(&doc); err != nil {
-			writeServerError(w, err)
-			return
-		}
-
-		if len(doc.Entries) == 0 {
-			continue
+			return nil, err
 		}
 
 		for _, entry := range doc.Entries {
@@ -181,22 +222,7 @@ func (h Handler) handle(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := cursor.Err(); err != nil {
-		writeServerError(w, err)
-		return
-	}
-
-	if len(results) == 0 {
-		writeNotFound(w, "no event data found")
-		return
-	}
-
-	grouped := categorizeEvents(results, time.Now().UTC())
-
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	if err := json.NewEncoder(w).Encode(grouped); err != nil {
-		log.Printf("failed to write response: %v", err)
-	}
+	return results, cursor.Err()
 }
 
 func categorizeEvents(entries []eventEntry, reference time.Time) groupedEvents {
@@ -235,22 +261,9 @@ func parseTimestamp(raw *string) *time.Time {
 
 	parsed, err := time.Parse(time.RFC3339, trimmed)
 	if err != nil {
-		log.Printf("events: failed to parse time %q: %v", trimmed, err)
+		slog.Warn("events: failed to parse time", "value", trimmed, "error", err)
 		return nil
 	}
 
 	return &parsed
 }
-
-func writeServerError(w http.ResponseWriter, err error) {
-	log.Printf("internal server error: %v", err)
-	http.Error(w, "internal server error", http.StatusInternalServerError)
-}
-
-func writeNotFound(w http.ResponseWriter, message string) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(http.StatusNotFound)
-	_ = json.NewEncoder(w).Encode(map[string]string{
-		"error": message,
-	})
-}
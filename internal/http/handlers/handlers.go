@@ -3,35 +3,110 @@ package handlers
 import (
 	"net/http"
 
+	"ss-api/internal/apikeys"
 	"ss-api/internal/app"
+	"ss-api/internal/http/export"
+	"ss-api/internal/http/handlers/admin"
 	"ss-api/internal/http/handlers/banner"
 	"ss-api/internal/http/handlers/characters"
 	"ss-api/internal/http/handlers/discs"
 	"ss-api/internal/http/handlers/events"
+	"ss-api/internal/http/handlers/history"
 	"ss-api/internal/http/handlers/news"
 	"ss-api/internal/http/handlers/status"
+	"ss-api/internal/http/handlers/versions"
+	"ss-api/internal/http/respcache"
 )
 
 type Set struct {
 	Status          http.HandlerFunc
+	Health          http.HandlerFunc
+	Ready           http.HandlerFunc
 	Characters      http.HandlerFunc
 	CharacterDetail http.HandlerFunc
 	Discs           http.HandlerFunc
 	DiscDetail      http.HandlerFunc
 	Banner          http.HandlerFunc
 	Events          http.HandlerFunc
+	Export          http.HandlerFunc
+	CatalogLog      http.HandlerFunc
+	CharacterLog    http.HandlerFunc
 	News            http.HandlerFunc
+	Versions        http.HandlerFunc
+	Changelog       http.HandlerFunc
+	// AdminAuth guards every admin handler below.
+	AdminAuth        func(http.HandlerFunc) http.HandlerFunc
+	AdminJobs        http.HandlerFunc
+	AdminRunJob      http.HandlerFunc
+	AdminReload      http.HandlerFunc
+	AdminConfig      http.HandlerFunc
+	AdminCache       http.HandlerFunc
+	AdminCachePurge  http.HandlerFunc
+	AdminAliasReload http.HandlerFunc
+	AdminAssetReload http.HandlerFunc
+	AdminNewsRefresh http.HandlerFunc
+	AdminKeys        http.HandlerFunc
+	AdminKey         http.HandlerFunc
+	AdminKeyCreate   http.HandlerFunc
+	AdminKeyUpdate   http.HandlerFunc
+	AdminKeyRotate   http.HandlerFunc
+	AdminKeyDelete   http.HandlerFunc
+	AdminTiers       http.HandlerFunc
+	AdminTierPut     http.HandlerFunc
+	AdminTierDelete  http.HandlerFunc
+	AdminValidate    http.HandlerFunc
+	AdminImports     http.HandlerFunc
 }
 
-func New(appInstance *app.App) Set {
+// New builds every handler. Cacheable routes share the response cache; the
+// key and tier routes manage the store the rate limiter reads. List routes
+// also answer in CSV and NDJSON, and the export bundle reuses them.
+func New(appInstance *app.App, cache *respcache.Store, keys *apikeys.Store) Set {
+	newsHandlers := news.New(appInstance, cache)
+	bannerHandler := banner.New(appInstance, cache)
+	eventsHandler := events.New(appInstance, cache)
+
 	return Set{
 		Status:          status.New(appInstance),
-		Characters:      characters.New(appInstance),
-		CharacterDetail: characters.NewDetail(appInstance),
-		Discs:           discs.New(appInstance),
-		DiscDetail:      discs.NewDetail(appInstance),
-		Banner:          banner.New(appInstance),
-		Events:          events.New(appInstance),
-		News:            news.New(appInstance),
+		Health:          status.NewHealth(appInstance),
+		Ready:           status.NewReady(appInstance),
+		Characters:      export.Handler("characters", characters.New(appInstance, cache)),
+		CharacterDetail: characters.NewDetail(appInstance, cache),
+		Discs:           export.Handler("discs", discs.New(appInstance, cache)),
+		DiscDetail:      discs.NewDetail(appInstance, cache),
+		Banner:          export.Handler("banners", bannerHandler),
+		Events:          export.Handler("events", eventsHandler),
+		Export: export.NewBundle(appInstance, cache,
+			export.Source{Name: "characters", Collections: []string{"characters"}, Render: characters.Catalog(appInstance)},
+			export.Source{Name: "discs", Collections: []string{"discs"}, Render: discs.Catalog(appInstance)},
+			export.FromHandler("banners", "/stella/banners", bannerHandler, "gacha", "characters", "discs"),
+			export.FromHandler("events", "/stella/events", eventsHandler, "events"),
+		),
+		CatalogLog:       history.New(appInstance),
+		CharacterLog:     history.NewCharacter(appInstance),
+		News:             newsHandlers.List,
+		Versions:         versions.New(appInstance),
+		Changelog:        versions.NewChangelog(appInstance),
+		AdminAuth:        admin.NewAuth(appInstance),
+		AdminJobs:        admin.NewJobs(appInstance),
+		AdminRunJob:      admin.NewRunJob(appInstance),
+		AdminReload:      admin.NewReload(appInstance),
+		AdminConfig:      admin.NewConfig(appInstance),
+		AdminCache:       admin.NewCache(cache),
+		AdminCachePurge:  admin.NewCachePurge(cache),
+		AdminAliasReload: admin.NewRebuild(appInstance, app.CatalogReloadJob),
+		AdminAssetReload: admin.NewRebuild(appInstance, app.AssetRebuildJob),
+		AdminNewsRefresh: newsHandlers.Refresh,
+		AdminKeys:        admin.NewKeys(appInstance, keys),
+		AdminKey:         admin.NewKey(appInstance, keys),
+		AdminKeyCreate:   admin.NewKeyCreate(appInstance, keys),
+		AdminKeyUpdate:   admin.NewKeyUpdate(appInstance, keys),
+		AdminKeyRotate:   admin.NewKeyRotate(appInstance, keys),
+		AdminKeyDelete:   admin.NewKeyDelete(appInstance, keys),
+		AdminTiers:       admin.NewTiers(appInstance, keys),
+		AdminTierPut:     admin.NewTierPut(appInstance, keys),
+		AdminTierDelete:  admin.NewTierDelete(appInstance, keys),
+		AdminValidate:    admin.NewValidate(appInstance),
+		AdminImports:     admin.NewImports(appInstance),
 	}
 }
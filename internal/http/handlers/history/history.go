@@ -0,0 +1,238 @@
+// Package history serves the catalog changelog recorded by
+// internal/changelog: what changed per region between data updates, and
+// the changes of a single character.
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ss-api/internal/apiversion"
+	"ss-api/internal/app"
+	"ss-api/internal/changelog"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/http/routes"
+	"ss-api/internal/locale"
+)
+
+// Page sizes of both routes.
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// types maps the type values clients send to catalog collections.
+var types = map[string]string{
+	"character":  "characters",
+	"characters": "characters",
+	"disc":       "discs",
+	"discs":      "discs",
+	"banner":     "gacha",
+	"banners":    "gacha",
+	"gacha":      "gacha",
+	"event":      "events",
+	"events":     "events",
+}
+
+// TypeNames lists the canonical type values, for parameter docs and
+// errors.
+var TypeNames = []string{"characters", "discs", "banners", "events"}
+
+type Handler struct {
+	app *app.App
+}
+
+type changelogView struct {
+	Lang    locale.Locale      `json:"lang"`
+	Since   *time.Time         `json:"since,omitempty"`
+	Count   int                `json:"count"`
+	Changes []changelog.Change `json:"changes"`
+}
+
+type historyView struct {
+	ID      int64                   `json:"id"`
+	Lang    locale.Locale           `json:"lang"`
+	Count   int                     `json:"count"`
+	History []changelog.EntryChange `json:"history"`
+}
+
+// New lists the catalog changes of a region, newest first. type narrows
+// them to characters, discs, banners or events; since to changes detected
+// at or after a date.
+func New(appInstance *app.App) http.HandlerFunc {
+	h := Handler{app: appInstance}
+	return h.handleChangelog
+}
+
+// NewCharacter lists the changes of one character, newest first.
+func NewCharacter(appInstance *app.App) http.HandlerFunc {
+	h := Handler{app: appInstance}
+	return h.handleCharacter
+}
+
+// ChangelogSchema describes the changelog of a region.
+func ChangelogSchema() *routes.Schema {
+	return routes.SchemaOf(changelogView{}).Named("CatalogChangelog")
+}
+
+// HistorySchema describes the history of one entry.
+func HistorySchema() *routes.Schema {
+	return routes.SchemaOf(historyView{}).Named("EntryHistory")
+}
+
+func (h Handler) handleChangelog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	sel, ok := h.app.Config().Locale.Negotiator().Resolve(r)
+	if !ok {
+		apierror.UnsupportedLang(w, r, r.URL.Query().Get("lang"))
+		return
+	}
+
+	query := r.URL.Query()
+	q := changelog.Query{Region: string(sel.Locale)}
+
+	if value := strings.TrimSpace(query.Get("type")); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			collection, ok := types[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeInvalidParameter,
+					"unknown type "+strconv.Quote(name), apierror.Details{"parameter": "type", "value": name, "supported": TypeNames})
+				return
+			}
+			if !slices.Contains(q.Collections, collection) {
+				q.Collections = append(q.Collections, collection)
+			}
+		}
+	}
+
+	var since *time.Time
+	if value := strings.TrimSpace(query.Get("since")); value != "" {
+		t, err := apiversion.ParseDate(value)
+		if err != nil {
+			apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeInvalidParameter,
+				"since: "+err.Error(), apierror.Details{"parameter": "since", "value": value})
+			return
+		}
+		q.Since = t.UTC()
+		since = &q.Since
+	}
+
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+	q.Limit = limit
+
+	client := h.app.MongoClient()
+	if client == nil {
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeUnavailable, "service unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.app.Config().Server.RequestTimeout)
+	defer cancel()
+
+	changes, err := changelog.Changes(ctx, client.Database(h.app.DatabaseName()), q)
+	if err != nil {
+		apierror.InternalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, sel.Locale, changelogView{Lang: sel.Locale, Since: since, Count: len(changes), Changes: changes})
+}
+
+func (h Handler) handleCharacter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	identifier := strings.TrimSpace(r.PathValue("identifier"))
+	if identifier == "" {
+		apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeInvalidParameter, "missing character identifier", apierror.Details{"parameter": "identifier"})
+		return
+	}
+
+	sel, ok := h.app.Config().Locale.Negotiator().Resolve(r)
+	if !ok {
+		apierror.UnsupportedLang(w, r, r.URL.Query().Get("lang"))
+		return
+	}
+
+	limit, ok := parseLimit(w, r)
+	if !ok {
+		return
+	}
+
+	client := h.app.MongoClient()
+	if client == nil {
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeUnavailable, "service unavailable")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.app.Config().Server.RequestTimeout)
+	defer cancel()
+
+	db := client.Database(h.app.DatabaseName())
+	region := string(sel.Locale)
+
+	id, err := strconv.ParseInt(identifier, 10, 64)
+	if err != nil {
+		var found bool
+		id, found, err = changelog.Resolve(ctx, db, "characters", region, identifier)
+		if err != nil {
+			apierror.InternalError(w, r, err)
+			return
+		}
+		if !found {
+			apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeCharacterNotFound, "character not found", apierror.Details{"identifier": identifier, "lang": sel.Locale})
+			return
+		}
+	}
+
+	history, err := changelog.History(ctx, db, "characters", region, id, limit)
+	if err != nil {
+		apierror.InternalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, r, sel.Locale, historyView{ID: id, Lang: sel.Locale, Count: len(history), History: history})
+}
+
+// parseLimit reads the limit parameter, answering 400 when it is invalid.
+func parseLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := strings.TrimSpace(r.URL.Query().Get("limit"))
+	if value == "" {
+		return defaultLimit, true
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 || n > maxLimit {
+		apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeInvalidParameter,
+			"limit must be an integer from 1 to 100", apierror.Details{"parameter": "limit", "value": value})
+		return 0, false
+	}
+	return n, true
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, served locale.Locale, payload any) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		apierror.InternalError(w, r, err)
+		return
+	}
+	locale.SetHeaders(w.Header(), served)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if _, err := w.Write(body); err != nil {
+		slog.WarnContext(r.Context(), "failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,346 @@
+package news
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	newsAssetsSubdir   = "news"
+	newsAssetsPrefix   = "/stella/assets/news/"
+	maxImageBytes      = 16 << 20
+	imageGCGracePeriod = time.Hour
+)
+
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/avif": ".avif",
+}
+
+// IsMirroredImage reports whether name has one of the extensions the mirror
+// writes. Other files in the news directory are not served.
+func IsMirroredImage(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, known := range imageExtensions {
+		if ext == known {
+			return true
+		}
+	}
+	return false
+}
+
+// imageMirror downloads article hero images into the assets directory so the
+// API does not depend on the official CDN keeping them available. Files are
+// named after the SHA-256 of their content.
+type imageMirror struct {
+	dir    string
+	client *http.Client
+
+	mu      sync.RWMutex
+	mirrors map[string]string // source URL → served path
+}
+
+func newImageMirror(assetsDir string, client *http.Client) *imageMirror {
+	if assetsDir == "" {
+		return nil
+	}
+
+	// Downloads are bounded by news.image_timeout through their context; a
+	// client-wide timeout would cut them short whatever it is set to.
+	mirrorClient := *client
+	mirrorClient.Timeout = 0
+
+	return &imageMirror{
+		dir:     filepath.Join(assetsDir, newsAssetsSubdir),
+		client:  &mirrorClient,
+		mirrors: make(map[string]string),
+	}
+}
+
+// mirrorThumbnails replaces each row's thumbnail with a locally served copy.
+// The original URL is kept in thumbnailSource so later syncs can skip the
+// download. Rows whose image cannot be fetched keep the upstream URL.
+func (h *Handler) mirrorThumbnails(ctx context.Context, rows []map[string]interface{}) {
+	if h.images == nil || len(rows) == 0 {
+		return
+	}
+
+	cfg := h.app.Config().News
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(cfg.Concurrency)
+
+	for i := range rows {
+		row := rows[i]
+		source, _ := row["thumbnail"].(string)
+		if source == "" || strings.HasPrefix(source, newsAssetsPrefix) {
+			continue
+		}
+
+		g.Go(func() error {
+			local, err := h.images.mirror(ctx, source, cfg.ImageTimeout)
+			if err != nil {
+				slog.WarnContext(ctx, "news: failed to mirror image", "source", source, "error", err)
+				return nil
+			}
+
+			row["thumbnail"] = local
+			row["thumbnailSource"] = source
+			return nil
+		})
+	}
+
+	_ = g.Wait()
+}
+
+func (m *imageMirror) mirror(ctx context.Context, source string, timeout time.Duration) (string, error) {
+	if local, ok := m.lookup(source); ok {
+		return local, nil
+	}
+
+	childCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(childCtx, http.MethodGet, source, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "image/*")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("upstream status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxImageBytes {
+		return "", fmt.Errorf("image exceeds %d bytes", maxImageBytes)
+	}
+
+	ext, err := imageExtension(resp.Header.Get("Content-Type"), data)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + ext
+
+	if err := m.write(name, data); err != nil {
+		return "", err
+	}
+
+	local := newsAssetsPrefix + name
+	m.mu.Lock()
+	m.mirrors[source] = local
+	m.mu.Unlock()
+
+	return local, nil
+}
+
+// lookup returns a previously mirrored path for source if its file is still
+// on disk.
+func (m *imageMirror) lookup(source string) (string, bool) {
+	m.mu.RLock()
+	local, ok := m.mirrors[source]
+	m.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+
+	if _, err := os.Stat(filepath.Join(m.dir, path.Base(local))); err != nil {
+		m.mu.Lock()
+		delete(m.mirrors, source)
+		m.mu.Unlock()
+		return "", false
+	}
+
+	return local, true
+}
+
+// remember seeds the source → path table from rows that were mirrored by an
+// earlier sync, possibly in a previous process.
+func (m *imageMirror) remember(rows []bson.M) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, row := range rows {
+		source, _ := row["thumbnailSource"].(string)
+		local, _ := row["thumbnail"].(string)
+		if source != "" && strings.HasPrefix(local, newsAssetsPrefix) {
+			m.mirrors[source] = local
+		}
+	}
+}
+
+func (m *imageMirror) write(name string, data []byte) error {
+	target := filepath.Join(m.dir, name)
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(m.dir, ".download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), target)
+}
+
+// collectGarbage removes mirrored images that are no longer referenced by any
+// stored news row. Files younger than the grace period are kept so a sync that
+// is still writing rows does not lose its images.
+func (m *imageMirror) collectGarbage(referenced map[string]struct{}) (int, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-imageGCGracePeriod)
+	removed := 0
+	var errs []error
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if _, ok := referenced[entry.Name()]; ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(m.dir, entry.Name())); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+
+	m.mu.Lock()
+	for source, local := range m.mirrors {
+		if _, ok := referenced[path.Base(local)]; !ok {
+			delete(m.mirrors, source)
+		}
+	}
+	m.mu.Unlock()
+
+	return removed, errors.Join(errs...)
+}
+
+// collectImageGarbage scans every stored category for mirrored thumbnails and
+// deletes the image files nothing points at anymore.
+func (h *Handler) collectImageGarbage(ctx context.Context) error {
+	if h.images == nil {
+		return nil
+	}
+
+	collection := h.newsCollection()
+	if collection == nil {
+		return errors.New("mongo client not initialised")
+	}
+
+	childCtx, cancel := context.WithTimeout(ctx, h.app.Config().Server.RequestTimeout)
+	defer cancel()
+
+	cursor, err := collection.Find(childCtx, bson.M{}, options.Find().SetProjection(bson.M{"rows.thumbnail": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(childCtx)
+
+	referenced := make(map[string]struct{})
+	for cursor.Next(childCtx) {
+		var doc newsCategoryDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		for _, row := range doc.Rows {
+			if local, ok := row["thumbnail"].(string); ok && strings.HasPrefix(local, newsAssetsPrefix) {
+				referenced[path.Base(local)] = struct{}{}
+			}
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	removed, err := h.images.collectGarbage(referenced)
+	if removed > 0 {
+		slog.InfoContext(ctx, "news: removed unreferenced mirrored images", "count", removed)
+	}
+	return err
+}
+
+// imageExtension returns the extension for a downloaded image. Only the
+// raster types in imageExtensions are accepted: the file is served from our
+// origin, so anything that can carry script, such as SVG, is rejected
+// whatever the upstream Content-Type or URL says. The content is sniffed
+// first; the declared type is only trusted for formats the sniffer does not
+// know.
+func imageExtension(contentType string, data []byte) (string, error) {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if ext, ok := imageExtensions[sniffed]; ok {
+		return ext, nil
+	}
+
+	declared, _, err := mime.ParseMediaType(contentType)
+	if err == nil && sniffed == "application/octet-stream" {
+		if ext, ok := imageExtensions[strings.ToLower(declared)]; ok {
+			return ext, nil
+		}
+	}
+
+	if declared == "" {
+		declared = sniffed
+	}
+	return "", fmt.Errorf("unsupported image type %q", declared)
+}
@@ -1,35 +1,42 @@
 package news
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/jasonlvhit/gocron"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/sync/errgroup"
 
 	"ss-api/internal/app"
+	"ss-api/internal/config"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/http/respcache"
+	"ss-api/internal/http/routes"
+	"ss-api/internal/locale"
+	"ss-api/internal/metrics"
 )
 
 const (
-	newsListPath      = "/api/resource/news"
-	newsDetailPath    = "/api/resource/news/detail"
-	thumbnailCacheTTL = 10 * time.Minute
+	newsListPath       = "/api/resource/news"
+	newsDetailPath     = "/api/resource/news/detail"
 	newsCollectionName = "news_articles"
 	newsSyncPageSize   = 30
+	newsSyncJobName    = "news-sync"
 )
 
 var (
@@ -39,35 +46,36 @@ var (
 		"news":    "news",
 		"events":  "activity",
 	}
-	regionBaseURLs = map[string]string{
-		"global": "https://stellasora.global",
-		"jp":     "https://stellasora.jp",
-		"tw":     "https://stellasora.stargazer-games.com",
-		"cn":     "https://stellasora.yostar.cn",
-	}
-	langToRegion = map[string]string{
-		"en":    "global",
-		"us":    "global",
-		"jp":    "jp",
-		"ja":    "jp",
-		"tw":    "tw",
-		"zh-tw": "tw",
-		"cn":    "cn",
-		"zh-cn": "cn",
-		"zh":    "cn",
+	// newsRegions maps locales to news regions. KR has no news site and
+	// follows the locale fallbacks.
+	newsRegions = map[locale.Locale]string{
+		locale.EN: "global",
+		locale.JP: "jp",
+		locale.TW: "tw",
+		locale.CN: "cn",
 	}
 	imgSrcPattern = regexp.MustCompile(`(?i)<img[^>]+src=["']([^"']+)["']`)
+
+	syncOutcomes = metrics.NewCounterVec(
+		"stella_news_sync_total",
+		"News category refreshes by region, category and result.",
+		"region", "category", "result",
+	)
+	syncDuration = metrics.NewHistogramVec(
+		"stella_news_sync_duration_seconds",
+		"Duration of news category refreshes, including upstream fetches and image mirroring.",
+		[]float64{0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		"region", "category",
+	)
 )
 
 type Handler struct {
-	app        *app.App
-	dbName     string
-	client     *http.Client
-	cache      map[string]cacheEntry
-	cacheMu    sync.RWMutex
-	syncOnce   sync.Once
-	scheduler  *gocron.Scheduler
-	stopSignal chan bool
+	app     *app.App
+	dbName  string
+	client  *http.Client
+	cache   map[string]cacheEntry
+	cacheMu sync.RWMutex
+	images  *imageMirror
 }
 
 type cacheEntry struct {
@@ -76,86 +84,156 @@ type cacheEntry struct {
 	expires       time.Time
 }
 
-// New constructs the news handler and starts the periodic cache synchronizer.
-func New(appInstance *app.App) http.HandlerFunc {
+// Handlers are the public news route and the admin refresh. They share one
+// upstream client, image mirror and detail cache.
+type Handlers struct {
+	List    http.HandlerFunc
+	Refresh http.HandlerFunc
+}
+
+// New constructs the news handlers and registers the periodic cache
+// synchronizer with the app scheduler.
+func New(appInstance *app.App, cache *respcache.Store) Handlers {
+	cfg := appInstance.Config()
+	// Upstream calls are bounded per request by news.request_timeout and
+	// news.image_timeout instead of a client-wide timeout.
+	client := &http.Client{}
 	h := &Handler{
 		app:    appInstance,
 		dbName: appInstance.DatabaseName(),
-		client: &http.Client{Timeout: 10 * time.Second},
+		client: client,
 		cache:  make(map[string]cacheEntry),
 	}
-	h.startSyncLoop()
-	return h.handle
+	if cfg.News.MirrorImages {
+		h.images = newImageMirror(appInstance.AssetsDir(), client)
+	}
+	if cfg.News.Sync {
+		h.registerSyncJob()
+		appInstance.OnReload(func(old, next config.Config) {
+			if old.News.SyncInterval != next.News.SyncInterval {
+				_ = appInstance.Scheduler().Reschedule(newsSyncJobName, next.News.SyncInterval)
+			}
+		})
+	}
+	appInstance.RegisterCache("news.details", cacheLen(h.cacheLen))
+
+	// Pages are read from Mongo on every request; the cache layer only adds
+	// validators so unchanged pages revalidate with 304.
+	return Handlers{
+		List: cache.Handler(respcache.Policy{
+			Name:         "news",
+			Route:        "/stella/news/{category}",
+			CacheControl: respcache.Public(time.Minute),
+		}, h.handle),
+		Refresh: h.handleRefresh,
+	}
 }
 
 func (h *Handler) handle(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
 	category := strings.ToLower(strings.TrimSpace(r.PathValue("category")))
 	if category == "" {
-		writeJSONError(w, http.StatusNotFound, "news category required")
+		apierror.Write(w, r, http.StatusNotFound, apierror.CodeNewsCategoryNotFound, "news category required")
 		return
 	}
 
 	newsType, ok := categoryTypeMap[category]
 	if !ok {
-		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown news category %q", category))
+		apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeNewsCategoryNotFound, fmt.Sprintf("unknown news category %q", category), apierror.Details{"category": category})
 		return
 	}
 
-	lang := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("lang")))
-	if lang == "" {
-		lang = "en"
+	sel, ok := h.app.Config().Locale.Negotiator().Resolve(r)
+	if !ok {
+		apierror.UnsupportedLang(w, r, r.URL.Query().Get("lang"))
+		return
 	}
 
-	region, ok := langToRegion[lang]
+	served, region, ok := newsLocale(sel)
 	if !ok {
-		// Fallback: check if the user provided the raw region key directly or an unknown lang
-		if _, valid := regionBaseURLs[lang]; valid {
-			region = lang
-		} else {
-			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("unsupported language/region %q", lang))
-			return
-		}
+		apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeNewsRegionNotFound, fmt.Sprintf("no news region for lang %s", sel.Locale), apierror.Details{"lang": sel.Locale})
+		return
+	}
+
+	query := r.URL.Query()
+
+	index, err := parsePositiveQueryInt("index", query.Get("index"), 1)
+	if err != nil {
+		writeParameterError(w, r, "index", err)
+		return
+	}
+
+	size, err := parsePositiveQueryInt("size", query.Get("size"), 6)
+	if err != nil {
+		writeParameterError(w, r, "size", err)
+		return
+	}
+
+	before, err := parseCursor(query.Get("before"))
+	if err != nil {
+		writeParameterError(w, r, "before", err)
+		return
 	}
 
-	index, err := parsePositiveQueryInt("index", r.URL.Query().Get("index"), 1)
+	from, err := parseDateQuery("from", query.Get("from"), false)
 	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, err.Error())
+		writeParameterError(w, r, "from", err)
 		return
 	}
 
-	size, err := parsePositiveQueryInt("size", r.URL.Query().Get("size"), 6)
+	to, err := parseDateQuery("to", query.Get("to"), true)
 	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, err.Error())
+		writeParameterError(w, r, "to", err)
+		return
+	}
+
+	if from != nil && to != nil && from.After(*to) {
+		apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeInvalidParameter, "from must not be after to", apierror.Details{"parameter": "from"})
 		return
 	}
 
 	collectionDoc, err := h.ensureCategoryDocument(r.Context(), category, region, newsType)
 	if err != nil {
-		log.Printf("news: failed to load cached data for %s (%s): %v", category, region, err)
-		writeJSONError(w, http.StatusServiceUnavailable, "news cache unavailable")
+		slog.ErrorContext(r.Context(), "news: failed to load cached data", "category", category, "region", region, "error", err)
+		apierror.Write(w, r, http.StatusServiceUnavailable, apierror.CodeUnavailable, "news cache unavailable")
 		return
 	}
 
-	rows := paginateRows(collectionDoc.Rows, index, size)
+	// Both pagination modes page over the same order, so a nextCursor from
+	// an index page continues where that page ended.
+	filtered := sortRowsNewestFirst(filterRowsByDate(collectionDoc.Rows, from, to))
+
+	var page newsPage
+	if before != nil {
+		page = paginateRowsBefore(filtered, *before, size)
+	} else {
+		page = paginateRows(filtered, index, size)
+	}
 
 	payload := newsListResponse{
 		Code:    0,
 		Message: "ok",
 		Data: newsListData{
-			Count: len(rows),
-			Rows:  rows,
+			Count:      len(page.rows),
+			Total:      page.total,
+			Index:      page.index,
+			Size:       size,
+			Pages:      page.pages,
+			HasNext:    page.hasNext,
+			NextCursor: page.nextCursor,
+			Rows:       page.rows,
 		},
 		Timestamp: json.Number(strconv.FormatInt(time.Now().UnixMilli(), 10)),
 	}
 
+	locale.SetHeaders(w.Header(), served)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		log.Printf("failed to write news response: %v", err)
+		slog.WarnContext(r.Context(), "failed to write news response", "error", err)
 	}
 }
 
@@ -165,21 +243,61 @@ type newsCategoryDocument struct {
 	UpdatedAt time.Time `bson:"updatedAt"`
 }
 
-func paginateRows(rows []bson.M, index, size int) []map[string]interface{} {
-	if len(rows) == 0 {
-		return []map[string]interface{}{}
+// newsPage is a single window over the stored rows of a category together
+// with the metadata clients need to keep paging.
+type newsPage struct {
+	rows       []map[string]interface{}
+	total      int
+	index      int
+	pages      int
+	hasNext    bool
+	nextCursor string
+}
+
+// newsCursor identifies a row by its publish time, with the article id as a
+// tie-breaker for rows published in the same millisecond.
+type newsCursor struct {
+	publishTime int64
+	id          int64
+	hasID       bool
+}
+
+func (c newsCursor) String() string {
+	if !c.hasID {
+		return strconv.FormatInt(c.publishTime, 10)
+	}
+	return strconv.FormatInt(c.publishTime, 10) + "|" + strconv.FormatInt(c.id, 10)
+}
+
+// after reports whether the row sorts after the cursor in the newest-first
+// ordering used by the upstream API.
+func (c newsCursor) after(publishTime, id int64) bool {
+	if publishTime != c.publishTime {
+		return publishTime < c.publishTime
+	}
+	return c.hasID && id < c.id
+}
+
+func paginateRows(rows []bson.M, index, size int) newsPage {
+	page := newsPage{
+		rows:  []map[string]interface{}{},
+		total: len(rows),
+		index: index,
 	}
 
 	if index <= 0 {
-		index = 1
+		page.index = 1
 	}
 	if size <= 0 {
 		size = len(rows)
 	}
+	if size > 0 {
+		page.pages = (len(rows) + size - 1) / size
+	}
 
-	start := (index - 1) * size
+	start := (page.index - 1) * size
 	if start >= len(rows) {
-		return []map[string]interface{}{}
+		return page
 	}
 
 	end := start + size
@@ -187,12 +305,131 @@ func paginateRows(rows []bson.M, index, size int) []map[string]interface{} {
 		end = len(rows)
 	}
 
-	paged := make([]map[string]interface{}, end-start)
+	page.rows = make([]map[string]interface{}, end-start)
 	for i := start; i < end; i++ {
-		paged[i-start] = rows[i]
+		page.rows[i-start] = rows[i]
+	}
+
+	page.hasNext = end < len(rows)
+	if page.hasNext {
+		page.nextCursor = rowCursor(rows[end-1]).String()
 	}
 
-	return paged
+	return page
+}
+
+// paginateRowsBefore returns up to size rows that follow the cursor. Rows
+// must be sorted newest first, as sortRowsNewestFirst does. The reported
+// index is the page the first returned row would be on when paging by index
+// with the same size.
+func paginateRowsBefore(rows []bson.M, before newsCursor, size int) newsPage {
+	start := len(rows)
+	for i, row := range rows {
+		publishTime, _ := rowInt(row, "publishTime")
+		id, _ := rowInt(row, "id")
+		if before.after(publishTime, id) {
+			start = i
+			break
+		}
+	}
+
+	page := newsPage{
+		rows:  []map[string]interface{}{},
+		total: len(rows),
+		index: start/size + 1,
+		pages: (len(rows) + size - 1) / size,
+	}
+
+	if start >= len(rows) {
+		return page
+	}
+
+	end := start + size
+	if end > len(rows) {
+		end = len(rows)
+	}
+
+	page.rows = make([]map[string]interface{}, end-start)
+	for i := start; i < end; i++ {
+		page.rows[i-start] = rows[i]
+	}
+
+	page.hasNext = end < len(rows)
+	if page.hasNext {
+		page.nextCursor = rowCursor(rows[end-1]).String()
+	}
+
+	return page
+}
+
+// sortRowsNewestFirst returns a copy of rows ordered by publish time, then
+// ID, both descending: the order cursors are defined in.
+func sortRowsNewestFirst(rows []bson.M) []bson.M {
+	sorted := slices.Clone(rows)
+	slices.SortStableFunc(sorted, func(a, b bson.M) int {
+		aTime, _ := rowInt(a, "publishTime")
+		bTime, _ := rowInt(b, "publishTime")
+		if c := cmp.Compare(bTime, aTime); c != 0 {
+			return c
+		}
+		aID, _ := rowInt(a, "id")
+		bID, _ := rowInt(b, "id")
+		return cmp.Compare(bID, aID)
+	})
+	return sorted
+}
+
+func filterRowsByDate(rows []bson.M, from, to *time.Time) []bson.M {
+	if from == nil && to == nil {
+		return rows
+	}
+
+	filtered := make([]bson.M, 0, len(rows))
+	for _, row := range rows {
+		publishTime, ok := rowInt(row, "publishTime")
+		if !ok {
+			continue
+		}
+
+		published := time.UnixMilli(publishTime)
+		if from != nil && published.Before(*from) {
+			continue
+		}
+		if to != nil && published.After(*to) {
+			continue
+		}
+
+		filtered = append(filtered, row)
+	}
+
+	return filtered
+}
+
+func rowCursor(row bson.M) newsCursor {
+	publishTime, _ := rowInt(row, "publishTime")
+	id, hasID := rowInt(row, "id")
+	return newsCursor{publishTime: publishTime, id: id, hasID: hasID}
+}
+
+func rowInt(row bson.M, key string) (int64, bool) {
+	switch v := row[key].(type) {
+	case int64:
+		return v, true
+	case int32:
+		return int64(v), true
+	case int:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
 }
 
 func (h *Handler) ensureCategoryDocument(ctx context.Context, category, region, newsType string) (newsCategoryDocument, error) {
@@ -218,7 +455,7 @@ func (h *Handler) loadCategoryDocument(ctx context.Context, dbCategory string) (
 		return newsCategoryDocument{}, errors.New("mongo client not initialised")
 	}
 
-	childCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	childCtx, cancel := context.WithTimeout(ctx, h.app.Config().Server.RequestTimeout)
 	defer cancel()
 
 	var doc newsCategoryDocument
@@ -227,6 +464,26 @@ func (h *Handler) loadCategoryDocument(ctx context.Context, dbCategory string) (
 }
 
 func (h *Handler) refreshCategory(ctx context.Context, category, region, newsType string) error {
+	start := time.Now()
+	err := h.syncCategory(ctx, category, region, newsType)
+	syncDuration.WithLabelValues(region, category).Observe(time.Since(start).Seconds())
+
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	syncOutcomes.WithLabelValues(region, category, result).Inc()
+
+	return err
+}
+
+func (h *Handler) syncCategory(ctx context.Context, category, region, newsType string) error {
+	if h.images != nil {
+		if existing, err := h.loadCategoryDocument(ctx, fmt.Sprintf("%s:%s", region, category)); err == nil {
+			h.images.remember(existing.Rows)
+		}
+	}
+
 	rows, err := h.fetchCategoryRows(ctx, region, newsType)
 	if err != nil {
 		return err
@@ -238,7 +495,7 @@ func (h *Handler) refreshCategory(ctx context.Context, category, region, newsTyp
 		return errors.New("mongo client not initialised")
 	}
 
-	childCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	childCtx, cancel := context.WithTimeout(ctx, h.app.Config().Server.RequestTimeout)
 	defer cancel()
 
 	dbCategory := fmt.Sprintf("%s:%s", region, category)
@@ -277,12 +534,12 @@ func (h *Handler) fetchCategoryRows(ctx context.Context, region, newsType string
 }
 
 func (h *Handler) fetchNewsPage(ctx context.Context, region, newsType string, index, size int) ([]map[string]interface{}, int, error) {
-	endpoint, err := buildNewsListURL(region, newsType, index, size)
+	endpoint, err := h.buildNewsListURL(region, newsType, index, size)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	childCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	childCtx, cancel := context.WithTimeout(ctx, h.app.Config().News.RequestTimeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(childCtx, http.MethodGet, endpoint, nil)
@@ -313,9 +570,11 @@ func (h *Handler) fetchNewsPage(ctx context.Context, region, newsType string, in
 	filteredRows := filterRowsByType(payload.Data.Rows, newsType)
 
 	if err := h.enrichThumbnails(ctx, region, filteredRows); err != nil {
-		log.Printf("news: thumbnail enrichment failed: %v", err)
+		slog.WarnContext(ctx, "news: thumbnail enrichment failed", "region", region, "error", err)
 	}
 
+	h.mirrorThumbnails(ctx, filteredRows)
+
 	return filteredRows, upstreamCount, nil
 }
 
@@ -375,35 +634,26 @@ func (h *Handler) newsCollection() *mongo.Collection {
 	return client.Database(h.dbName).Collection(newsCollectionName)
 }
 
-func (h *Handler) startSyncLoop() {
+func (h *Handler) registerSyncJob() {
 	if h.app == nil {
 		return
 	}
 
-	h.syncOnce.Do(func() {
-		scheduler := gocron.NewScheduler()
-		scheduler.ChangeLoc(time.UTC)
-
-		start := nextHalfHour(time.Now().UTC())
-		job := scheduler.Every(30).Minutes().From(&start)
-		if err := job.Do(func() {
-			if err := h.refreshAll(context.Background()); err != nil {
-				log.Printf("news: scheduled sync failed: %v", err)
-			}
-		}); err != nil {
-			log.Printf("news: failed to schedule sync job: %v", err)
-			return
-		}
-
-		h.scheduler = scheduler
-		h.stopSignal = h.scheduler.Start()
+	err := h.app.Scheduler().Register(app.Job{
+		Name:     newsSyncJobName,
+		Interval: h.app.Config().News.SyncInterval,
+		Align:    true,
+		Run:      h.refreshAll,
 	})
+	if err != nil {
+		slog.Error("news: failed to schedule sync job", "error", err)
+	}
 }
 
 func (h *Handler) refreshAll(ctx context.Context) error {
 	var errs []error
 
-	for region := range regionBaseURLs {
+	for region := range h.app.Config().News.Upstreams {
 		for category, newsType := range categoryTypeMap {
 			if err := h.refreshCategory(ctx, category, region, newsType); err != nil {
 				errs = append(errs, fmt.Errorf("%s (%s): %w", category, region, err))
@@ -411,15 +661,11 @@ func (h *Handler) refreshAll(ctx context.Context) error {
 		}
 	}
 
-	return errors.Join(errs...)
-}
-
-func nextHalfHour(now time.Time) time.Time {
-	start := now.Truncate(30 * time.Minute)
-	if !start.After(now) {
-		start = start.Add(30 * time.Minute)
+	if err := h.collectImageGarbage(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("image cleanup: %w", err))
 	}
-	return start
+
+	return errors.Join(errs...)
 }
 
 func (h *Handler) enrichThumbnails(ctx context.Context, region string, rows []map[string]interface{}) error {
@@ -428,7 +674,7 @@ func (h *Handler) enrichThumbnails(ctx context.Context, region string, rows []ma
 	}
 
 	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(4)
+	g.SetLimit(h.app.Config().News.Concurrency)
 
 	for i := range rows {
 		row := rows[i]
@@ -461,10 +707,10 @@ func (h *Handler) fetchNewsDetail(ctx context.Context, region string, id int) (n
 		return detail, hero, nil
 	}
 
-	childCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	childCtx, cancel := context.WithTimeout(ctx, h.app.Config().News.RequestTimeout)
 	defer cancel()
 
-	endpoint, err := buildNewsDetailURL(region, id)
+	endpoint, err := h.buildNewsDetailURL(region, id)
 	if err != nil {
 		return newsDetail{}, "", err
 	}
@@ -522,8 +768,64 @@ func parsePositiveQueryInt(name, value string, fallback int) (int, error) {
 	return n, nil
 }
 
-func buildNewsListURL(region, newsType string, index, size int) (string, error) {
-	base, ok := regionBaseURLs[region]
+// parseCursor accepts either a bare publish time in Unix milliseconds or a
+// "publishTime|id" pair as returned in nextCursor.
+func parseCursor(value string) (*newsCursor, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	timePart, idPart, hasID := strings.Cut(value, "|")
+
+	publishTime, err := strconv.ParseInt(strings.TrimSpace(timePart), 10, 64)
+	if err != nil || publishTime <= 0 {
+		return nil, errors.New("before must be a publish time or publishTime|id cursor")
+	}
+
+	cursor := &newsCursor{publishTime: publishTime}
+	if hasID {
+		id, err := strconv.ParseInt(strings.TrimSpace(idPart), 10, 64)
+		if err != nil {
+			return nil, errors.New("before must be a publish time or publishTime|id cursor")
+		}
+		cursor.id = id
+		cursor.hasID = true
+	}
+
+	return cursor, nil
+}
+
+// parseDateQuery accepts RFC 3339 timestamps, plain dates (YYYY-MM-DD, UTC)
+// or Unix milliseconds. A plain date used as an upper bound covers the whole
+// day.
+func parseDateQuery(name, value string, endOfDay bool) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		t := time.UnixMilli(millis).UTC()
+		return &t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Millisecond)
+		}
+		return &t, nil
+	}
+
+	return nil, fmt.Errorf("%s must be a date (YYYY-MM-DD), RFC 3339 timestamp or Unix milliseconds", name)
+}
+
+func (h *Handler) buildNewsListURL(region, newsType string, index, size int) (string, error) {
+	base, ok := h.app.Config().News.Upstreams[region]
 	if !ok {
 		return "", fmt.Errorf("unknown region %q", region)
 	}
@@ -542,8 +844,8 @@ func buildNewsListURL(region, newsType string, index, size int) (string, error)
 	return endpoint.String(), nil
 }
 
-func buildNewsDetailURL(region string, id int) (string, error) {
-	base, ok := regionBaseURLs[region]
+func (h *Handler) buildNewsDetailURL(region string, id int) (string, error) {
+	base, ok := h.app.Config().News.Upstreams[region]
 	if !ok {
 		return "", fmt.Errorf("unknown region %q", region)
 	}
@@ -567,8 +869,29 @@ type newsListResponse struct {
 }
 
 type newsListData struct {
-	Count int                      `json:"count"`
-	Rows  []map[string]interface{} `json:"rows"`
+	Count      int                      `json:"count"`
+	Total      int                      `json:"total"`
+	Index      int                      `json:"index"`
+	Size       int                      `json:"size"`
+	Pages      int                      `json:"pages"`
+	HasNext    bool                     `json:"hasNext"`
+	NextCursor string                   `json:"nextCursor,omitempty"`
+	Rows       []map[string]interface{} `json:"rows"`
+}
+
+// ListSchema describes a news page.
+func ListSchema() *routes.Schema {
+	return routes.SchemaOf(newsListResponse{}).Named("NewsPage")
+}
+
+// RefreshSchema describes the result of an admin refresh.
+func RefreshSchema() *routes.Schema {
+	return routes.SchemaOf(refreshResponse{}).Named("NewsRefresh")
+}
+
+// CategorySchema describes the category path parameter.
+func CategorySchema() *routes.Schema {
+	return routes.String().OneOf("updates", "notices", "news", "events")
 }
 
 type newsDetailResponse struct {
@@ -591,10 +914,35 @@ type newsDetail struct {
 	Content     string `json:"content"`
 }
 
-func writeJSONError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+// newsRegion maps a raw region key such as "global" or "jp", or a lang such
+// as "ja", to the news region.
+func newsRegion(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if _, ok := config.DefaultNewsUpstreams[value]; ok {
+		return value, true
+	}
+	l, ok := locale.Parse(value)
+	if !ok {
+		return "", false
+	}
+	region, ok := newsRegions[l]
+	return region, ok
+}
+
+// newsLocale picks the first locale in the selection's chain that has a
+// news region.
+func newsLocale(sel locale.Selection) (locale.Locale, string, bool) {
+	for _, l := range sel.Chain {
+		if region, ok := newsRegions[l]; ok {
+			return l, region, true
+		}
+	}
+	return "", "", false
+}
+
+// writeParameterError answers 400 for a query parameter that failed to parse.
+func writeParameterError(w http.ResponseWriter, r *http.Request, parameter string, err error) {
+	apierror.WriteDetails(w, r, http.StatusBadRequest, apierror.CodeInvalidParameter, err.Error(), apierror.Details{"parameter": parameter})
 }
 
 func filterRowsByType(rows []map[string]interface{}, expectedType string) []map[string]interface{} {
@@ -619,6 +967,7 @@ func (h *Handler) cachedNews(region string, id int) (newsDetail, string, bool) {
 	entry, ok := h.cache[key]
 	h.cacheMu.RUnlock()
 	if !ok {
+		metrics.CacheHit("news.details", false)
 		return newsDetail{}, "", false
 	}
 
@@ -626,12 +975,25 @@ func (h *Handler) cachedNews(region string, id int) (newsDetail, string, bool) {
 		h.cacheMu.Lock()
 		delete(h.cache, key)
 		h.cacheMu.Unlock()
+		metrics.CacheHit("news.details", false)
 		return newsDetail{}, "", false
 	}
 
+	metrics.CacheHit("news.details", true)
 	return entry.detail, entry.heroThumbnail, true
 }
 
+// cacheLen adapts a size function to app.Cache.
+type cacheLen func() int
+
+func (f cacheLen) Len() int { return f() }
+
+func (h *Handler) cacheLen() int {
+	h.cacheMu.RLock()
+	defer h.cacheMu.RUnlock()
+	return len(h.cache)
+}
+
 func (h *Handler) storeNews(region string, id int, detail newsDetail, hero string) {
 	if hero == "" {
 		hero = detail.Thumbnail
@@ -642,7 +1004,7 @@ func (h *Handler) storeNews(region string, id int, detail newsDetail, hero strin
 	h.cache[key] = cacheEntry{
 		detail:        detail,
 		heroThumbnail: hero,
-		expires:       time.Now().Add(thumbnailCacheTTL),
+		expires:       time.Now().Add(h.app.Config().Cache.ThumbnailTTL),
 	}
 	h.cacheMu.Unlock()
 }
@@ -0,0 +1,88 @@
+package news
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPaginationModesAgreeOnUnsortedRows(t *testing.T) {
+	stored := []bson.M{
+		{"id": int64(3), "publishTime": int64(300)},
+		{"id": int64(1), "publishTime": int64(100)},
+		{"id": int64(5), "publishTime": int64(500)},
+		{"id": int64(2), "publishTime": int64(200)},
+		{"id": int64(4), "publishTime": int64(300)},
+	}
+	rows := sortRowsNewestFirst(stored)
+	if stored[0]["id"] != int64(3) {
+		t.Errorf("stored rows were reordered")
+	}
+
+	ids := func(page newsPage) []int64 {
+		var got []int64
+		for _, row := range page.rows {
+			id, _ := rowInt(row, "id")
+			got = append(got, id)
+		}
+		return got
+	}
+
+	// Start by index, then follow cursors; every page must match the
+	// index page of the same number.
+	page := paginateRows(rows, 1, 2)
+	var got []int64
+	for {
+		if want := ids(paginateRows(rows, page.index, 2)); !slices.Equal(ids(page), want) {
+			t.Fatalf("page %d = %v, index page = %v", page.index, ids(page), want)
+		}
+		got = append(got, ids(page)...)
+		if !page.hasNext {
+			break
+		}
+		next, err := parseCursor(page.nextCursor)
+		if err != nil {
+			t.Fatalf("parse cursor %q: %v", page.nextCursor, err)
+		}
+		page = paginateRowsBefore(rows, *next, 2)
+	}
+
+	if want := []int64{5, 4, 3, 2, 1}; !slices.Equal(got, want) {
+		t.Fatalf("ids = %v, want %v", got, want)
+	}
+}
+
+func TestImageExtensionRejectsScriptableTypes(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		data        []byte
+		want        string
+	}{
+		{"png", "image/png", png, ".png"},
+		{"png without type", "", png, ".png"},
+		{"avif by declared type", "image/avif", []byte{0, 0, 0, 0x1c, 'f', 't', 'y', 'p', 'a', 'v', 'i', 'f'}, ".avif"},
+		{"svg", "image/svg+xml", svg, ""},
+		{"svg declared as png", "image/png", svg, ""},
+		{"html", "image/jpeg", []byte("<html><script>alert(1)</script></html>"), ""},
+	} {
+		got, err := imageExtension(tc.contentType, tc.data)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("%s: accepted as %q", tc.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+
+	if IsMirroredImage("0123abcd.svg") || !IsMirroredImage("0123abcd.webp") {
+		t.Error("IsMirroredImage accepts the wrong extensions")
+	}
+}
@@ -0,0 +1,72 @@
+package news
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"ss-api/internal/http/apierror"
+)
+
+// refreshTimeout bounds an on-demand refresh, which pages through the whole
+// upstream category and may mirror images.
+const refreshTimeout = 5 * time.Minute
+
+type refreshResponse struct {
+	Region    string    `json:"region"`
+	Category  string    `json:"category"`
+	Rows      int       `json:"rows"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// handleRefresh re-fetches one category of one region from upstream right
+// away instead of waiting for the next news-sync run.
+func (h *Handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.MethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	region, ok := newsRegion(r.PathValue("region"))
+	if !ok {
+		apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeNewsRegionNotFound, fmt.Sprintf("unknown news region %q", r.PathValue("region")), apierror.Details{"region": r.PathValue("region")})
+		return
+	}
+
+	category := strings.ToLower(strings.TrimSpace(r.PathValue("category")))
+	newsType, ok := categoryTypeMap[category]
+	if !ok {
+		apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeNewsCategoryNotFound, fmt.Sprintf("unknown news category %q", category), apierror.Details{"category": category})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), refreshTimeout)
+	defer cancel()
+
+	if err := h.refreshCategory(ctx, category, region, newsType); err != nil {
+		slog.WarnContext(r.Context(), "admin: news refresh failed", "region", region, "category", category, "error", err)
+		apierror.WriteDetails(w, r, http.StatusBadGateway, apierror.CodeUpstreamFailed, fmt.Sprintf("refresh %s (%s): %v", category, region, err), apierror.Details{"region": region, "category": category})
+		return
+	}
+
+	doc, err := h.loadCategoryDocument(ctx, fmt.Sprintf("%s:%s", region, category))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "admin: news refresh reload failed", "region", region, "category", category, "error", err)
+		apierror.Write(w, r, http.StatusInternalServerError, apierror.CodeInternal, "internal server error")
+		return
+	}
+
+	slog.InfoContext(r.Context(), "admin: news refreshed", "region", region, "category", category, "rows", len(doc.Rows))
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(refreshResponse{
+		Region:    region,
+		Category:  category,
+		Rows:      len(doc.Rows),
+		UpdatedAt: doc.UpdatedAt,
+	})
+}
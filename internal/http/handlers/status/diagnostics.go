@@ -0,0 +1,149 @@
+package status
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ss-api/internal/app"
+)
+
+const newsCollectionName = "news_articles"
+
+type diagnostics struct {
+	Mongo   mongoStatus
+	Regions map[string]map[string]regionCount
+	News    map[string]time.Time
+}
+
+type mongoStatus struct {
+	Connected bool    `json:"connected"`
+	LatencyMs float64 `json:"latencyMs,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type regionCount struct {
+	Documents int `json:"documents" bson:"documents"`
+	Entries   int `json:"entries" bson:"entries"`
+}
+
+func collectDiagnostics(ctx context.Context, appInstance *app.App) *diagnostics {
+	diag := &diagnostics{
+		Regions: make(map[string]map[string]regionCount),
+		News:    make(map[string]time.Time),
+	}
+
+	client := appInstance.MongoClient()
+	diag.Mongo = pingMongo(ctx, client)
+	if !diag.Mongo.Connected {
+		return diag
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	db := client.Database(appInstance.DatabaseName())
+	for _, name := range app.CatalogCollections {
+		counts, err := countRegions(ctx, db.Collection(name))
+		if err != nil {
+			continue
+		}
+		diag.Regions[name] = counts
+	}
+
+	if updated, err := newsUpdateTimes(ctx, db.Collection(newsCollectionName)); err == nil {
+		diag.News = updated
+	}
+
+	return diag
+}
+
+func pingMongo(ctx context.Context, client *mongo.Client) mongoStatus {
+	if client == nil {
+		return mongoStatus{Error: "mongo client not initialised"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := client.Ping(ctx, nil); err != nil {
+		return mongoStatus{Error: err.Error()}
+	}
+
+	return mongoStatus{
+		Connected: true,
+		LatencyMs: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+}
+
+// countRegions returns, per region, how many documents exist and how many
+// entries they hold in total.
+func countRegions(ctx context.Context, collection *mongo.Collection) (map[string]regionCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$region"},
+			{Key: "documents", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "entries", Value: bson.D{{Key: "$sum", Value: bson.D{
+				{Key: "$cond", Value: bson.A{
+					bson.D{{Key: "$isArray", Value: "$entries"}},
+					bson.D{{Key: "$size", Value: "$entries"}},
+					0,
+				}},
+			}}}},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := make(map[string]regionCount)
+	for cursor.Next(ctx) {
+		var row struct {
+			Region      string `bson:"_id"`
+			regionCount `bson:",inline"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		if row.Region == "" {
+			continue
+		}
+		counts[row.Region] = row.regionCount
+	}
+
+	return counts, cursor.Err()
+}
+
+// newsUpdateTimes maps each stored news category (e.g. "global:updates") to
+// the time it was last synchronised.
+func newsUpdateTimes(ctx context.Context, collection *mongo.Collection) (map[string]time.Time, error) {
+	opts := options.Find().SetProjection(bson.M{"category": 1, "updatedAt": 1})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := make(map[string]time.Time)
+	for cursor.Next(ctx) {
+		var doc struct {
+			Category  string    `bson:"category"`
+			UpdatedAt time.Time `bson:"updatedAt"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		if doc.Category != "" {
+			updated[doc.Category] = doc.UpdatedAt
+		}
+	}
+
+	return updated, cursor.Err()
+}
@@ -0,0 +1,161 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"ss-api/internal/app"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/http/routes"
+)
+
+// newsStaleAfter is how old the most recent news sync may be before the
+// readiness check reports the news cache as stale: one missed sync is
+// tolerated. It follows news.sync_interval, which is reloadable.
+func newsStaleAfter(appInstance *app.App) time.Duration {
+	return 2 * appInstance.Config().News.SyncInterval
+}
+
+type check struct {
+	Status    string     `json:"status"`
+	Critical  bool       `json:"critical"`
+	LatencyMs float64    `json:"latencyMs,omitempty"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// NewHealth reports liveness. It answers 200 as long as the process can serve
+// HTTP and never touches Mongo.
+func NewHealth(appInstance *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			apierror.MethodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+			return
+		}
+
+		writeHealth(w, http.StatusOK, map[string]any{
+			"status": "ok",
+			"uptime": int64(time.Since(appInstance.StartTime()).Seconds()),
+		})
+	}
+}
+
+// NewReady reports readiness: Mongo must answer a ping and the assets
+// directory must be readable. News freshness is reported but does not fail
+// the check, since the news cache recovers on its own on the next sync.
+func NewReady(appInstance *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			apierror.MethodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+			return
+		}
+
+		checks := map[string]check{
+			"mongo":  mongoCheck(r.Context(), appInstance),
+			"news":   newsCheck(r.Context(), appInstance),
+			"assets": assetsCheck(appInstance.AssetsDir()),
+		}
+
+		overall := "ready"
+		code := http.StatusOK
+		for _, c := range checks {
+			if c.Status == "ok" {
+				continue
+			}
+			if c.Critical {
+				overall = "unavailable"
+				code = http.StatusServiceUnavailable
+				break
+			}
+			overall = "degraded"
+		}
+
+		writeHealth(w, code, map[string]any{
+			"status": overall,
+			"checks": checks,
+		})
+	}
+}
+
+// HealthSchema describes the liveness payload.
+func HealthSchema() *routes.Schema {
+	return routes.Object(map[string]*routes.Schema{
+		"status": routes.String().OneOf("ok"),
+		"uptime": routes.Integer().Describe("Seconds since the server started."),
+	}, "status", "uptime").Named("Health")
+}
+
+// ReadySchema describes the readiness payload; 503 carries the same body.
+func ReadySchema() *routes.Schema {
+	return routes.Object(map[string]*routes.Schema{
+		"status": routes.String().OneOf("ready", "degraded", "unavailable"),
+		"checks": routes.Map(routes.SchemaOf(check{})),
+	}, "status", "checks").Named("Readiness")
+}
+
+func mongoCheck(ctx context.Context, appInstance *app.App) check {
+	ping := pingMongo(ctx, appInstance.MongoClient())
+	if !ping.Connected {
+		return check{Status: "fail", Critical: true, Error: ping.Error}
+	}
+	return check{Status: "ok", Critical: true, LatencyMs: ping.LatencyMs}
+}
+
+func newsCheck(ctx context.Context, appInstance *app.App) check {
+	client := appInstance.MongoClient()
+	if client == nil {
+		return check{Status: "fail", Error: "mongo client not initialised"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	updated, err := newsUpdateTimes(ctx, client.Database(appInstance.DatabaseName()).Collection(newsCollectionName))
+	if err != nil {
+		return check{Status: "fail", Error: err.Error()}
+	}
+
+	var latest time.Time
+	for _, t := range updated {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+
+	if latest.IsZero() {
+		return check{Status: "stale", Error: "news has not been synchronised yet"}
+	}
+
+	result := check{Status: "ok", UpdatedAt: &latest}
+	if staleAfter := newsStaleAfter(appInstance); time.Since(latest) > staleAfter {
+		result.Status = "stale"
+		result.Error = "last news sync is older than " + staleAfter.String()
+	}
+	return result
+}
+
+func assetsCheck(dir string) check {
+	f, err := os.Open(dir)
+	if err != nil {
+		return check{Status: "fail", Critical: true, Error: err.Error()}
+	}
+	defer f.Close()
+
+	if _, err := f.Readdirnames(1); err != nil && !errors.Is(err, io.EOF) {
+		return check{Status: "fail", Critical: true, Error: err.Error()}
+	}
+
+	return check{Status: "ok", Critical: true}
+}
+
+func writeHealth(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
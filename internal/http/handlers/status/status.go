@@ -3,37 +3,61 @@ package status
 import (
 	"encoding/json"
 	"net/http"
+	"sync"
+	"time"
 
 	"ss-api/internal/app"
+	"ss-api/internal/buildinfo"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/http/routes"
 )
 
 type Handler struct {
 	app *app.App
+
+	mu          sync.Mutex
+	diagnostics *diagnostics
+	expires     time.Time
 }
 
 func New(appInstance *app.App) http.HandlerFunc {
-	h := Handler{app: appInstance}
+	h := &Handler{app: appInstance}
+	// Region counts are stale as soon as the catalog changes.
+	appInstance.OnCatalogChange(func(app.CatalogChange) {
+		h.mu.Lock()
+		h.diagnostics = nil
+		h.mu.Unlock()
+	})
 	return h.handle
 }
 
-func (h Handler) handle(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) handle(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
 	if path := r.URL.Path; path != "/stella" && path != "/stella/" {
-		writeJSONError(w, http.StatusNotFound, "not found")
+		apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeRouteNotFound, "not found", apierror.Details{"path": path})
 		return
 	}
 
-	response := struct {
-		Status    int      `json:"status"`
-		Uptime    int64    `json:"uptime"`
-		Endpoints []string `json:"endpoints"`
-	}{
+	started := h.app.StartTime()
+	diag := h.loadDiagnostics(r)
+
+	response := statusResponse{
 		Status:    http.StatusOK,
-		Uptime:    h.app.StartTime().Unix(),
+		Uptime:    int64(time.Since(started).Seconds()),
+		StartedAt: started.Unix(),
+		Build:     buildinfo.Get(),
+		Mongo:     diag.Mongo,
+		Regions:   diag.Regions,
+		Caches:    h.app.CacheSizes(),
+		Watch:     h.app.CatalogWatchMode(),
+		LastSync: lastSync{
+			News: diag.News,
+			Jobs: h.jobSyncTimes(),
+		},
 		Endpoints: h.app.Endpoints(),
 	}
 
@@ -41,10 +65,50 @@ func (h Handler) handle(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(response)
 }
 
-func writeJSONError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(map[string]string{
-		"error": message,
-	})
+func (h *Handler) loadDiagnostics(r *http.Request) *diagnostics {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.diagnostics != nil && time.Now().Before(h.expires) {
+		return h.diagnostics
+	}
+
+	diag := collectDiagnostics(r.Context(), h.app)
+	h.diagnostics = diag
+	// cache.status_ttl bounds how often Mongo is queried for document
+	// counts and sync times.
+	h.expires = time.Now().Add(h.app.Config().Cache.StatusTTL)
+	return diag
+}
+
+func (h *Handler) jobSyncTimes() map[string]*time.Time {
+	jobs := h.app.Scheduler().Jobs()
+	result := make(map[string]*time.Time, len(jobs))
+	for _, job := range jobs {
+		result[job.Name] = job.LastSuccess
+	}
+	return result
+}
+
+type statusResponse struct {
+	Status    int                               `json:"status"`
+	Uptime    int64                             `json:"uptime"`
+	StartedAt int64                             `json:"startedAt"`
+	Build     buildinfo.Info                    `json:"build"`
+	Mongo     mongoStatus                       `json:"mongo"`
+	Regions   map[string]map[string]regionCount `json:"regions"`
+	Caches    map[string]int                    `json:"caches"`
+	Watch     string                            `json:"watch"`
+	LastSync  lastSync                          `json:"lastSync"`
+	Endpoints []string                          `json:"endpoints"`
+}
+
+// Schema describes the status payload.
+func Schema() *routes.Schema {
+	return routes.SchemaOf(statusResponse{}).Named("Status")
+}
+
+type lastSync struct {
+	News map[string]time.Time  `json:"news"`
+	Jobs map[string]*time.Time `json:"jobs"`
 }
@@ -0,0 +1,107 @@
+package versions
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"ss-api/internal/apiversion"
+	"ss-api/internal/app"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/http/routes"
+)
+
+type Handler struct {
+	app *app.App
+}
+
+// versionView is a version with its retirement schedule.
+type versionView struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Default bool   `json:"default,omitempty"`
+	apiversion.Lifecycle
+}
+
+type changelogView struct {
+	versionView
+	Changes []apiversion.Change `json:"changes"`
+}
+
+// New lists the API versions, which one unversioned paths use, and when
+// each is deprecated or sunset.
+func New(appInstance *app.App) http.HandlerFunc {
+	h := Handler{app: appInstance}
+	return h.handleList
+}
+
+// NewChangelog shows the changes made in one version.
+func NewChangelog(appInstance *app.App) http.HandlerFunc {
+	h := Handler{app: appInstance}
+	return h.handleChangelog
+}
+
+// ListSchema describes the version list.
+func ListSchema() *routes.Schema {
+	return routes.Object(map[string]*routes.Schema{
+		"default":  routes.String(),
+		"versions": routes.Array(routes.SchemaOf(versionView{}).Named("Version")),
+	}, "default", "versions")
+}
+
+// ChangelogSchema describes the changelog of one version.
+func ChangelogSchema() *routes.Schema {
+	return routes.SchemaOf(changelogView{}).Named("Changelog")
+}
+
+func (h Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	views := make([]versionView, 0, len(apiversion.All))
+	for _, version := range apiversion.All {
+		views = append(views, h.view(version))
+	}
+	writeJSON(w, map[string]any{
+		"default":  apiversion.Default,
+		"versions": views,
+	})
+}
+
+func (h Handler) handleChangelog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.MethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+
+	name := strings.ToLower(strings.TrimSpace(r.PathValue("version")))
+	version, ok := apiversion.Lookup(name)
+	if !ok {
+		apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeVersionNotFound, "unknown API version", apierror.Details{
+			"version":   r.PathValue("version"),
+			"supported": apiversion.Names(),
+		})
+		return
+	}
+
+	writeJSON(w, changelogView{versionView: h.view(version), Changes: version.Changes})
+}
+
+func (h Handler) view(version apiversion.Version) versionView {
+	return versionView{
+		Name:      version.Name,
+		Status:    version.Status,
+		Default:   version.Name == apiversion.Default,
+		Lifecycle: h.app.Config().Versions.Lifecycle(version.Name),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, payload any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		slog.Warn("failed to write response", "error", err)
+	}
+}
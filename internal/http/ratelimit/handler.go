@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"ss-api/internal/apikeys"
+	"ss-api/internal/app"
+	"ss-api/internal/config"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/metrics"
+)
+
+var requests = metrics.NewCounterVec(
+	"stella_ratelimit_requests_total",
+	"Requests seen by the rate limiter by tier and result (allowed, limited, rejected).",
+	"tier", "result",
+)
+
+// Handler limits requests before they reach next. Settings, keys and tiers
+// are read per request and follow reloads.
+func Handler(appInstance *app.App, keys *apikeys.Store, next http.Handler) http.Handler {
+	limiter := NewLimiter()
+	metrics.NewGaugeFunc("stella_ratelimit_buckets", "Token buckets tracked by the rate limiter.", func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(limiter.Len())}}
+	})
+
+	var trusted atomic.Pointer[[]*net.IPNet]
+	trusted.Store(parseNetworks(appInstance.Config().RateLimit.TrustedProxies))
+	appInstance.OnReload(func(old, next config.Config) {
+		if !slices.Equal(old.RateLimit.TrustedProxies, next.RateLimit.TrustedProxies) {
+			trusted.Store(parseNetworks(next.RateLimit.TrustedProxies))
+		}
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := appInstance.Config().RateLimit
+		if !cfg.Enabled || exempt(cfg.ExemptPrefixes, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		quota := apikeys.Anonymous(cfg)
+		client, keyID := "ip:"+ClientIP(r, *trusted.Load()), ""
+
+		// Until keys have been loaded once, a key cannot be verified and the
+		// request is treated as anonymous rather than rejected.
+		if secret := r.Header.Get(cfg.KeyHeader); secret != "" && keys.Loaded() {
+			key, ok := keys.Lookup(secret)
+			if !ok {
+				requests.WithLabelValues("none", "rejected").Inc()
+				apierror.Write(w, r, http.StatusUnauthorized, apierror.CodeInvalidAPIKey, "invalid API key")
+				return
+			}
+			if key.Disabled {
+				requests.WithLabelValues(key.Tier, "rejected").Inc()
+				apierror.WriteDetails(w, r, http.StatusForbidden, apierror.CodeAPIKeyDisabled, "API key disabled", apierror.Details{"key": key.ID})
+				return
+			}
+			quota = keys.Quota(key, cfg)
+			client, keyID = "key:"+key.ID, key.ID
+		}
+
+		if quota.Unlimited {
+			record(keys, quota.Name, keyID, true)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		decision := limiter.Take(client, quota)
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+		header.Set("RateLimit-Policy", strconv.Itoa(quota.RequestsPerMinute)+";w=60;burst="+strconv.Itoa(quota.Burst))
+		record(keys, quota.Name, keyID, decision.Allowed)
+
+		if !decision.Allowed {
+			retryAfter := max(ceilSeconds(decision.RetryAfter), 1)
+			slog.DebugContext(r.Context(), "ratelimit: request limited", "client", client, "tier", quota.Name, "retryAfter", retryAfter)
+			header.Set("Retry-After", strconv.Itoa(retryAfter))
+			apierror.WriteDetails(w, r, http.StatusTooManyRequests, apierror.CodeRateLimited, "rate limit exceeded, retry in "+strconv.Itoa(retryAfter)+"s", apierror.Details{
+				"tier":       quota.Name,
+				"retryAfter": retryAfter,
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientIP returns the address of the client that sent r. X-Forwarded-For
+// is only followed through proxies listed in trusted: the rightmost entry
+// that is not a trusted proxy is the client, so entries a client prepends
+// itself are ignored.
+func ClientIP(r *http.Request, trusted []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if len(trusted) == 0 || !isTrusted(trusted, host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		host = hop
+		if !isTrusted(trusted, hop) {
+			break
+		}
+	}
+	return host
+}
+
+func isTrusted(trusted []*net.IPNet, host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return slices.ContainsFunc(trusted, func(network *net.IPNet) bool {
+		return network.Contains(ip)
+	})
+}
+
+// parseNetworks parses validated trusted_proxies entries.
+func parseNetworks(values []string) *[]*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		if network, err := config.ParseNetwork(value); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return &networks
+}
+
+func exempt(prefixes []string, path string) bool {
+	return slices.ContainsFunc(prefixes, func(prefix string) bool {
+		return strings.HasPrefix(path, prefix)
+	})
+}
+
+// record counts a request per tier. Per-key counts stay in the key store,
+// served by the admin API: key IDs are not exposed on the public /metrics.
+func record(keys *apikeys.Store, tier, keyID string, allowed bool) {
+	result := "allowed"
+	if !allowed {
+		result = "limited"
+	}
+	requests.WithLabelValues(tier, result).Inc()
+	if keyID != "" {
+		keys.RecordUse(keyID, !allowed)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,103 @@
+// Package ratelimit throttles the public API with token buckets: one per
+// client IP for anonymous traffic and one per API key, sized by the key's
+// tier. Every limited response carries RateLimit-* headers and rejected
+// requests get 429 with Retry-After.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"ss-api/internal/apikeys"
+)
+
+// sweepInterval is how often buckets that have refilled completely are
+// dropped; a full bucket behaves exactly like a new one.
+const sweepInterval = time.Minute
+
+// Limiter holds the token buckets of every active client.
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will have refilled completely.
+	full time.Time
+}
+
+// Decision is the outcome of one Take and the values for the headers.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next request would be allowed.
+	RetryAfter time.Duration
+}
+
+// NewLimiter returns an empty limiter.
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+// Take spends one token from the bucket of client. The quota is passed on
+// every call so reloaded limits and tier changes apply immediately.
+func (l *Limiter) Take(client string, quota apikeys.Quota) Decision {
+	rate := float64(quota.RequestsPerMinute) / 60
+	capacity := float64(quota.Burst)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	decision := Decision{Limit: quota.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	decision.Remaining = int(math.Floor(b.tokens))
+	decision.Reset = seconds((capacity - b.tokens) / rate)
+	b.full = now.Add(decision.Reset)
+	return decision
+}
+
+// Len returns the number of tracked buckets.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for client, b := range l.buckets {
+		if !now.Before(b.full) {
+			delete(l.buckets, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ss-api/internal/apikeys"
+)
+
+// fakeClock returns a limiter whose time only moves through advance.
+func fakeClock() (*Limiter, func(time.Duration)) {
+	now := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter()
+	l.now = func() time.Time { return now }
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestTakeRefills(t *testing.T) {
+	l, advance := fakeClock()
+	quota := apikeys.Quota{Name: "anonymous", RequestsPerMinute: 60, Burst: 2}
+
+	for i := range 2 {
+		if d := l.Take("client", quota); !d.Allowed || d.Remaining != 1-i {
+			t.Fatalf("take %d = %+v, want allowed with %d remaining", i, d, 1-i)
+		}
+	}
+
+	d := l.Take("client", quota)
+	if d.Allowed || d.RetryAfter != time.Second || d.Reset != 2*time.Second || d.Limit != 2 {
+		t.Fatalf("third take = %+v, want denied, retry after 1s, reset in 2s", d)
+	}
+
+	advance(500 * time.Millisecond)
+	d = l.Take("client", quota)
+	if d.Allowed || d.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("take after 0.5s = %+v, want denied, retry after 0.5s", d)
+	}
+	// Retry-After is sent in whole seconds, rounded up.
+	if got := ceilSeconds(d.RetryAfter); got != 1 {
+		t.Errorf("Retry-After = %d, want 1", got)
+	}
+
+	advance(500 * time.Millisecond)
+	if d := l.Take("client", quota); !d.Allowed || d.Remaining != 0 {
+		t.Fatalf("take after 1s = %+v, want allowed", d)
+	}
+
+	if d := l.Take("other", quota); !d.Allowed || d.Remaining != 1 {
+		t.Errorf("another client = %+v, want its own full bucket", d)
+	}
+}
+
+func TestTakeAppliesQuotaChanges(t *testing.T) {
+	l, _ := fakeClock()
+	l.Take("client", apikeys.Quota{RequestsPerMinute: 60, Burst: 1})
+	if d := l.Take("client", apikeys.Quota{RequestsPerMinute: 60, Burst: 1}); d.Allowed {
+		t.Fatal("empty bucket allowed a request")
+	}
+	// A larger burst does not refill the bucket on its own.
+	if d := l.Take("client", apikeys.Quota{RequestsPerMinute: 60, Burst: 10}); d.Allowed || d.Limit != 10 {
+		t.Errorf("take with a new quota = %+v, want denied with limit 10", d)
+	}
+}
+
+func TestSweepDropsFullBuckets(t *testing.T) {
+	l, advance := fakeClock()
+	quota := apikeys.Quota{RequestsPerMinute: 60, Burst: 10}
+
+	l.Take("idle", quota)
+	advance(sweepInterval)
+	l.Take("busy", quota)
+	if n := l.Len(); n != 1 {
+		t.Fatalf("buckets = %d after the idle bucket refilled, want 1", n)
+	}
+
+	// A bucket that is still refilling survives the sweep: at one request
+	// a minute, a drained bucket of 10 takes 10 minutes to fill.
+	slow := apikeys.Quota{RequestsPerMinute: 1, Burst: 10}
+	for range 10 {
+		l.Take("drained", slow)
+	}
+	advance(sweepInterval)
+	l.Take("busy", quota)
+	if n := l.Len(); n != 2 {
+		t.Errorf("buckets = %d, want the busy and the drained one", n)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	trusted := []*net.IPNet{proxies}
+
+	for name, tc := range map[string]struct {
+		remote  string
+		xff     []string
+		trusted []*net.IPNet
+		want    string
+	}{
+		"direct":                  {remote: "203.0.113.7:5000", want: "203.0.113.7"},
+		"no trusted proxies":      {remote: "10.0.0.1:5000", xff: []string{"198.51.100.1"}, want: "10.0.0.1"},
+		"untrusted sender":        {remote: "203.0.113.7:5000", xff: []string{"198.51.100.1"}, trusted: trusted, want: "203.0.113.7"},
+		"through a proxy":         {remote: "10.0.0.1:5000", xff: []string{"198.51.100.1"}, trusted: trusted, want: "198.51.100.1"},
+		"through two proxies":     {remote: "10.0.0.1:5000", xff: []string{"198.51.100.1, 10.0.0.2"}, trusted: trusted, want: "198.51.100.1"},
+		"spoofed leading entry":   {remote: "10.0.0.1:5000", xff: []string{"6.6.6.6, 198.51.100.1"}, trusted: trusted, want: "198.51.100.1"},
+		"spoofed header line":     {remote: "10.0.0.1:5000", xff: []string{"6.6.6.6", "198.51.100.1"}, trusted: trusted, want: "198.51.100.1"},
+		"garbage stops the walk":  {remote: "10.0.0.1:5000", xff: []string{"198.51.100.1, not-an-ip"}, trusted: trusted, want: "10.0.0.1"},
+		"only proxies in the hop": {remote: "10.0.0.1:5000", xff: []string{"10.0.0.3, 10.0.0.2"}, trusted: trusted, want: "10.0.0.3"},
+		"remote without port":     {remote: "203.0.113.7", want: "203.0.113.7"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/stella/characters", nil)
+			r.RemoteAddr = tc.remote
+			for _, value := range tc.xff {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := ClientIP(r, tc.trusted); got != tc.want {
+				t.Errorf("ClientIP = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
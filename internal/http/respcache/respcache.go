@@ -0,0 +1,771 @@
+// Package respcache is the shared HTTP response cache. It keeps rendered
+// payloads in memory keyed by path, negotiated locale and the query
+// parameters a route depends on, and answers conditional requests with 304
+// using strong ETags computed from the payload bytes.
+//
+// Concurrent misses for the same key share a single render. Once a payload
+// expires it keeps being served, for up to cache.stale_ttl, while one
+// background render replaces it.
+package respcache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"ss-api/internal/app"
+	"ss-api/internal/config"
+	"ss-api/internal/http/compress"
+	"ss-api/internal/locale"
+	"ss-api/internal/metrics"
+)
+
+// DefaultMaxEntries bounds the store when New is given no limit.
+const DefaultMaxEntries = 10000
+
+// Policy describes how one route is cached.
+type Policy struct {
+	// Name labels the route in cache metrics and the status payload, e.g.
+	// "characters.list".
+	Name string
+	// Route is the mux pattern without the method, e.g.
+	// "/stella/character/{identifier}". It is used to purge by route.
+	Route string
+	// Params lists the query parameters that select a different payload.
+	// Other parameters are ignored for the key. The locale negotiated from
+	// lang and Accept-Language is always included.
+	Params []string
+	// Collections lists the Mongo catalog collections the payload is built
+	// from, e.g. banners read gacha, characters and discs. Entries are
+	// purged per region when any of them changes.
+	Collections []string
+	// TTL returns how long a payload is served from memory. It is called on
+	// every store so reloaded settings apply immediately. A nil TTL or a
+	// zero duration keeps nothing in memory; responses still carry
+	// validators and conditional requests still get 304s.
+	TTL func() time.Duration
+	// CacheControl is sent with every 200 and 304 response of the route.
+	CacheControl string
+}
+
+// CacheHeader reports how a response was produced: HIT, STALE or MISS.
+const CacheHeader = "X-Cache"
+
+var (
+	cacheFills = metrics.NewCounterVec(
+		"stella_cache_fills_total",
+		"Response cache renders by cache and trigger (miss or refresh).",
+		"cache", "trigger",
+	)
+	cacheCoalesced = metrics.NewCounterVec(
+		"stella_cache_coalesced_total",
+		"Requests that waited for another request's render instead of rendering themselves.",
+		"cache",
+	)
+)
+
+// Public returns a Cache-Control value that lets browsers and shared caches
+// reuse a response for maxAge before revalidating it with its ETag.
+func Public(maxAge time.Duration) string {
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
+// Store holds cached responses for every route.
+type Store struct {
+	app        *app.App
+	maxEntries int
+	group      singleflight.Group
+
+	mu      sync.RWMutex
+	entries map[string]*entry
+	// generations counts the purges of each collection. A render that saw
+	// a different count than the store does is not kept, so a render that
+	// started before a catalog change cannot bring its payload back.
+	generations map[string]uint64
+	// refreshing holds the keys with a background refresh running.
+	refreshing map[string]bool
+}
+
+type entry struct {
+	policy      string
+	route       string
+	collections []string
+	lang        string
+	// served is the region the payload was rendered from, which differs
+	// from lang when the handler fell back. regions holds every region from
+	// lang to served: a change to any of them can change the payload.
+	served  string
+	regions []string
+	header  http.Header
+	body    []byte
+	etag    string
+	// variants holds the body precompressed per content coding.
+	variants     map[string][]byte
+	lastModified time.Time
+	expires      time.Time
+	// staleUntil is when the entry can no longer be served, even while a
+	// refresh is pending.
+	staleUntil time.Time
+}
+
+// New creates a store. Every policy with a TTL passed to Handler is
+// registered with appInstance as a cache so its size shows up in diagnostics,
+// and entries are purged when the app reports a catalog change.
+func New(appInstance *app.App, maxEntries int) *Store {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	s := &Store{
+		app:         appInstance,
+		maxEntries:  maxEntries,
+		entries:     make(map[string]*entry),
+		generations: make(map[string]uint64),
+		refreshing:  make(map[string]bool),
+	}
+	if appInstance != nil {
+		appInstance.OnCatalogChange(func(change app.CatalogChange) {
+			removed := s.PurgeCollection(change.Collection, change.Regions)
+			slog.Debug("response cache purged", "collection", change.Collection, "regions", change.Regions, "entries", removed)
+		})
+	}
+	return s
+}
+
+// Handler wraps next with the cache for policy p. Only GET and HEAD requests
+// are cached, and only 200 responses are stored. next always renders a GET,
+// so a HEAD shares the GET entry and gets its headers without the body.
+func (s *Store) Handler(p Policy, next http.HandlerFunc) http.HandlerFunc {
+	if s.app != nil && p.Name != "" && p.TTL != nil {
+		s.app.RegisterCache(p.Name, policyView{store: s, policy: p.Name})
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		key, sel := s.cacheKey(p, r)
+
+		if p.TTL == nil {
+			rec := render(next, r)
+			if rec.status != http.StatusOK {
+				rec.flush(w, r)
+				return
+			}
+			s.serve(w, r, p, s.store(p, key, sel, rec, 0), "")
+			return
+		}
+
+		switch e, state := s.lookup(key); state {
+		case fresh:
+			metrics.CacheLookups.WithLabelValues(p.Name, "hit").Inc()
+			s.serve(w, r, p, e, "HIT")
+			return
+		case stale:
+			metrics.CacheLookups.WithLabelValues(p.Name, "stale").Inc()
+			s.refresh(p, key, sel, next, r)
+			s.serve(w, r, p, e, "STALE")
+			return
+		}
+
+		metrics.CacheLookups.WithLabelValues(p.Name, "miss").Inc()
+		res, shared := s.fill(p, key, sel, next, r.Clone(context.WithoutCancel(r.Context())), "miss")
+		if shared {
+			cacheCoalesced.WithLabelValues(p.Name).Inc()
+		}
+		if res.rec.status != http.StatusOK {
+			// Errors are not shared: they carry the request ID of the
+			// request that rendered them.
+			if shared {
+				res.rec = render(next, r)
+			}
+			res.rec.flush(w, r)
+			return
+		}
+		s.serve(w, r, p, res.entry, "MISS")
+	}
+}
+
+type fillResult struct {
+	rec   *recorder
+	entry *entry
+}
+
+// fill renders key once no matter how many requests ask for it at the same
+// time, with r's context. Client requests pass a context detached from their
+// cancellation so a client that disconnects does not fail the render for
+// everyone waiting on it. It always runs as a GET, so a HEAD that starts the
+// fill hands concurrent GETs the full payload.
+func (s *Store) fill(p Policy, key string, sel locale.Selection, next http.HandlerFunc, r *http.Request, trigger string) (fillResult, bool) {
+	v, _, shared := s.group.Do(key, func() (any, error) {
+		cacheFills.WithLabelValues(p.Name, trigger).Inc()
+
+		generation := s.generation(p.Collections)
+		rec := render(next, r)
+		res := fillResult{rec: rec}
+		if rec.status == http.StatusOK {
+			res.entry = s.store(p, key, sel, rec, generation)
+		}
+		return res, nil
+	})
+	return v.(fillResult), shared
+}
+
+// refresh starts a background render of a stale key unless one is already
+// running. Failed renders leave the stale payload in place.
+func (s *Store) refresh(p Policy, key string, sel locale.Selection, next http.HandlerFunc, r *http.Request) {
+	s.mu.Lock()
+	if s.refreshing[key] {
+		s.mu.Unlock()
+		return
+	}
+	s.refreshing[key] = true
+	s.mu.Unlock()
+
+	r = r.Clone(context.WithoutCancel(r.Context()))
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.refreshing, key)
+			s.mu.Unlock()
+		}()
+		s.fill(p, key, sel, next, r, "refresh")
+	}()
+}
+
+// generation returns the purge count of collections; it changes whenever
+// one of them is purged.
+func (s *Store) generation(collections []string) uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.generationLocked(collections)
+}
+
+func (s *Store) generationLocked(collections []string) uint64 {
+	var sum uint64
+	for _, collection := range collections {
+		sum += s.generations[collection]
+	}
+	return sum
+}
+
+// Warm renders target through next and stores the result as if a client had
+// requested it, replacing any cached payload. Responses other than 200 are
+// not stored; 5xx responses are reported as errors. The render runs with
+// ctx, so a job timeout or shutdown cancels it.
+func (s *Store) Warm(ctx context.Context, p Policy, next http.HandlerFunc, target string) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+
+	key, sel := s.cacheKey(p, r)
+	res, _ := s.fill(p, key, sel, next, r, "refresh")
+	rec := res.rec
+	switch {
+	case rec.status == http.StatusOK:
+		return nil
+	case rec.status >= http.StatusInternalServerError:
+		return fmt.Errorf("warm %s: status %d", target, rec.status)
+	default:
+		return nil
+	}
+}
+
+// Filter selects cached responses. Empty fields match everything.
+type Filter struct {
+	// Route matches the policy route pattern, e.g. "/stella/characters",
+	// or the policy name, e.g. "characters.list".
+	Route string
+	// Region matches the locale the response was requested in, e.g. "EN",
+	// compared case-insensitively.
+	Region string
+	// Key matches one cache key exactly, as listed by Entries.
+	Key string
+}
+
+func (f Filter) matches(key string, e *entry) bool {
+	switch {
+	case f.Key != "" && key != f.Key:
+		return false
+	case f.Route != "" && e.route != f.Route && e.policy != f.Route:
+		return false
+	case f.Region != "" && !strings.EqualFold(e.lang, f.Region):
+		return false
+	default:
+		return true
+	}
+}
+
+// Purge drops the cached responses selected by f and returns how many were
+// removed. A zero Filter empties the store.
+func (s *Store) Purge(f Filter) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for key, e := range s.entries {
+		if !f.matches(key, e) {
+			continue
+		}
+		delete(s.entries, key)
+		removed++
+	}
+	return removed
+}
+
+// EntryInfo describes one cached response.
+type EntryInfo struct {
+	Key    string `json:"key"`
+	Policy string `json:"policy"`
+	Route  string `json:"route"`
+	Region string `json:"region"`
+	// Served is the region the payload came from when it fell back.
+	Served string `json:"served,omitempty"`
+	Bytes  int    `json:"bytes"`
+	// Encoded lists the size of each precompressed variant.
+	Encoded      map[string]int `json:"encoded,omitempty"`
+	ETag         string         `json:"etag"`
+	LastModified time.Time      `json:"lastModified"`
+	Expires      time.Time      `json:"expires"`
+	Stale        bool           `json:"stale"`
+}
+
+// Entries lists the cached responses selected by f, ordered by key.
+func (s *Store) Entries(f Filter) []EntryInfo {
+	now := time.Now()
+
+	s.mu.RLock()
+	result := make([]EntryInfo, 0, len(s.entries))
+	for key, e := range s.entries {
+		if !f.matches(key, e) {
+			continue
+		}
+		var encoded map[string]int
+		if len(e.variants) > 0 {
+			encoded = make(map[string]int, len(e.variants))
+			for encoding, variant := range e.variants {
+				encoded[encoding] = len(variant)
+			}
+		}
+		result = append(result, EntryInfo{
+			Key:          key,
+			Policy:       e.policy,
+			Route:        e.route,
+			Region:       e.lang,
+			Served:       servedIfFallback(e),
+			Bytes:        len(e.body),
+			Encoded:      encoded,
+			ETag:         e.etag,
+			LastModified: e.lastModified,
+			Expires:      e.expires,
+			Stale:        now.After(e.expires),
+		})
+	}
+	s.mu.RUnlock()
+
+	slices.SortFunc(result, func(a, b EntryInfo) int { return strings.Compare(a.Key, b.Key) })
+	return result
+}
+
+// PurgeCollection drops cached responses built from collection for the
+// given regions, including responses that fell back past one of them. No
+// regions means every region. It returns the number of entries removed.
+func (s *Store) PurgeCollection(collection string, regions []string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generations[collection]++
+	removed := 0
+	for key, e := range s.entries {
+		if !slices.Contains(e.collections, collection) {
+			continue
+		}
+		if len(regions) > 0 && !slices.ContainsFunc(regions, func(region string) bool {
+			return slices.ContainsFunc(e.regions, func(depends string) bool {
+				return strings.EqualFold(region, depends)
+			})
+		}) {
+			continue
+		}
+		delete(s.entries, key)
+		removed++
+	}
+	return removed
+}
+
+// Len reports the number of cached responses, including stale ones that
+// have not been replaced yet.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+type entryState int
+
+const (
+	missing entryState = iota
+	fresh
+	stale
+)
+
+func (s *Store) lookup(key string) (*entry, entryState) {
+	s.mu.RLock()
+	e, ok := s.entries[key]
+	s.mu.RUnlock()
+
+	switch now := time.Now(); {
+	case !ok || now.After(e.staleUntil):
+		return nil, missing
+	case now.After(e.expires):
+		return e, stale
+	default:
+		return e, fresh
+	}
+}
+
+// staleWindow is how long an expired entry may still be served while it is
+// refreshed. Zero disables stale-while-revalidate.
+func (s *Store) staleWindow() time.Duration {
+	if s.app == nil {
+		return 0
+	}
+	return max(s.app.Config().Cache.StaleTTL, 0)
+}
+
+// store builds the entry for rec and keeps it when the policy has a TTL
+// and none of its collections was purged since generation was read.
+// Last-Modified only moves forward when the payload actually changed.
+func (s *Store) store(p Policy, key string, sel locale.Selection, rec *recorder, generation uint64) *entry {
+	body := rec.body.Bytes()
+	now := time.Now()
+
+	// Handlers that fall back name the region they used in Content-Language.
+	served := sel.Locale
+	if l, ok := locale.Parse(rec.header.Get("Content-Language")); ok {
+		served = l
+	}
+	regions := make([]string, 0, len(sel.Chain))
+	for _, l := range sel.Through(served) {
+		regions = append(regions, string(l))
+	}
+
+	e := &entry{
+		policy:       p.Name,
+		route:        p.Route,
+		collections:  p.Collections,
+		lang:         string(sel.Locale),
+		served:       string(served),
+		regions:      regions,
+		header:       rec.header.Clone(),
+		body:         body,
+		etag:         computeETag(body),
+		lastModified: now,
+	}
+
+	var ttl time.Duration
+	if p.TTL != nil {
+		ttl = p.TTL()
+	}
+	if cfg := s.compression(); ttl > 0 && compress.Eligible(cfg, e.header.Get("Content-Type"), len(body)) {
+		e.variants = precompress(cfg, body)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if previous, ok := s.entries[key]; ok && previous.etag == e.etag {
+		e.lastModified = previous.lastModified
+	}
+
+	if ttl <= 0 {
+		delete(s.entries, key)
+		return e
+	}
+	if s.generationLocked(p.Collections) != generation {
+		slog.Debug("response cache: render outdated by a catalog change, not stored", "key", key)
+		return e
+	}
+
+	e.expires = now.Add(ttl)
+	e.staleUntil = e.expires.Add(s.staleWindow())
+	if _, exists := s.entries[key]; !exists && len(s.entries) >= s.maxEntries {
+		s.evictLocked(now)
+	}
+	s.entries[key] = e
+
+	return e
+}
+
+// evictLocked removes entries past their stale window, or one arbitrary
+// entry when there are none. It must be called with s.mu held.
+func (s *Store) evictLocked(now time.Time) {
+	removed := false
+	for key, e := range s.entries {
+		if now.After(e.staleUntil) {
+			delete(s.entries, key)
+			removed = true
+		}
+	}
+	if removed {
+		return
+	}
+	for key := range s.entries {
+		delete(s.entries, key)
+		return
+	}
+}
+
+// compression returns the current compression settings, or disabled ones
+// when the store has no app.
+func (s *Store) compression() config.CompressionConfig {
+	if s.app == nil {
+		return config.CompressionConfig{}
+	}
+	return s.app.Config().Compression
+}
+
+// precompress encodes body in every supported coding so cache hits only
+// copy bytes.
+func precompress(cfg config.CompressionConfig, body []byte) map[string][]byte {
+	variants := make(map[string][]byte, 2)
+	for _, encoding := range []string{compress.Brotli, compress.Gzip} {
+		encoded, err := compress.Encode(cfg, encoding, body)
+		if err != nil {
+			slog.Warn("response cache: precompression failed", "encoding", encoding, "error", err)
+			continue
+		}
+		variants[encoding] = encoded
+	}
+	return variants
+}
+
+// encode returns the body in encoding, from the stored variant when there is
+// one. It returns "" and nil when the body goes out uncompressed.
+func (e *entry) encode(cfg config.CompressionConfig, encoding string) (string, []byte) {
+	if encoding == "" {
+		return "", nil
+	}
+	if variant, ok := e.variants[encoding]; ok {
+		compress.Observe(encoding, "cache")
+		return encoding, variant
+	}
+	encoded, err := compress.Encode(cfg, encoding, e.body)
+	if err != nil {
+		slog.Warn("response cache: compression failed", "encoding", encoding, "error", err)
+		return "", nil
+	}
+	compress.Observe(encoding, "live")
+	return encoding, encoded
+}
+
+func (s *Store) count(policy string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := 0
+	for _, e := range s.entries {
+		if e.policy == policy {
+			n++
+		}
+	}
+	return n
+}
+
+// policyView reports the entries of one policy to app.RegisterCache.
+type policyView struct {
+	store  *Store
+	policy string
+}
+
+func (v policyView) Len() int {
+	return v.store.count(v.policy)
+}
+
+// cacheKey combines the request path, the negotiated locale and the
+// policy's parameters. The locale is resolved the same way the handlers
+// resolve it, so "lang=ja", "lang=JP" and "Accept-Language: ja" share one
+// entry. An unsupported lang keeps its raw value; the handler rejects it
+// and nothing is stored.
+func (s *Store) cacheKey(p Policy, r *http.Request) (key string, sel locale.Selection) {
+	query := r.URL.Query()
+
+	negotiator := locale.Negotiator{Default: locale.EN}
+	if s.app != nil {
+		negotiator = s.app.Config().Locale.Negotiator()
+	}
+	sel, ok := negotiator.Resolve(r)
+	lang := string(sel.Locale)
+	if !ok {
+		lang = "!" + query.Get("lang")
+	}
+
+	selected := url.Values{}
+	for _, name := range p.Params {
+		if values, ok := query[name]; ok {
+			selected[name] = values
+		}
+	}
+
+	return r.URL.Path + "|" + lang + "|" + selected.Encode(), sel
+}
+
+// servedIfFallback reports the served region only when it differs from the
+// requested one.
+func servedIfFallback(e *entry) string {
+	if e.served == e.lang {
+		return ""
+	}
+	return e.served
+}
+
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// serve writes e, or 304 when the request's validators match it. An empty
+// source omits the X-Cache header. The body is sent in the coding the client
+// prefers, from the stored variant when there is one.
+func (s *Store) serve(w http.ResponseWriter, r *http.Request, p Policy, e *entry, source string) {
+	header := w.Header()
+	for name, values := range e.header {
+		header[name] = values
+	}
+
+	body, etag := e.body, e.etag
+	if cfg := s.compression(); compress.Eligible(cfg, e.header.Get("Content-Type"), len(e.body)) {
+		compress.AddVary(header)
+		if encoding, encoded := e.encode(cfg, compress.Negotiate(r.Header.Get("Accept-Encoding"))); encoding != "" {
+			body, etag = encoded, compress.VariantETag(e.etag, encoding)
+			header.Set("Content-Encoding", encoding)
+		}
+	}
+
+	header.Set("ETag", etag)
+	header.Set("Last-Modified", e.lastModified.UTC().Format(http.TimeFormat))
+	if p.CacheControl != "" {
+		header.Set("Cache-Control", p.CacheControl)
+	}
+	if source != "" {
+		header.Set(CacheHeader, source)
+	}
+
+	if notModified(r, etag, e.lastModified) {
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+		header.Del("Content-Encoding")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(body); err != nil {
+		slog.WarnContext(r.Context(), "failed to write response", "error", err)
+	}
+}
+
+// notModified applies If-None-Match, falling back to If-Modified-Since only
+// when no entity tag was sent (RFC 9110, section 13.2.2).
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		since, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// etagMatches uses the weak comparison RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// recorder buffers a handler's response so it can be hashed and stored
+// before anything reaches the client.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// render runs next as a GET: handlers only answer GET, and the payload of a
+// HEAD is the one a GET would get.
+func render(next http.HandlerFunc, r *http.Request) *recorder {
+	if r.Method != http.MethodGet {
+		r = r.Clone(r.Context())
+		r.Method = http.MethodGet
+	}
+
+	rec := &recorder{header: make(http.Header)}
+	next(rec, r)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
+
+// flush copies an uncached response to the client unchanged, without the
+// body for a HEAD.
+func (rec *recorder) flush(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	for name, values := range rec.header {
+		header[name] = values
+	}
+	w.WriteHeader(rec.status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(rec.body.Bytes())
+}
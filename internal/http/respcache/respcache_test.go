@@ -0,0 +1,258 @@
+package respcache
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ss-api/internal/app"
+	"ss-api/internal/config"
+)
+
+// getOnly answers like the catalog handlers: 405 for anything but GET.
+func getOnly(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = io.WriteString(w, `{"ok":true}`)
+}
+
+func TestHeadRendersAsGet(t *testing.T) {
+	for name, ttl := range map[string]func() time.Duration{
+		"stored":     func() time.Duration { return time.Minute },
+		"not stored": nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler := New(nil, 0).Handler(Policy{Name: "test", Route: "/test", TTL: ttl}, getOnly)
+
+			head := httptest.NewRecorder()
+			handler(head, httptest.NewRequest(http.MethodHead, "/test", nil))
+			if head.Code != http.StatusOK {
+				t.Fatalf("HEAD status = %d, want 200", head.Code)
+			}
+			if head.Body.Len() != 0 {
+				t.Errorf("HEAD body = %q, want none", head.Body.String())
+			}
+			if head.Header().Get("ETag") == "" || head.Header().Get("Content-Length") != "11" {
+				t.Errorf("HEAD headers = %v, want ETag and Content-Length 11", head.Header())
+			}
+
+			get := httptest.NewRecorder()
+			handler(get, httptest.NewRequest(http.MethodGet, "/test", nil))
+			if get.Code != http.StatusOK || get.Body.String() != `{"ok":true}` {
+				t.Fatalf("GET = %d %q", get.Code, get.Body.String())
+			}
+			if get.Header().Get("ETag") != head.Header().Get("ETag") {
+				t.Errorf("GET ETag %s differs from HEAD ETag %s", get.Header().Get("ETag"), head.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestConcurrentHeadAndGetShareFill(t *testing.T) {
+	var renders atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := New(nil, 0).Handler(Policy{
+		Name:  "test",
+		Route: "/test",
+		TTL:   func() time.Duration { return time.Minute },
+	}, func(w http.ResponseWriter, r *http.Request) {
+		if renders.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		getOnly(w, r)
+	})
+
+	var wg sync.WaitGroup
+	head, get := httptest.NewRecorder(), httptest.NewRecorder()
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		handler(head, httptest.NewRequest(http.MethodHead, "/test", nil))
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		handler(get, httptest.NewRequest(http.MethodGet, "/test", nil))
+	}()
+	// Give the GET time to join the fill the HEAD started.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if head.Code != http.StatusOK || head.Body.Len() != 0 {
+		t.Errorf("HEAD = %d %q, want 200 without body", head.Code, head.Body.String())
+	}
+	if get.Code != http.StatusOK || get.Body.String() != `{"ok":true}` {
+		t.Errorf("GET = %d %q, want 200 with body", get.Code, get.Body.String())
+	}
+	if n := renders.Load(); n != 1 {
+		t.Errorf("renders = %d, want 1", n)
+	}
+}
+
+func TestWarmUsesCallerContext(t *testing.T) {
+	store := New(nil, 0)
+	p := Policy{Name: "test", Route: "/test", TTL: func() time.Duration { return time.Minute }}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := store.Warm(ctx, p, func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Err() != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		getOnly(w, r)
+	}, "/test")
+	if err == nil {
+		t.Fatal("Warm with a cancelled context succeeded; the render did not see the cancellation")
+	}
+	if n := len(store.Entries(Filter{})); n != 0 {
+		t.Errorf("stored %d entries, want 0", n)
+	}
+}
+
+func TestPurgeCollectionMatchesEveryListedCollection(t *testing.T) {
+	store := New(nil, 0)
+	handler := store.Handler(Policy{
+		Name:        "banners",
+		Route:       "/test",
+		Collections: []string{"gacha", "characters"},
+		TTL:         func() time.Duration { return time.Minute },
+	}, getOnly)
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test?lang=EN", nil))
+
+	if n := store.PurgeCollection("discs", []string{"EN"}); n != 0 {
+		t.Errorf("purging discs removed %d entries, want 0", n)
+	}
+	if n := store.PurgeCollection("characters", []string{"EN"}); n != 1 {
+		t.Errorf("purging characters removed %d entries, want 1", n)
+	}
+}
+
+func TestPurgeDuringRenderIsNotStored(t *testing.T) {
+	store := New(nil, 0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := store.Handler(Policy{
+		Name:        "characters.list",
+		Route:       "/test",
+		Collections: []string{"characters"},
+		TTL:         func() time.Duration { return time.Minute },
+	}, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		getOnly(w, r)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test?lang=EN", nil))
+	}()
+	<-started
+	store.PurgeCollection("characters", []string{"EN"})
+	close(release)
+	<-done
+
+	if n := len(store.Entries(Filter{})); n != 0 {
+		t.Errorf("stored %d entries rendered before the purge, want 0", n)
+	}
+}
+
+func TestStaleBurstStartsOneRefresh(t *testing.T) {
+	store := New(app.New(config.Default()), 0)
+	var renders atomic.Int32
+	release := make(chan struct{})
+	handler := store.Handler(Policy{
+		Name:  "test",
+		Route: "/test",
+		TTL:   func() time.Duration { return time.Millisecond },
+	}, func(w http.ResponseWriter, r *http.Request) {
+		if renders.Add(1) > 1 {
+			<-release
+		}
+		getOnly(w, r)
+	})
+
+	handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	time.Sleep(5 * time.Millisecond)
+
+	before := runtime.NumGoroutine()
+	for range 50 {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+		if got := rec.Header().Get(CacheHeader); got != "STALE" {
+			t.Fatalf("X-Cache = %q, want STALE", got)
+		}
+	}
+	if grown := runtime.NumGoroutine() - before; grown > 5 {
+		t.Errorf("%d goroutines started by 50 stale hits, want one refresh", grown)
+	}
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for renders.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := renders.Load(); n != 2 {
+		t.Errorf("renders = %d, want 2", n)
+	}
+}
+
+func TestSharedFillDoesNotShareErrors(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var renders atomic.Int32
+	handler := New(nil, 0).Handler(Policy{
+		Name:  "test",
+		Route: "/test",
+		TTL:   func() time.Duration { return time.Minute },
+	}, func(w http.ResponseWriter, r *http.Request) {
+		if renders.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, r.Header.Get("X-Request-Id"))
+	})
+
+	request := func(id string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/test", nil)
+		r.Header.Set("X-Request-Id", id)
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+		return rec
+	}
+
+	var wg sync.WaitGroup
+	var first, second *httptest.ResponseRecorder
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		first = request("first")
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		second = request("second")
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if first.Body.String() != "first" || second.Body.String() != "second" {
+		t.Errorf("bodies = %q, %q; want each request's own error", first.Body.String(), second.Body.String())
+	}
+}
@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// redocScript is the Redoc bundle the docs page loads.
+const redocScript = "https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"
+
+// docsPolicy replaces the API's default Content-Security-Policy, which
+// allows no scripts, for the docs page only.
+const docsPolicy = "default-src 'none'; script-src https://cdn.redoc.ly; style-src 'unsafe-inline'; " +
+	"img-src 'self' data: https:; connect-src 'self'; worker-src blob:"
+
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>body { margin: 0; }</style>
+</head>
+<body>
+<redoc spec-url="{{.SpecURL}}"></redoc>
+<script src="{{.Script}}"></script>
+</body>
+</html>
+`))
+
+// DocsHandler serves a Redoc page that renders the document at specURL.
+func DocsHandler(title, specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		header.Set("Content-Security-Policy", docsPolicy)
+		_ = docsPage.Execute(w, map[string]string{
+			"Title":   title,
+			"SpecURL": specURL,
+			"Script":  redocScript,
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"ss-api/internal/apiversion"
+)
+
+// Info describes the API in the document.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Options are the parts of the document that do not come from the routes.
+type Options struct {
+	Info Info
+	// KeyHeader is the header that carries an API key.
+	KeyHeader string
+}
+
+// Document is an OpenAPI 3.0 document.
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Tags       []tag                 `json:"tags,omitempty"`
+	Security   []map[string][]string `json:"security,omitempty"`
+	Paths      map[string]pathItem   `json:"paths"`
+	Components components            `json:"components"`
+}
+
+type tag struct {
+	Name string `json:"name"`
+}
+
+// pathItem maps a lower-case method to its operation.
+type pathItem map[string]*operation
+
+type operation struct {
+	OperationID string                `json:"operationId,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+const (
+	jsonType = "application/json"
+
+	adminScheme  = "adminToken"
+	apiKeyScheme = "apiKey"
+	errorSchema  = "Error"
+)
+
+// errorEnvelope mirrors the body written by apierror.
+var errorEnvelope = Object(map[string]*Schema{
+	"error": Object(map[string]*Schema{
+		"code":      String().Describe("Stable machine-readable error code, e.g. character_not_found."),
+		"message":   String(),
+		"details":   Map(Any()),
+		"requestId": String(),
+	}, "code", "message", "details", "requestId"),
+}, "error")
+
+// Document builds the OpenAPI document of one version: its versioned
+// routes under /stella/{version}/ and every unversioned route. Routes of a
+// deprecated version are marked deprecated.
+func (reg *Registry) Document(version string, opts Options) *Document {
+	b := &builder{schemas: map[string]*Schema{errorSchema: errorEnvelope}}
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    opts.Info,
+		// Anonymous access is allowed everywhere a key is accepted.
+		Security: []map[string][]string{{}, {apiKeyScheme: {}}},
+		Paths:    map[string]pathItem{},
+		Components: components{
+			Schemas: b.schemas,
+			SecuritySchemes: map[string]securityScheme{
+				adminScheme: {Type: "http", Scheme: "bearer", Description: "A token from admin.tokens."},
+				apiKeyScheme: {Type: "apiKey", In: "header", Name: opts.KeyHeader,
+					Description: "Optional API key; requests without one use the anonymous rate limit."},
+			},
+		},
+	}
+
+	deprecated := false
+	if reg.lifecycle != nil {
+		deprecated = !reg.lifecycle(version).Deprecated.IsZero()
+	}
+
+	seen := map[string]bool{}
+	for _, route := range reg.routes {
+		path := route.DocPath()
+		if versions := route.Versions(); versions != nil {
+			if !slices.Contains(versions, version) {
+				continue
+			}
+			path = docPath(VersionPath(version, route.Path()))
+			route.Deprecated = route.Deprecated || deprecated
+		}
+		if route.Hidden {
+			continue
+		}
+		if route.Tag != "" && !seen[route.Tag] {
+			seen[route.Tag] = true
+			doc.Tags = append(doc.Tags, tag{Name: route.Tag})
+		}
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = pathItem{}
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method())] = b.operation(route)
+	}
+	return doc
+}
+
+// Handler serves the document of the version the request was routed to,
+// or of the default version, as JSON. It is built on every request so it
+// follows reloads of the settings opts and the lifecycle read.
+func (reg *Registry) Handler(opts func() Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := VersionOf(r)
+		if version == "" {
+			version = apiversion.Default
+		}
+		options := opts()
+		options.Info.Version = version
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(reg.Document(version, options))
+	}
+}
+
+type builder struct {
+	schemas map[string]*Schema
+}
+
+func (b *builder) operation(route Route) *operation {
+	op := &operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Description: route.Description,
+		Deprecated:  route.Deprecated,
+		Responses:   map[string]response{},
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+	if route.Admin {
+		op.Security = []map[string][]string{{adminScheme: {}}}
+	}
+
+	for _, p := range route.params() {
+		op.Parameters = append(op.Parameters, parameter{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required || p.In == "path",
+			Schema:      b.hoist(p.Schema),
+		})
+	}
+
+	if route.Body != nil {
+		op.RequestBody = &requestBody{
+			Required: true,
+			Content:  map[string]mediaType{jsonType: {Schema: b.hoist(route.Body)}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := response{Description: http.StatusText(status)}
+	switch {
+	case route.ContentType != "":
+		schema := route.Response
+		if schema == nil {
+			schema = &Schema{Type: "string", Format: "binary"}
+			if strings.HasPrefix(route.ContentType, "text/") {
+				schema = String()
+			}
+		}
+		success.Content = map[string]mediaType{route.ContentType: {Schema: b.hoist(schema)}}
+	case route.Response != nil:
+		success.Content = map[string]mediaType{jsonType: {Schema: b.hoist(route.Response)}}
+	}
+	for _, alternate := range route.Alternates {
+		if success.Content == nil {
+			success.Content = map[string]mediaType{}
+		}
+		success.Content[alternate] = mediaType{Schema: String()}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	op.Responses["default"] = response{
+		Description: "Error",
+		Content:     map[string]mediaType{jsonType: {Schema: &Schema{Ref: componentRef(errorSchema)}}},
+	}
+	return op
+}
+
+// hoist returns a copy of s in which every named schema is replaced by a
+// reference to its entry in components.
+func (b *builder) hoist(s *Schema) *Schema {
+	if s == nil {
+		return nil
+	}
+
+	c := *s
+	c.name = ""
+	c.Items = b.hoist(s.Items)
+	c.AdditionalProperties = b.hoist(s.AdditionalProperties)
+	if s.AllOf != nil {
+		c.AllOf = make([]*Schema, len(s.AllOf))
+		for i, item := range s.AllOf {
+			c.AllOf[i] = b.hoist(item)
+		}
+	}
+	if s.Properties != nil {
+		c.Properties = make(map[string]*Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			c.Properties[name] = b.hoist(property)
+		}
+	}
+
+	if s.name == "" {
+		return &c
+	}
+	return &Schema{Ref: componentRef(b.register(s.name, &c))}
+}
+
+// register stores s under name, or under name2, name3, ... when a different
+// schema already has that name, and returns the name used.
+func (b *builder) register(name string, s *Schema) string {
+	candidate := name
+	for i := 2; ; i++ {
+		existing, ok := b.schemas[candidate]
+		if !ok {
+			b.schemas[candidate] = s
+			return candidate
+		}
+		if reflect.DeepEqual(existing, s) {
+			return candidate
+		}
+		candidate = name + strconv.Itoa(i)
+	}
+}
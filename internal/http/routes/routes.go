@@ -0,0 +1,261 @@
+// Package routes is the route registry. Each route declares its pattern,
+// handler, parameters and response schema once; the mux, the endpoint list
+// of the status payload and the OpenAPI document are all built from it, so
+// they cannot drift apart.
+//
+// Versioned routes are declared with their unversioned path and served under
+// /stella/{version}/ for every version they belong to; the default version
+// is also served at the unversioned path.
+package routes
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"ss-api/internal/apiversion"
+)
+
+// pathParamPattern matches the wildcards of a ServeMux pattern, including
+// the trailing "..." of a rest wildcard.
+var pathParamPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)(\.\.\.)?\}`)
+
+// Route is one method and path served by the API.
+type Route struct {
+	// Pattern is the ServeMux pattern, e.g. "GET /stella/character/{identifier}".
+	Pattern string
+	Handler http.Handler
+	// OperationID names the operation for client generators, e.g.
+	// "getCharacter". It must be unique.
+	OperationID string
+	Summary     string
+	Description string
+	// Tag groups operations in the document, e.g. "catalog" or "admin".
+	Tag string
+	// Params lists the query parameters and describes path parameters.
+	// Path parameters that are not listed are documented as plain strings.
+	Params []Param
+	// Body is the JSON request body, if the route reads one.
+	Body *Schema
+	// Status is the success status; zero means 200.
+	Status int
+	// Response is the success body. Nil documents a response without a
+	// body, unless ContentType is set.
+	Response *Schema
+	// ContentType is the success media type when it is not JSON, e.g.
+	// "image/*" for assets.
+	ContentType string
+	// Alternates lists other success media types the route negotiates,
+	// e.g. "text/csv" for list routes. They are documented as text.
+	Alternates []string
+	// Admin routes require an admin bearer token. Mount wraps them in the
+	// registry's admin guard; a route under AdminPrefix must set it.
+	Admin bool
+	// Deprecated marks routes kept for existing clients.
+	Deprecated bool
+	// Hidden routes are served but left out of the endpoint list and the
+	// document, e.g. "/stella" next to "/stella/".
+	Hidden bool
+
+	// Since and Until bound the versions that serve the route, e.g. "v1"
+	// to "v1" for a payload v2 replaces. An empty Since leaves the route
+	// unversioned; an empty Until keeps it in every later version.
+	Since string
+	Until string
+	// Aliases are further unversioned paths serving the default version,
+	// e.g. "/news/{category}" next to "/stella/news/{category}".
+	Aliases []string
+}
+
+// Method returns the HTTP method of the pattern.
+func (r Route) Method() string {
+	method, _, _ := strings.Cut(r.Pattern, " ")
+	return method
+}
+
+// Path returns the pattern without the method.
+func (r Route) Path() string {
+	_, path, _ := strings.Cut(r.Pattern, " ")
+	return path
+}
+
+// DocPath returns the path in OpenAPI form: "{path...}" becomes "{path}".
+func (r Route) DocPath() string {
+	return docPath(r.Path())
+}
+
+// Versions lists the versions that serve the route, oldest first.
+func (r Route) Versions() []string {
+	if r.Since == "" {
+		return nil
+	}
+	from, to := apiversion.Index(r.Since), len(apiversion.All)-1
+	if r.Until != "" {
+		to = apiversion.Index(r.Until)
+	}
+	if from < 0 || to < from {
+		return nil
+	}
+	return apiversion.Names()[from : to+1]
+}
+
+// VersionPath inserts the version after /stella: "/stella/characters"
+// becomes "/stella/v1/characters".
+func VersionPath(version, path string) string {
+	return "/stella/" + version + strings.TrimPrefix(path, "/stella")
+}
+
+func docPath(path string) string {
+	return pathParamPattern.ReplaceAllString(path, "{$1}")
+}
+
+// Param is a query or path parameter.
+type Param struct {
+	Name string
+	// In is "query" or "path".
+	In          string
+	Description string
+	Required    bool
+	Schema      *Schema
+}
+
+// Query declares an optional query parameter.
+func Query(name, description string, schema *Schema) Param {
+	return Param{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+// Path declares a path parameter.
+func Path(name, description string) Param {
+	return Param{Name: name, In: "path", Description: description, Required: true, Schema: String()}
+}
+
+// AdminPrefix is the path prefix reserved for admin routes.
+const AdminPrefix = "/stella/admin/"
+
+// Registry holds the routes in registration order.
+type Registry struct {
+	routes     []Route
+	lifecycle  func(version string) apiversion.Lifecycle
+	adminGuard func(http.HandlerFunc) http.HandlerFunc
+}
+
+// New returns an empty registry. lifecycle is read on every versioned
+// request, so a reloaded schedule applies right away.
+func New(lifecycle func(version string) apiversion.Lifecycle) *Registry {
+	return &Registry{lifecycle: lifecycle}
+}
+
+// Add registers routes.
+func (reg *Registry) Add(routes ...Route) {
+	reg.routes = append(reg.routes, routes...)
+}
+
+// GuardAdmin sets the authentication every admin route is wrapped in.
+func (reg *Registry) GuardAdmin(guard func(http.HandlerFunc) http.HandlerFunc) {
+	reg.adminGuard = guard
+}
+
+// Routes returns every registered route.
+func (reg *Registry) Routes() []Route {
+	return slices.Clone(reg.routes)
+}
+
+// mount is one pattern a route is served at.
+type mount struct {
+	route   Route
+	path    string
+	version string
+}
+
+// mounts expands versioned routes into their versioned paths, followed by
+// the unversioned path and aliases when the default version serves them.
+func (reg *Registry) mounts() []mount {
+	var mounts []mount
+	for _, route := range reg.routes {
+		versions := route.Versions()
+		if versions == nil {
+			mounts = append(mounts, mount{route: route, path: route.Path()})
+			continue
+		}
+		for _, version := range versions {
+			mounts = append(mounts, mount{route: route, path: VersionPath(version, route.Path()), version: version})
+		}
+		if slices.Contains(versions, apiversion.Default) {
+			for _, path := range append([]string{route.Path()}, route.Aliases...) {
+				mounts = append(mounts, mount{route: route, path: path, version: apiversion.Default})
+			}
+		}
+	}
+	return mounts
+}
+
+// Mount registers every route with mux. It panics when an admin route
+// would be served without the admin guard, so one cannot ship
+// unauthenticated by mistake.
+func (reg *Registry) Mount(mux *http.ServeMux) {
+	for _, m := range reg.mounts() {
+		handler := m.route.Handler
+		if strings.HasPrefix(m.path, AdminPrefix) && !m.route.Admin {
+			panic("routes: " + m.route.Pattern + " is under " + AdminPrefix + " but not marked Admin")
+		}
+		if m.route.Admin {
+			if reg.adminGuard == nil {
+				panic("routes: admin route " + m.route.Pattern + " mounted without an admin guard")
+			}
+			handler = reg.adminGuard(handler.ServeHTTP)
+		}
+		if m.version != "" {
+			handler = reg.versioned(m.version, handler)
+		}
+		mux.Handle(m.route.Method()+" "+m.path, handler)
+	}
+}
+
+// Endpoints lists the distinct paths served, in registration order.
+func (reg *Registry) Endpoints() []string {
+	var paths []string
+	for _, m := range reg.mounts() {
+		if m.route.Hidden {
+			continue
+		}
+		if path := docPath(m.path); !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+type versionKey struct{}
+
+// VersionOf returns the version a request was routed to; unversioned
+// routes report "".
+func VersionOf(r *http.Request) string {
+	version, _ := r.Context().Value(versionKey{}).(string)
+	return version
+}
+
+// versioned records the version in the request context and announces its
+// retirement schedule.
+func (reg *Registry) versioned(version string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reg.lifecycle != nil {
+			reg.lifecycle(version).SetHeaders(w.Header())
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey{}, version)))
+	})
+}
+
+// params returns the declared parameters plus any path wildcard that was
+// not declared.
+func (r Route) params() []Param {
+	params := slices.Clone(r.Params)
+	for _, match := range pathParamPattern.FindAllStringSubmatch(r.Path(), -1) {
+		name := match[1]
+		if !slices.ContainsFunc(params, func(p Param) bool { return p.In == "path" && p.Name == name }) {
+			params = append(params, Path(name, ""))
+		}
+	}
+	return params
+}
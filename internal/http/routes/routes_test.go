@@ -0,0 +1,113 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"ss-api/internal/apiversion"
+)
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte(VersionOf(r)))
+}
+
+// denyAll stands in for the admin token check.
+func denyAll(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func mustPanic(t *testing.T, mount func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Error("Mount did not panic")
+		}
+	}()
+	mount()
+}
+
+func TestMountRequiresAdminGuard(t *testing.T) {
+	reg := New(nil)
+	reg.Add(Route{Pattern: "GET /stella/admin/jobs", Handler: http.HandlerFunc(ok), Admin: true})
+	mustPanic(t, func() { reg.Mount(http.NewServeMux()) })
+}
+
+func TestMountRequiresAdminMark(t *testing.T) {
+	reg := New(nil)
+	reg.GuardAdmin(denyAll)
+	reg.Add(Route{Pattern: "POST /stella/admin/jobs/{name}", Handler: http.HandlerFunc(ok)})
+	mustPanic(t, func() { reg.Mount(http.NewServeMux()) })
+}
+
+func TestMountGuardsAdminRoutes(t *testing.T) {
+	reg := New(nil)
+	reg.GuardAdmin(denyAll)
+	reg.Add(
+		Route{Pattern: "GET /stella/admin/jobs", Handler: http.HandlerFunc(ok), Admin: true},
+		Route{Pattern: "GET /stella/characters", Handler: http.HandlerFunc(ok), Since: "v1"},
+	)
+	mux := http.NewServeMux()
+	reg.Mount(mux)
+
+	for _, tc := range []struct {
+		path, auth string
+		want       int
+	}{
+		{"/stella/admin/jobs", "", http.StatusUnauthorized},
+		{"/stella/admin/jobs", "Bearer token", http.StatusOK},
+		{"/stella/characters", "", http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.auth != "" {
+			r.Header.Set("Authorization", tc.auth)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, r)
+		if rec.Code != tc.want {
+			t.Errorf("GET %s with %q = %d, want %d", tc.path, tc.auth, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestMountVersionsRoutes(t *testing.T) {
+	sunset := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	reg := New(func(version string) apiversion.Lifecycle {
+		return apiversion.Lifecycle{Sunset: sunset}
+	})
+	reg.Add(
+		Route{Pattern: "GET /stella/news/{category}", Handler: http.HandlerFunc(ok), Since: "v1", Aliases: []string{"/news/{category}"}},
+		Route{Pattern: "GET /stella/healthz", Handler: http.HandlerFunc(ok)},
+	)
+	mux := http.NewServeMux()
+	reg.Mount(mux)
+
+	for path, version := range map[string]string{
+		"/stella/v1/news/updates": "v1",
+		"/stella/news/updates":    apiversion.Default,
+		"/news/updates":           apiversion.Default,
+		"/stella/healthz":         "",
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != version {
+			t.Errorf("GET %s = %d %q, want version %q", path, rec.Code, rec.Body.String(), version)
+		}
+		if hasSunset := rec.Header().Get("Sunset") != ""; hasSunset != (version != "") {
+			t.Errorf("GET %s Sunset = %q", path, rec.Header().Get("Sunset"))
+		}
+	}
+
+	want := []string{"/stella/v1/news/{category}", "/stella/news/{category}", "/news/{category}", "/stella/healthz"}
+	if got := reg.Endpoints(); !slices.Equal(got, want) {
+		t.Errorf("endpoints = %v, want %v", got, want)
+	}
+}
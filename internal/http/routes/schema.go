@@ -0,0 +1,240 @@
+package routes
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Schema is an OpenAPI 3.0 schema object. Build it with SchemaOf for Go
+// types, or with the helpers below for payloads assembled from maps.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Default              any                `json:"default,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+
+	// name is set for named Go types; the document moves them to
+	// components and refers to them by name.
+	name string
+}
+
+// String, Integer, Number, Boolean and DateTime are scalar schemas.
+func String() *Schema   { return &Schema{Type: "string"} }
+func Integer() *Schema  { return &Schema{Type: "integer"} }
+func Number() *Schema   { return &Schema{Type: "number"} }
+func Boolean() *Schema  { return &Schema{Type: "boolean"} }
+func DateTime() *Schema { return &Schema{Type: "string", Format: "date-time"} }
+
+// Any accepts every JSON value.
+func Any() *Schema { return &Schema{} }
+
+// Array is a list of items.
+func Array(items *Schema) *Schema { return &Schema{Type: "array", Items: items} }
+
+// Map is an object with arbitrary keys whose values match values.
+func Map(values *Schema) *Schema { return &Schema{Type: "object", AdditionalProperties: values} }
+
+// Object is an object with the given properties. Properties listed in
+// required are always present.
+func Object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: "object", Properties: properties, Required: required}
+}
+
+// Describe returns a copy of s with a description.
+func (s *Schema) Describe(description string) *Schema {
+	c := *s
+	c.Description = description
+	return &c
+}
+
+// OneOf returns a copy of s restricted to values.
+func (s *Schema) OneOf(values ...any) *Schema {
+	c := *s
+	c.Enum = values
+	return &c
+}
+
+// WithDefault returns a copy of s with a default value.
+func (s *Schema) WithDefault(value any) *Schema {
+	c := *s
+	c.Default = value
+	return &c
+}
+
+// AtLeast returns a copy of s with a minimum.
+func (s *Schema) AtLeast(minimum float64) *Schema {
+	c := *s
+	c.Minimum = &minimum
+	return &c
+}
+
+// Open returns a copy of the object schema s that also allows properties
+// it does not list, for documents whose fields vary by entry.
+func (s *Schema) Open() *Schema {
+	c := *s
+	c.AdditionalProperties = Any()
+	return &c
+}
+
+// Named returns a copy of s that the document lists under components.
+func (s *Schema) Named(name string) *Schema {
+	c := *s
+	c.name = name
+	return &c
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	durationType   = reflect.TypeFor[time.Duration]()
+	numberType     = reflect.TypeFor[json.Number]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	marshalerType  = reflect.TypeFor[json.Marshaler]()
+)
+
+// SchemaOf describes the JSON encoding of v's type, following encoding/json
+// rules for field names, omitempty and embedded structs. Named struct types
+// become components. Types with their own MarshalJSON are described as any
+// value, since their shape cannot be derived.
+func SchemaOf(v any) *Schema {
+	return schemaOf(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+func schemaOf(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	if t == nil {
+		return Any()
+	}
+
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var s *Schema
+	switch {
+	case t == timeType:
+		s = DateTime()
+	case t == durationType:
+		s = Integer().Describe("nanoseconds")
+	case t == numberType:
+		s = Number()
+	case t == rawMessageType:
+		s = Any()
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		s = Any()
+	default:
+		s = kindSchema(t, visiting)
+	}
+
+	if nullable {
+		if s.name != "" {
+			return &Schema{AllOf: []*Schema{s}, Nullable: true}
+		}
+		s.Nullable = true
+	}
+	return s
+}
+
+func kindSchema(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	switch t.Kind() {
+	case reflect.String:
+		return String()
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Integer()
+	case reflect.Float32, reflect.Float64:
+		return Number()
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return Array(schemaOf(t.Elem(), visiting))
+	case reflect.Map:
+		return Map(schemaOf(t.Elem(), visiting))
+	case reflect.Struct:
+		return structSchema(t, visiting)
+	default:
+		return Any()
+	}
+}
+
+func structSchema(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	name := componentName(t)
+	if visiting[t] {
+		// A recursive type refers to itself by name.
+		return &Schema{Ref: componentRef(name)}
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	s := Object(map[string]*Schema{})
+	s.name = name
+	addFields(s, t, visiting)
+	return s
+}
+
+func addFields(s *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			for fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				addFields(s, fieldType, visiting)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		s.Properties[name] = schemaOf(field.Type, visiting)
+		if !strings.Contains(","+options+",", ",omitempty,") && !strings.Contains(","+options+",", ",omitzero,") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// componentName exports a Go type name: groupedBanners becomes
+// GroupedBanners. Anonymous structs stay inline.
+func componentName(t reflect.Type) string {
+	name := t.Name()
+	if name == "" {
+		return ""
+	}
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+func componentRef(name string) string {
+	return "#/components/schemas/" + name
+}
@@ -0,0 +1,32 @@
+// Package security adds the standard security headers to every response.
+package security
+
+import (
+	"net/http"
+	"strconv"
+
+	"ss-api/internal/app"
+)
+
+// Headers sets X-Content-Type-Options, X-Frame-Options, Referrer-Policy and
+// Content-Security-Policy before next runs, so a handler that serves
+// something other than JSON can still replace them. Assets stay embeddable
+// from other sites through Cross-Origin-Resource-Policy: cross-origin.
+func Headers(appInstance *app.App, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := appInstance.Config().Security
+		if cfg.Headers {
+			header := w.Header()
+			header.Set("X-Content-Type-Options", "nosniff")
+			header.Set("X-Frame-Options", "DENY")
+			header.Set("Cross-Origin-Resource-Policy", "cross-origin")
+			header.Set("Referrer-Policy", cfg.ReferrerPolicy)
+			header.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+			if cfg.HSTSMaxAge > 0 {
+				header.Set("Strict-Transport-Security", "max-age="+strconv.Itoa(int(cfg.HSTSMaxAge.Seconds())))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
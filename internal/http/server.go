@@ -1,39 +1,62 @@
 package httpserver
 
 import (
-	"log"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"ss-api/internal/apikeys"
 	"ss-api/internal/app"
+	"ss-api/internal/changelog"
+	"ss-api/internal/http/apierror"
+	"ss-api/internal/http/compress"
+	"ss-api/internal/http/cors"
 	"ss-api/internal/http/handlers"
+	"ss-api/internal/http/ratelimit"
+	"ss-api/internal/http/respcache"
+	"ss-api/internal/http/security"
+	"ss-api/internal/logging"
+	"ss-api/internal/metrics"
 )
 
-const (
-	colorReset   = "\033[0m"
-	colorGreen   = "\033[32m"
-	colorCyan    = "\033[36m"
-	colorYellow  = "\033[33m"
-	colorRed     = "\033[31m"
-	colorBlue    = "\033[34m"
-	colorMagenta = "\033[35m"
+var (
+	httpRequests = metrics.NewCounterVec(
+		"stella_http_requests_total",
+		"HTTP requests by route pattern, method and status code.",
+		"route", "method", "status",
+	)
+	httpDuration = metrics.NewHistogramVec(
+		"stella_http_request_duration_seconds",
+		"HTTP request latency by route pattern, method and status code.",
+		nil,
+		"route", "method", "status",
+	)
 )
 
 type Server struct {
+	app      *app.App
 	mux      *http.ServeMux
+	root     http.Handler
 	handlers handlers.Set
-	logger   *log.Logger
+	logger   *logging.Logger
 	assets   http.Handler
 }
 
-func New(appInstance *app.App) *Server {
+// New builds the server. A nil logger falls back to logging.Default.
+func New(appInstance *app.App, logger *logging.Logger) *Server {
+	if logger == nil {
+		logger = logging.Default()
+	}
+
 	mux := http.NewServeMux()
-	handlerSet := handlers.New(appInstance)
-	logger := log.New(os.Stdout, "", log.LstdFlags)
+	cache := respcache.New(appInstance, appInstance.Config().Cache.MaxEntries)
+	keys := apikeys.New(appInstance)
+	changelog.Register(appInstance)
+	handlerSet := handlers.New(appInstance, cache, keys)
 
 	srv := &Server{
+		app:      appInstance,
 		mux:      mux,
 		handlers: handlerSet,
 		logger:   logger,
@@ -41,64 +64,143 @@ func New(appInstance *app.App) *Server {
 	}
 
 	srv.registerRoutes()
+	// Security headers apply to preflights too; CORS answers OPTIONS before
+	// the limiter so preflights cost no tokens and 429s stay readable from
+	// browsers, and compression only sees responses the mux produced.
+	srv.root = security.Headers(appInstance,
+		cors.Handler(appInstance,
+			ratelimit.Handler(appInstance, keys,
+				compress.Handler(appInstance, srv.dispatch()))))
+
+	metrics.NewGaugeFunc("stella_cache_entries", "Entries held by each in-memory cache.", func() []metrics.Sample {
+		sizes := appInstance.CacheSizes()
+		samples := make([]metrics.Sample, 0, len(sizes))
+		for name, size := range sizes {
+			samples = append(samples, metrics.Sample{Labels: []string{name}, Value: float64(size)})
+		}
+		return samples
+	}, "cache")
+
 	return srv
 }
 
 func (s *Server) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+
+		requestID, ok := logging.SanitizeRequestID(r.Header.Get(logging.RequestIDHeader))
+		if !ok {
+			requestID = logging.NewRequestID()
+		}
+		w.Header().Set(logging.RequestIDHeader, requestID)
+		r = r.WithContext(logging.WithRequestID(r.Context(), requestID))
+
 		rec := &responseRecorder{ResponseWriter: w}
-		s.mux.ServeHTTP(rec, r)
+		s.root.ServeHTTP(rec, r)
 		status := rec.status
 		if status == 0 {
 			status = http.StatusOK
 		}
 		duration := time.Since(start)
-		elapsedMillis := float64(duration) / float64(time.Millisecond)
-		if status == http.StatusNotFound && !strings.HasPrefix(r.URL.Path, "/stella") {
+		route := observeRequest(r, status, duration)
+
+		if !s.logger.ShouldLogAccess(r.URL.Path, status) {
 			return
 		}
-		if strings.HasPrefix(r.URL.Path, "/stella/assets/") || strings.HasPrefix(r.URL.Path, "/assets/") {
+
+		s.logger.Access(r.Context(), logging.AccessEntry{
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      r.URL.RawQuery,
+			Route:      route,
+			Status:     status,
+			Bytes:      rec.bytes,
+			Duration:   duration,
+			UserAgent:  r.Header.Get("User-Agent"),
+			RemoteAddr: r.RemoteAddr,
+			RequestID:  requestID,
+		})
+	})
+}
+
+// registerRoutes mounts the registry and publishes its endpoint list.
+func (s *Server) registerRoutes() {
+	reg := s.registry()
+	reg.Mount(s.mux)
+	s.app.SetEndpoints(reg.Endpoints())
+}
+
+// dispatch serves the mux, answering requests that match no route with the
+// JSON error envelope instead of the mux's plain-text 404 and 405.
+func (s *Server) dispatch() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, pattern := s.mux.Handler(r)
+		if pattern != "" {
+			s.mux.ServeHTTP(w, r)
 			return
 		}
-		ua := r.Header.Get("User-Agent")
-		if ua == "" {
-			ua = "-"
-		}
 
-		path := r.URL.Path
-		if raw := r.URL.RawQuery; raw != "" {
-			path = path + "?" + raw
+		probe := &statusProbe{header: make(http.Header)}
+		handler.ServeHTTP(probe, r)
+		switch probe.status {
+		case http.StatusNotFound:
+			apierror.WriteDetails(w, r, http.StatusNotFound, apierror.CodeRouteNotFound, "no route matches "+r.URL.Path, apierror.Details{"path": r.URL.Path})
+		case http.StatusMethodNotAllowed:
+			var allowed []string
+			if allow := probe.header.Get("Allow"); allow != "" {
+				allowed = strings.Split(allow, ", ")
+			}
+			apierror.MethodNotAllowed(w, r, allowed...)
+		default:
+			s.mux.ServeHTTP(w, r)
 		}
-
-		s.logger.Printf(
-			"%s%s%s %s%d%s %s %.2fms %q",
-			methodColor(r.Method), r.Method, colorReset,
-			statusColor(status), status, colorReset,
-			path,
-			elapsedMillis,
-			ua,
-		)
 	})
 }
 
-func (s *Server) registerRoutes() {
-	s.mux.HandleFunc("GET /stella", s.handlers.Status)
-	s.mux.HandleFunc("GET /stella/", s.handlers.Status)
-	s.mux.HandleFunc("GET /stella/characters", s.handlers.Characters)
-	s.mux.HandleFunc("GET /stella/character/{identifier}", s.handlers.CharacterDetail)
-	s.mux.HandleFunc("GET /stella/discs", s.handlers.Discs)
-	s.mux.HandleFunc("GET /stella/disc/{identifier}", s.handlers.DiscDetail)
-	s.mux.HandleFunc("GET /stella/banners", s.handlers.Banner)
-	s.mux.HandleFunc("GET /stella/events", s.handlers.Events)
-	s.mux.HandleFunc("GET /stella/news/{category}", s.handlers.News)
-	s.mux.HandleFunc("GET /news/{category}", s.handlers.News)
-	s.mux.Handle("GET /stella/assets/{path...}", s.assets)
+// statusProbe captures the status and headers the mux would send for an
+// unmatched request and discards the body.
+type statusProbe struct {
+	header http.Header
+	status int
+}
+
+func (p *statusProbe) Header() http.Header { return p.header }
+
+func (p *statusProbe) WriteHeader(status int) {
+	if p.status == 0 {
+		p.status = status
+	}
+}
+
+func (p *statusProbe) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+// observeRequest records the request under its route pattern rather than the
+// raw path so IDs and names do not explode the label space. It returns the
+// route label.
+func observeRequest(r *http.Request, status int, duration time.Duration) string {
+	route := "unmatched"
+	if pattern := r.Pattern; pattern != "" {
+		if _, path, ok := strings.Cut(pattern, " "); ok {
+			pattern = path
+		}
+		route = pattern
+	}
+
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(route, r.Method, code).Inc()
+	httpDuration.WithLabelValues(route, r.Method, code).Observe(duration.Seconds())
+	return route
 }
 
 type responseRecorder struct {
 	http.ResponseWriter
 	status int
+	bytes  int64
 }
 
 func (r *responseRecorder) WriteHeader(statusCode int) {
@@ -110,35 +212,20 @@ func (r *responseRecorder) Write(b []byte) (int, error) {
 	if r.status == 0 {
 		r.status = http.StatusOK
 	}
-	return r.ResponseWriter.Write(b)
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
 }
 
-func statusColor(status int) string {
-	switch {
-	case status >= 500:
-		return colorRed
-	case status >= 400:
-		return colorYellow
-	case status >= 300:
-		return colorCyan
-	default:
-		return colorGreen
+// Flush lets streamed responses reach the connection through the
+// recorder.
+func (r *responseRecorder) Flush() {
+	if r.status == 0 {
+		r.status = http.StatusOK
 	}
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
 }
 
-func methodColor(method string) string {
-	switch method {
-	case http.MethodGet:
-		return colorBlue
-	case http.MethodPost:
-		return colorMagenta
-	case http.MethodPut:
-		return colorYellow
-	case http.MethodDelete:
-		return colorRed
-	case http.MethodPatch:
-		return colorCyan
-	default:
-		return colorGreen
-	}
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
 }
@@ -0,0 +1,223 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"gopkg.in/yaml.v3"
+
+	"ss-api/internal/app"
+	"ss-api/internal/locale"
+)
+
+// Dump is the entries of one collection and region read from a dump file.
+type Dump struct {
+	Collection string
+	Region     string
+	File       string
+	// Entries are in file order; a nil entry is one that is not an
+	// object.
+	Entries []bson.Raw
+}
+
+// dumpExtensions are the file types ReadDir accepts.
+var dumpExtensions = []string{".json", ".yaml", ".yml"}
+
+// ReadDir reads a dump directory laid out as <region>/<collection>.json,
+// .yaml or .yml, e.g. EN/characters.json or jp/discs.yaml. Region
+// directories take any lang value the API accepts. A file holds either the
+// entries array or an object with an "entries" array.
+func ReadDir(dir string) ([]Dump, error) {
+	regionDirs, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dumps []Dump
+	var errs []error
+	seen := map[string]string{}
+
+	for _, regionDir := range regionDirs {
+		if !regionDir.IsDir() || strings.HasPrefix(regionDir.Name(), ".") {
+			continue
+		}
+		region, ok := locale.Parse(regionDir.Name())
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: not a region; use one of %s", regionDir.Name(), strings.Join(locale.Names(), ", ")))
+			continue
+		}
+
+		files, err := os.ReadDir(filepath.Join(dir, regionDir.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, file := range files {
+			name := file.Name()
+			ext := strings.ToLower(filepath.Ext(name))
+			if file.IsDir() || strings.HasPrefix(name, ".") || !slices.Contains(dumpExtensions, ext) {
+				continue
+			}
+			rel := filepath.Join(regionDir.Name(), name)
+
+			collection := strings.TrimSuffix(name, filepath.Ext(name))
+			if !slices.Contains(app.CatalogCollections, collection) {
+				errs = append(errs, fmt.Errorf("%s: not a catalog collection; use one of %s", rel, strings.Join(app.CatalogCollections, ", ")))
+				continue
+			}
+			key := collection + "/" + string(region)
+			if other, dup := seen[key]; dup {
+				errs = append(errs, fmt.Errorf("%s: %s %s is also in %s", rel, collection, region, other))
+				continue
+			}
+			seen[key] = rel
+
+			entries, err := readDump(filepath.Join(dir, rel), ext, region)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", rel, err))
+				continue
+			}
+			dumps = append(dumps, Dump{Collection: collection, Region: string(region), File: rel, Entries: entries})
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if len(dumps) == 0 {
+		return nil, fmt.Errorf("%s: no dump files found", dir)
+	}
+	slices.SortFunc(dumps, func(a, b Dump) int {
+		if c := strings.Compare(a.Collection, b.Collection); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Region, b.Region)
+	})
+	return dumps, nil
+}
+
+func readDump(path, ext string, region locale.Locale) ([]bson.Raw, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.D
+	if ext == ".json" {
+		doc, err = parseJSON(data)
+	} else {
+		doc, err = parseYAML(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries any
+	for _, elem := range doc {
+		switch elem.Key {
+		case "entries":
+			entries = elem.Value
+		case "region":
+			if s, _ := elem.Value.(string); !strings.EqualFold(s, string(region)) {
+				return nil, fmt.Errorf("region %v does not match the directory (%s)", elem.Value, region)
+			}
+		}
+	}
+	array, ok := entries.(bson.A)
+	if !ok {
+		return nil, errors.New(`expected an array of entries or an object with an "entries" array`)
+	}
+
+	result := make([]bson.Raw, len(array))
+	for i, value := range array {
+		if _, isDoc := value.(bson.D); !isDoc {
+			continue
+		}
+		raw, err := bson.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		result[i] = raw
+	}
+	return result, nil
+}
+
+// parseJSON reads a dump as relaxed extended JSON, so integers stay
+// integers and key order is kept. A bare array becomes {"entries": [...]}.
+func parseJSON(data []byte) (bson.D, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if bytes.HasPrefix(data, []byte("[")) {
+		data = slices.Concat([]byte(`{"entries":`), data, []byte("}"))
+	}
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(data, false, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// parseYAML reads a dump as YAML, keeping key order. Timestamps stay
+// strings, as the API parses them itself.
+func parseYAML(data []byte) (bson.D, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, errors.New("empty file")
+	}
+	value, err := yamlValue(root.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case bson.D:
+		return v, nil
+	case bson.A:
+		return bson.D{{Key: "entries", Value: v}}, nil
+	}
+	return nil, errors.New(`expected an array of entries or an object with an "entries" array`)
+}
+
+func yamlValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.MappingNode:
+		doc := make(bson.D, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := yamlValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			doc = append(doc, bson.E{Key: node.Content[i].Value, Value: value})
+		}
+		return doc, nil
+	case yaml.SequenceNode:
+		array := make(bson.A, 0, len(node.Content))
+		for _, child := range node.Content {
+			value, err := yamlValue(child)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case yaml.ScalarNode:
+		if node.Tag == "!!timestamp" {
+			return node.Value, nil
+		}
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+}
@@ -0,0 +1,434 @@
+// Package importer loads catalog dumps into Mongo. A dump directory holds
+// one file per region and collection; each file replaces the region's
+// documents with a single {region, entries[]} document stamped with a new
+// version and updatedAt, so the catalog watcher and the poll fingerprints
+// notice it. Dumps are validated first, and every import that is not a dry
+// run is recorded in the imports collection. The stella-import command
+// drives it; GET /stella/admin/imports lists the history.
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"ss-api/internal/catalogdiff"
+	"ss-api/internal/validate"
+)
+
+// HistoryCollection records every import that is not a dry run.
+const HistoryCollection = "imports"
+
+// Statuses, as reported in Result.Status.
+const (
+	// StatusApplied: the changed regions were written.
+	StatusApplied = "applied"
+	// StatusRejected: validation found errors and nothing was written.
+	StatusRejected = "rejected"
+	// StatusFailed: writing failed. With transactions nothing was
+	// written; without them some regions may have been.
+	StatusFailed = "failed"
+	// StatusDryRun: nothing was written or recorded.
+	StatusDryRun = "dry_run"
+)
+
+// Scope actions, as reported in ScopeResult.Action.
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+)
+
+// illegalOperation is the server error for a transaction on a standalone
+// deployment.
+const illegalOperation = 20
+
+// ErrConflict is returned when a region was written by someone else
+// between planning an import and writing it.
+var ErrConflict = errors.New("region changed during the import, run it again")
+
+// Options controls an import.
+type Options struct {
+	// Source names the dump in the history, usually its directory.
+	Source string
+	// DryRun validates and diffs without writing anything.
+	DryRun bool
+	// Force writes even when validation found errors.
+	Force bool
+	// Assets enables the asset checks; nil skips them.
+	Assets *validate.Assets
+}
+
+// ScopeResult is the outcome for one collection and region.
+type ScopeResult struct {
+	Collection string `bson:"collection" json:"collection"`
+	Region     string `bson:"region" json:"region"`
+	File       string `bson:"file" json:"file"`
+	Entries    int    `bson:"entries" json:"entries"`
+	// Action is create, update or unchanged. Unchanged regions are not
+	// written and keep their version.
+	Action string `bson:"action" json:"action"`
+	// Version is the version the region got, or would get in a dry run.
+	Version          int64 `bson:"version" json:"version"`
+	catalogdiff.Diff `bson:",inline"`
+}
+
+// Validation summarises the validation of an import.
+type Validation struct {
+	OK       bool           `bson:"ok" json:"ok"`
+	Errors   int            `bson:"errors" json:"errors"`
+	Warnings int            `bson:"warnings" json:"warnings"`
+	Checks   map[string]int `bson:"checks" json:"checks"`
+}
+
+// Result is an import as recorded in the history.
+type Result struct {
+	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	Source     string             `bson:"source" json:"source"`
+	Status     string             `bson:"status" json:"status"`
+	DryRun     bool               `bson:"dryRun" json:"dryRun"`
+	Forced     bool               `bson:"forced,omitempty" json:"forced,omitempty"`
+	StartedAt  time.Time          `bson:"startedAt" json:"startedAt"`
+	FinishedAt time.Time          `bson:"finishedAt" json:"finishedAt"`
+	Scopes     []ScopeResult      `bson:"scopes" json:"scopes"`
+	Validation Validation         `bson:"validation" json:"validation"`
+	// Issues are the validation issues that concern the imported regions.
+	// They are not stored in the history.
+	Issues []validate.Issue `bson:"-" json:"issues,omitempty"`
+	Error  string           `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// Import validates dumps against the stored catalog and writes the regions
+// that changed. The returned error is set when the import could not run or
+// writing failed; a rejected import returns a result and no error.
+func Import(ctx context.Context, db *mongo.Database, dumps []Dump, opts Options) (Result, error) {
+	result := Result{
+		ID:        primitive.NewObjectID(),
+		Source:    opts.Source,
+		DryRun:    opts.DryRun,
+		Forced:    opts.Force,
+		StartedAt: time.Now().UTC(),
+		Scopes:    []ScopeResult{},
+	}
+
+	stored, loadIssues, err := validate.Load(ctx, db)
+	if err != nil {
+		return result, err
+	}
+
+	regions, scopes, err := plan(ctx, db, stored, dumps)
+	if err != nil {
+		return result, err
+	}
+	result.Scopes = scopes
+
+	report := validate.Check(regions, opts.Assets, loadIssues...)
+	result.Issues, result.Validation = relevantIssues(report, dumps)
+
+	switch {
+	case opts.DryRun:
+		result.Status = StatusDryRun
+	case !result.Validation.OK && !opts.Force:
+		result.Status = StatusRejected
+	default:
+		if err = write(ctx, db, dumps, result); err != nil {
+			result.Status = StatusFailed
+			result.Error = err.Error()
+		} else {
+			result.Status = StatusApplied
+		}
+	}
+	result.FinishedAt = time.Now().UTC()
+
+	if !opts.DryRun {
+		if _, recordErr := db.Collection(HistoryCollection).InsertOne(ctx, result); recordErr != nil {
+			err = errors.Join(err, fmt.Errorf("record import: %w", recordErr))
+		}
+	}
+	return result, err
+}
+
+// plan replaces the stored regions the dumps cover and diffs each dump
+// against what is stored.
+func plan(ctx context.Context, db *mongo.Database, stored []validate.Region, dumps []Dump) ([]validate.Region, []ScopeResult, error) {
+	imported := map[string]Dump{}
+	for _, d := range dumps {
+		imported[d.Collection+"/"+d.Region] = d
+	}
+
+	regions := make([]validate.Region, 0, len(stored)+len(dumps))
+	current := map[string]validate.Region{}
+	for _, r := range stored {
+		key := r.Collection + "/" + r.Region
+		current[key] = r
+		if _, replaced := imported[key]; !replaced {
+			regions = append(regions, r)
+		}
+	}
+
+	scopes := make([]ScopeResult, 0, len(dumps))
+	for _, d := range dumps {
+		regions = append(regions, validate.Region{
+			Collection: d.Collection,
+			Region:     d.Region,
+			Documents:  1,
+			Entries:    d.Entries,
+		})
+
+		old, exists := current[d.Collection+"/"+d.Region]
+		scope := ScopeResult{
+			Collection: d.Collection,
+			Region:     d.Region,
+			File:       d.File,
+			Entries:    len(d.Entries),
+			Action:     ActionCreate,
+			Diff:       catalogdiff.Entries(old.Entries, d.Entries),
+		}
+
+		version, err := storedVersion(ctx, db.Collection(d.Collection), d.Region)
+		if err != nil {
+			return nil, nil, err
+		}
+		scope.Version = version + 1
+		if exists {
+			scope.Action = ActionUpdate
+			if unchanged(old, d) {
+				scope.Action = ActionUnchanged
+				scope.Version = version
+			}
+		}
+		scopes = append(scopes, scope)
+	}
+	return regions, scopes, nil
+}
+
+// unchanged reports whether writing d would leave the region as it is:
+// one document with the same entries in the same order.
+func unchanged(old validate.Region, d Dump) bool {
+	if old.Documents != 1 || len(old.Entries) != len(d.Entries) {
+		return false
+	}
+	for i := range d.Entries {
+		if old.Entries[i] == nil || d.Entries[i] == nil || len(catalogdiff.Fields(old.Entries[i], d.Entries[i])) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// storedVersion returns the highest version of a region, 0 when it has
+// none.
+func storedVersion(ctx context.Context, collection *mongo.Collection, region string) (int64, error) {
+	var doc struct {
+		Version bson.RawValue `bson:"version"`
+	}
+	err := collection.FindOne(ctx, bson.D{{Key: "region", Value: region}},
+		options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}).SetProjection(bson.D{{Key: "version", Value: 1}}),
+	).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s %s: %w", collection.Name(), region, err)
+	}
+	if version, ok := doc.Version.AsInt64OK(); ok {
+		return version, nil
+	}
+	return 0, nil
+}
+
+// relevantIssues keeps the issues of the imported collections and regions,
+// plus rate-up and missing-entry issues the import can cause elsewhere in
+// those regions, and missing-entry issues of any region when EN is
+// imported, and summarises them.
+func relevantIssues(report validate.Report, dumps []Dump) ([]validate.Issue, Validation) {
+	scopes := map[string]bool{}
+	regions := map[string]bool{}
+	for _, d := range dumps {
+		scopes[d.Collection+"/"+d.Region] = true
+		regions[d.Region] = true
+	}
+
+	summary := Validation{OK: true, Checks: map[string]int{}}
+	issues := []validate.Issue{}
+	for _, issue := range report.Issues {
+		relevant := scopes[issue.Collection+"/"+issue.Region] ||
+			(issue.Check == validate.CheckUnknownRateUp && regions[issue.Region]) ||
+			(issue.Check == validate.CheckMissingEntry && (regions[issue.Region] || scopes[issue.Collection+"/EN"]))
+		if !relevant {
+			continue
+		}
+		issues = append(issues, issue)
+		summary.Checks[issue.Check]++
+		if issue.Severity == validate.SeverityError {
+			summary.Errors++
+			summary.OK = false
+		} else {
+			summary.Warnings++
+		}
+	}
+	return issues, summary
+}
+
+// write replaces the changed regions in one transaction. Deployments
+// without transactions, such as a standalone server, get the regions
+// written one by one.
+func write(ctx context.Context, db *mongo.Database, dumps []Dump, result Result) error {
+	writeAll := func(ctx context.Context) error {
+		for i, d := range dumps {
+			if err := writeRegion(ctx, db.Collection(d.Collection), d, result.Scopes[i], result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	if err := ensureIndexes(ctx, db, dumps); err != nil {
+		return err
+	}
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		return nil, writeAll(sc)
+	})
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(illegalOperation) {
+		slog.Warn("import: transactions unsupported by this deployment, writing regions one by one")
+		return writeAll(ctx)
+	}
+	return err
+}
+
+// ensureIndexes creates the unique (region, version) index on every
+// collection the dumps write, so two imports can never store the same
+// version of a region. Documents not written by the importer are left out
+// of it.
+func ensureIndexes(ctx context.Context, db *mongo.Database, dumps []Dump) error {
+	seen := map[string]bool{}
+	for _, d := range dumps {
+		if seen[d.Collection] {
+			continue
+		}
+		seen[d.Collection] = true
+		_, err := db.Collection(d.Collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: "region", Value: 1}, {Key: "version", Value: 1}},
+			Options: options.Index().
+				SetName("region_version").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.D{{Key: "importId", Value: bson.D{{Key: "$exists", Value: true}}}}),
+		})
+		if err != nil {
+			return fmt.Errorf("%s: create version index: %w", d.Collection, err)
+		}
+	}
+	return nil
+}
+
+// writeRegion replaces the documents of one region with a single document.
+// The stored document with the highest version keeps its _id. The region
+// must still be at the version plan saw: the replace only matches that
+// version and the unique index rejects a second insert, so a concurrent
+// import fails with ErrConflict instead of being overwritten.
+func writeRegion(ctx context.Context, collection *mongo.Collection, d Dump, scope ScopeResult, result Result) error {
+	if scope.Action == ActionUnchanged {
+		return nil
+	}
+	fail := func(err error) error {
+		return fmt.Errorf("%s %s: %w", collection.Name(), d.Region, err)
+	}
+
+	entries := make(bson.A, len(d.Entries))
+	for i, entry := range d.Entries {
+		entries[i] = entry
+	}
+	doc := bson.D{
+		{Key: "region", Value: d.Region},
+		{Key: "entries", Value: entries},
+		{Key: "version", Value: scope.Version},
+		{Key: "updatedAt", Value: result.StartedAt},
+		{Key: "importId", Value: result.ID},
+	}
+
+	var kept struct {
+		ID      any           `bson:"_id"`
+		Version bson.RawValue `bson:"version"`
+	}
+	err := collection.FindOne(ctx, bson.D{{Key: "region", Value: d.Region}},
+		options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}).SetProjection(bson.D{{Key: "_id", Value: 1}, {Key: "version", Value: 1}}),
+	).Decode(&kept)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		if scope.Version != 1 {
+			return fail(ErrConflict)
+		}
+		inserted, err := collection.InsertOne(ctx, doc)
+		if mongo.IsDuplicateKeyError(err) {
+			return fail(ErrConflict)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		kept.ID = inserted.InsertedID
+	case err != nil:
+		return fail(err)
+	default:
+		version, _ := kept.Version.AsInt64OK()
+		if version != scope.Version-1 {
+			return fail(ErrConflict)
+		}
+		match := bson.D{{Key: "$exists", Value: false}}
+		if kept.Version.Type != 0 {
+			match = bson.D{{Key: "$eq", Value: kept.Version}}
+		}
+		replaced, err := collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: kept.ID}, {Key: "version", Value: match}}, doc)
+		if mongo.IsDuplicateKeyError(err) {
+			return fail(ErrConflict)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if replaced.MatchedCount == 0 {
+			return fail(ErrConflict)
+		}
+	}
+
+	_, err = collection.DeleteMany(ctx, bson.D{
+		{Key: "region", Value: d.Region},
+		{Key: "_id", Value: bson.D{{Key: "$ne", Value: kept.ID}}},
+	})
+	if err != nil {
+		return fail(err)
+	}
+	return nil
+}
+
+// History returns the latest imports, newest first.
+func History(ctx context.Context, db *mongo.Database, limit int) ([]Result, error) {
+	cursor, err := db.Collection(HistoryCollection).Find(ctx, bson.D{},
+		options.Find().SetSort(bson.D{{Key: "startedAt", Value: -1}}).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	results := []Result{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		if results[i].Scopes == nil {
+			results[i].Scopes = []ScopeResult{}
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,39 @@
+package importer
+
+import (
+	"testing"
+
+	"ss-api/internal/validate"
+)
+
+func TestRelevantIssuesMissingEntry(t *testing.T) {
+	report := validate.Report{Issues: []validate.Issue{
+		{Check: validate.CheckMissingEntry, Severity: validate.SeverityWarning, Collection: "characters", Region: "JP", ID: 1},
+		{Check: validate.CheckMissingEntry, Severity: validate.SeverityWarning, Collection: "characters", Region: "KR", ID: 2},
+	}}
+
+	for name, tc := range map[string]struct {
+		dumps []Dump
+		want  []int64
+	}{
+		"imported region":         {dumps: []Dump{{Collection: "discs", Region: "JP"}}, want: []int64{1}},
+		"imported collection":     {dumps: []Dump{{Collection: "characters", Region: "KR"}}, want: []int64{2}},
+		"EN affects every region": {dumps: []Dump{{Collection: "characters", Region: "EN"}}, want: []int64{1, 2}},
+		"unrelated region":        {dumps: []Dump{{Collection: "characters", Region: "CN"}}, want: nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			issues, summary := relevantIssues(report, tc.dumps)
+			if len(issues) != len(tc.want) {
+				t.Fatalf("issues = %+v, want ids %v", issues, tc.want)
+			}
+			for i, issue := range issues {
+				if issue.ID != tc.want[i] {
+					t.Errorf("issue %d id = %d, want %d", i, issue.ID, tc.want[i])
+				}
+			}
+			if summary.Warnings != len(tc.want) || !summary.OK {
+				t.Errorf("summary = %+v, want %d warnings and ok", summary, len(tc.want))
+			}
+		})
+	}
+}
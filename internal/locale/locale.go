@@ -0,0 +1,247 @@
+// Package locale maps the lang values and Accept-Language headers clients
+// send to the catalog regions stored in Mongo (EN, JP, KR, CN, TW), and
+// walks the fallback chain used when a region has no data.
+package locale
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Locale is a catalog region as stored in the Mongo "region" field.
+type Locale string
+
+const (
+	EN Locale = "EN"
+	JP Locale = "JP"
+	KR Locale = "KR"
+	CN Locale = "CN"
+	TW Locale = "TW"
+)
+
+// All lists the supported locales.
+var All = []Locale{EN, JP, KR, CN, TW}
+
+// aliases maps lower-case lang values and language tags to locales. Tags
+// not listed are retried without their last subtag, so "en-AU" matches
+// "en" and "zh-Hant-HK" matches "zh-hant".
+var aliases = map[string]Locale{
+	"en":     EN,
+	"us":     EN,
+	"global": EN,
+
+	"jp": JP,
+	"ja": JP,
+
+	"kr": KR,
+	"ko": KR,
+
+	"cn":      CN,
+	"zh":      CN,
+	"zh-cn":   CN,
+	"zh-sg":   CN,
+	"zh-hans": CN,
+
+	"tw":      TW,
+	"zh-tw":   TW,
+	"zh-hk":   TW,
+	"zh-mo":   TW,
+	"zh-hant": TW,
+}
+
+var tags = map[Locale]string{
+	EN: "en",
+	JP: "ja",
+	KR: "ko",
+	CN: "zh-CN",
+	TW: "zh-TW",
+}
+
+// Names returns the supported locales as strings, for error details and
+// validation messages.
+func Names() []string {
+	names := make([]string, len(All))
+	for i, l := range All {
+		names[i] = string(l)
+	}
+	return names
+}
+
+// Parse maps a lang value such as "JP", "ja", "zh_TW" or "en-US" to a locale.
+func Parse(value string) (Locale, bool) {
+	tag := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(value), "_", "-"))
+	for tag != "" {
+		if l, ok := aliases[tag]; ok {
+			return l, true
+		}
+		i := strings.LastIndexByte(tag, '-')
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return "", false
+}
+
+// Tag is the language tag sent in Content-Language.
+func (l Locale) Tag() string {
+	return tags[l]
+}
+
+// Dir is the lower-case name used for regional asset directories.
+func (l Locale) Dir() string {
+	return strings.ToLower(string(l))
+}
+
+// Negotiate picks the supported locale the client ranks highest in an
+// Accept-Language header. Ranges with q=0 and the "*" range are skipped.
+func Negotiate(header string) (Locale, bool) {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "q") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				parsed = 0
+			}
+			q = parsed
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag: tag, q: q})
+		}
+	}
+
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	for _, c := range candidates {
+		if l, ok := Parse(c.tag); ok {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// Source records how a request's locale was chosen.
+type Source string
+
+const (
+	FromQuery          Source = "query"
+	FromAcceptLanguage Source = "accept-language"
+	FromDefault        Source = "default"
+)
+
+// Selection is the locale chosen for a request and the locales to try, in
+// order, when it has no data.
+type Selection struct {
+	Locale Locale
+	Source Source
+	// Chain starts with Locale and continues with its fallbacks.
+	Chain []Locale
+}
+
+// Through returns the part of the chain up to and including served: the
+// regions whose data decided the response.
+func (s Selection) Through(served Locale) []Locale {
+	if i := slices.Index(s.Chain, served); i >= 0 {
+		return s.Chain[:i+1]
+	}
+	return s.Chain
+}
+
+// Negotiator chooses the locale of a request.
+type Negotiator struct {
+	// Default is used when the request names no supported locale.
+	Default Locale
+	// Fallbacks maps a locale to the one tried next, e.g. TW to CN.
+	Fallbacks map[Locale]Locale
+	// AcceptLanguage consults the Accept-Language header when lang is
+	// absent.
+	AcceptLanguage bool
+}
+
+// Resolve reads ?lang=, then Accept-Language, then falls back to the
+// default. It reports false when lang is present but unsupported; an
+// unusable Accept-Language header is not an error.
+func (n Negotiator) Resolve(r *http.Request) (Selection, bool) {
+	query := r.URL.Query()
+	if query.Has("lang") {
+		if value := strings.TrimSpace(query.Get("lang")); value != "" {
+			l, ok := Parse(value)
+			if !ok {
+				return Selection{}, false
+			}
+			return n.selection(l, FromQuery), true
+		}
+	}
+
+	if n.AcceptLanguage {
+		if l, ok := Negotiate(strings.Join(r.Header.Values("Accept-Language"), ",")); ok {
+			return n.selection(l, FromAcceptLanguage), true
+		}
+	}
+
+	return n.selection(n.defaultLocale(), FromDefault), true
+}
+
+// Chain returns l followed by its fallbacks. A cycle in the configured
+// fallbacks ends the chain instead of looping.
+func (n Negotiator) Chain(l Locale) []Locale {
+	chain := []Locale{l}
+	for {
+		next, ok := n.Fallbacks[chain[len(chain)-1]]
+		if !ok || slices.Contains(chain, next) {
+			return chain
+		}
+		chain = append(chain, next)
+	}
+}
+
+func (n Negotiator) selection(l Locale, source Source) Selection {
+	return Selection{Locale: l, Source: source, Chain: n.Chain(l)}
+}
+
+func (n Negotiator) defaultLocale() Locale {
+	if n.Default == "" {
+		return EN
+	}
+	return n.Default
+}
+
+// SetHeaders announces the locale a response was rendered in. Vary is set
+// even when lang was given so shared caches never mix negotiated variants.
+func SetHeaders(header http.Header, served Locale) {
+	header.Set("Content-Language", served.Tag())
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name == "*" || strings.EqualFold(name, "Accept-Language") {
+				return
+			}
+		}
+	}
+	header.Add("Vary", "Accept-Language")
+}
@@ -0,0 +1,105 @@
+package locale
+
+import (
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for value, want := range map[string]Locale{
+		"EN":         EN,
+		" jp ":       JP,
+		"ja":         JP,
+		"ko-KR":      KR,
+		"zh":         CN,
+		"zh-Hans-CN": CN,
+		"zh_TW":      TW,
+		"zh-Hant-HK": TW,
+		"en-GB":      EN,
+		"global":     EN,
+	} {
+		if got, ok := Parse(value); !ok || got != want {
+			t.Errorf("Parse(%q) = %q, %v; want %q", value, got, ok, want)
+		}
+	}
+	for _, value := range []string{"", "fr", "english", "-"} {
+		if got, ok := Parse(value); ok {
+			t.Errorf("Parse(%q) = %q, want unsupported", value, got)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	for header, want := range map[string]Locale{
+		"ja":                        JP,
+		"fr-FR, ko;q=0.8, en;q=0.5": KR,
+		"en;q=0.4, zh-TW;q=0.9":     TW,
+		"*, ja;q=0.1":               JP,
+		"ja;q=0, en":                EN,
+		"ko;q=0.5, ja;q=0.5":        KR,
+		"ja;q=abc, en;q=0.2":        EN,
+		"de, zh-Hant;q=0.7, fr;q=1": TW,
+	} {
+		if got, ok := Negotiate(header); !ok || got != want {
+			t.Errorf("Negotiate(%q) = %q, %v; want %q", header, got, ok, want)
+		}
+	}
+	for _, header := range []string{"", "*", "fr, de", "ja;q=0"} {
+		if got, ok := Negotiate(header); ok {
+			t.Errorf("Negotiate(%q) = %q, want none", header, got)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	n := Negotiator{Fallbacks: map[Locale]Locale{TW: CN, CN: EN, JP: KR, KR: JP}}
+	for l, want := range map[Locale][]Locale{
+		TW: {TW, CN, EN},
+		EN: {EN},
+		JP: {JP, KR},
+	} {
+		if got := n.Chain(l); !slices.Equal(got, want) {
+			t.Errorf("Chain(%s) = %v, want %v", l, got, want)
+		}
+	}
+
+	sel := Selection{Locale: TW, Chain: n.Chain(TW)}
+	if got := sel.Through(CN); !slices.Equal(got, []Locale{TW, CN}) {
+		t.Errorf("Through(CN) = %v, want [TW CN]", got)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	n := Negotiator{Default: JP, AcceptLanguage: true, Fallbacks: map[Locale]Locale{TW: CN}}
+
+	for name, tc := range map[string]struct {
+		target, accept string
+		negotiator     Negotiator
+		want           Locale
+		source         Source
+		ok             bool
+	}{
+		"lang":                     {target: "/?lang=zh-TW", accept: "ko", negotiator: n, want: TW, source: FromQuery, ok: true},
+		"unsupported lang":         {target: "/?lang=fr", negotiator: n, ok: false},
+		"empty lang":               {target: "/?lang=", accept: "ko", negotiator: n, want: KR, source: FromAcceptLanguage, ok: true},
+		"accept-language":          {target: "/", accept: "ko", negotiator: n, want: KR, source: FromAcceptLanguage, ok: true},
+		"unusable accept-language": {target: "/", accept: "fr", negotiator: n, want: JP, source: FromDefault, ok: true},
+		"accept-language off":      {target: "/", accept: "ko", negotiator: Negotiator{Default: JP}, want: JP, source: FromDefault, ok: true},
+		"no default":               {target: "/", negotiator: Negotiator{}, want: EN, source: FromDefault, ok: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.target, nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept-Language", tc.accept)
+			}
+			sel, ok := tc.negotiator.Resolve(r)
+			if ok != tc.ok {
+				t.Fatalf("ok = %v, want %v", ok, tc.ok)
+			}
+			if ok && (sel.Locale != tc.want || sel.Source != tc.source || sel.Chain[0] != tc.want) {
+				t.Errorf("selection = %+v, want %s from %s", sel, tc.want, tc.source)
+			}
+		})
+	}
+}
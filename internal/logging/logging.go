@@ -0,0 +1,304 @@
+// Package logging builds the process logger from config.LogConfig. Handler
+// and background logs go through log/slog; access logs are either colored
+// text lines or slog JSON records, depending on the configured format.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"ss-api/internal/config"
+)
+
+const (
+	colorReset   = "\033[0m"
+	colorGreen   = "\033[32m"
+	colorCyan    = "\033[36m"
+	colorYellow  = "\033[33m"
+	colorRed     = "\033[31m"
+	colorBlue    = "\033[34m"
+	colorMagenta = "\033[35m"
+)
+
+// Logger is the configured slog logger plus the access log writer that
+// shares its destination.
+type Logger struct {
+	*slog.Logger
+
+	level  *slog.LevelVar
+	json   bool
+	color  bool
+	access *log.Logger
+	closer io.Closer
+
+	mu     sync.RWMutex
+	filter config.AccessLogConfig
+}
+
+// New builds a logger from cfg. The caller owns the returned logger and
+// should Close it on shutdown to flush file outputs.
+func New(cfg config.LogConfig) (*Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	out, closer, err := openOutput(cfg.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(level)
+
+	opts := &slog.HandlerOptions{Level: levelVar}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(out, opts)
+	case "json":
+		handler = slog.NewJSONHandler(out, opts)
+	default:
+		if closer != nil {
+			_ = closer.Close()
+		}
+		return nil, fmt.Errorf("log format must be text or json, got %q", cfg.Format)
+	}
+
+	color, err := colorEnabled(cfg.Color, out)
+	if err != nil {
+		if closer != nil {
+			_ = closer.Close()
+		}
+		return nil, err
+	}
+
+	return &Logger{
+		Logger: slog.New(contextHandler{handler}),
+		level:  levelVar,
+		json:   strings.EqualFold(cfg.Format, "json"),
+		color:  color,
+		access: log.New(out, "", log.LstdFlags),
+		closer: closer,
+		filter: cfg.Access,
+	}, nil
+}
+
+// Default returns a text logger on stdout with the default settings. It is
+// used when a component is constructed without an explicit logger.
+func Default() *Logger {
+	logger, err := New(config.Default().Log)
+	if err != nil {
+		panic(err)
+	}
+	return logger
+}
+
+// Install makes l the slog default. Output of the standard log package is
+// routed through it as well.
+func (l *Logger) Install() {
+	slog.SetDefault(l.Logger)
+}
+
+// SetLevel changes the minimum level at runtime.
+func (l *Logger) SetLevel(value string) error {
+	level, err := ParseLevel(value)
+	if err != nil {
+		return err
+	}
+	l.level.Set(level)
+	return nil
+}
+
+// SetAccessFilter replaces the access log skip rules at runtime.
+func (l *Logger) SetAccessFilter(filter config.AccessLogConfig) {
+	l.mu.Lock()
+	l.filter = filter
+	l.mu.Unlock()
+}
+
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// ParseLevel accepts debug, info, warn/warning and error.
+func ParseLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("log level must be debug, info, warn or error, got %q", value)
+	}
+}
+
+func openOutput(value string) (io.Writer, io.Closer, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "stdout":
+		return os.Stdout, nil, nil
+	case "stderr":
+		return os.Stderr, nil, nil
+	}
+
+	f, err := os.OpenFile(value, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open log output: %w", err)
+	}
+	return f, f, nil
+}
+
+func colorEnabled(mode string, out io.Writer) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "", "auto":
+		if _, noColor := os.LookupEnv("NO_COLOR"); noColor {
+			return false, nil
+		}
+		f, ok := out.(*os.File)
+		if !ok {
+			return false, nil
+		}
+		info, err := f.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return info.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("log color must be auto, always or never, got %q", mode)
+	}
+}
+
+// AccessEntry describes one served request.
+type AccessEntry struct {
+	Method     string
+	Path       string
+	Query      string
+	Route      string
+	Status     int
+	Bytes      int64
+	Duration   time.Duration
+	UserAgent  string
+	RemoteAddr string
+	RequestID  string
+}
+
+// ShouldLogAccess applies the configured skip rules.
+func (l *Logger) ShouldLogAccess(path string, status int) bool {
+	l.mu.RLock()
+	filter := l.filter
+	l.mu.RUnlock()
+
+	for _, prefix := range filter.SkipPrefixes {
+		if prefix != "" && strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+
+	if status == 404 && len(filter.NotFoundPrefixes) > 0 {
+		for _, prefix := range filter.NotFoundPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// Access writes an access log entry in the configured format.
+func (l *Logger) Access(ctx context.Context, entry AccessEntry) {
+	if l.json {
+		l.LogAttrs(ctx, slog.LevelInfo, "request",
+			slog.String("method", entry.Method),
+			slog.String("path", entry.Path),
+			slog.String("query", entry.Query),
+			slog.String("route", entry.Route),
+			slog.Int("status", entry.Status),
+			slog.Int64("bytes", entry.Bytes),
+			slog.Float64("duration_ms", float64(entry.Duration)/float64(time.Millisecond)),
+			slog.String("user_agent", entry.UserAgent),
+			slog.String("remote_addr", entry.RemoteAddr),
+		)
+		return
+	}
+
+	if !l.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+
+	path := entry.Path
+	if entry.Query != "" {
+		path = path + "?" + entry.Query
+	}
+
+	ua := entry.UserAgent
+	if ua == "" {
+		ua = "-"
+	}
+
+	elapsedMillis := float64(entry.Duration) / float64(time.Millisecond)
+
+	if !l.color {
+		l.access.Printf("%s %d %s %.2fms %q", entry.Method, entry.Status, path, elapsedMillis, ua)
+		return
+	}
+
+	l.access.Printf(
+		"%s%s%s %s%d%s %s %.2fms %q",
+		methodColor(entry.Method), entry.Method, colorReset,
+		statusColor(entry.Status), entry.Status, colorReset,
+		path,
+		elapsedMillis,
+		ua,
+	)
+}
+
+func statusColor(status int) string {
+	switch {
+	case status >= 500:
+		return colorRed
+	case status >= 400:
+		return colorYellow
+	case status >= 300:
+		return colorCyan
+	default:
+		return colorGreen
+	}
+}
+
+func methodColor(method string) string {
+	switch method {
+	case "GET":
+		return colorBlue
+	case "POST":
+		return colorMagenta
+	case "PUT":
+		return colorYellow
+	case "DELETE":
+		return colorRed
+	case "PATCH":
+		return colorCyan
+	default:
+		return colorGreen
+	}
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+)
+
+var (
+	processStart = time.Now()
+
+	// CacheLookups counts in-memory cache lookups by cache name and result
+	// ("hit", "miss", or "stale" for expired payloads served while they are
+	// refreshed).
+	CacheLookups = NewCounterVec(
+		"stella_cache_lookups_total",
+		"In-memory cache lookups by cache and result.",
+		"cache", "result",
+	)
+)
+
+func init() {
+	NewGaugeFunc("process_start_time_seconds", "Start time of the process since the Unix epoch in seconds.", func() []Sample {
+		return []Sample{{Value: float64(processStart.UnixNano()) / 1e9}}
+	})
+	NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() []Sample {
+		return []Sample{{Value: float64(runtime.NumGoroutine())}}
+	})
+	NewGaugeFunc("go_memstats_heap_alloc_bytes", "Number of heap bytes allocated and still in use.", func() []Sample {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		return []Sample{{Value: float64(stats.HeapAlloc)}}
+	})
+}
+
+// CacheHit records a lookup against the named cache.
+func CacheHit(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	CacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// Handler serves the default registry in the Prometheus text format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write([]byte(Default.Render()))
+	})
+}
@@ -0,0 +1,351 @@
+// Package metrics implements the small subset of Prometheus instrumentation
+// the API needs: labelled counters, histograms and gauges computed at scrape
+// time, rendered in the text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets suit request and query latencies measured in seconds.
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds every collector exposed on /metrics.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+type collector interface {
+	name() string
+	write(b *strings.Builder)
+}
+
+// Default is the registry the package-level constructors register into.
+var Default = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// register adds c, replacing an earlier collector with the same name. This
+// lets scrape-time gauges be re-bound when the app they observe is rebuilt.
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors[c.name()] = c
+}
+
+// Render writes every collector in the Prometheus text format, sorted by
+// metric name.
+func (r *Registry) Render() string {
+	r.mu.RLock()
+	collectors := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].name() < collectors[j].name()
+	})
+
+	var b strings.Builder
+	for _, c := range collectors {
+		c.write(&b)
+	}
+	return b.String()
+}
+
+type desc struct {
+	metric string
+	help   string
+	labels []string
+}
+
+func (d desc) name() string { return d.metric }
+
+func (d desc) header(b *strings.Builder, kind string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", d.metric, escapeHelp(d.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", d.metric, kind)
+}
+
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.metric, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// CounterVec is a family of monotonically increasing counters.
+type CounterVec struct {
+	desc
+	mu     sync.RWMutex
+	values map[string]*Counter
+}
+
+// Counter is a single counter series.
+type Counter struct {
+	labels []string
+	bits   atomic.Uint64
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{metric: name, help: help, labels: labels},
+		values: make(map[string]*Counter),
+	}
+	Default.register(c)
+	return c
+}
+
+// WithLabelValues returns the series for the given label values, creating it
+// on first use.
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	key := c.key(values)
+
+	c.mu.RLock()
+	counter, ok := c.values[key]
+	c.mu.RUnlock()
+	if ok {
+		return counter
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if counter, ok = c.values[key]; ok {
+		return counter
+	}
+	counter = &Counter{labels: append([]string(nil), values...)}
+	c.values[key] = counter
+	return counter
+}
+
+func (c *Counter) Inc() { c.Add(1) }
+
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+	for {
+		old := c.bits.Load()
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if c.bits.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+func (c *CounterVec) write(b *strings.Builder) {
+	c.header(b, "counter")
+
+	c.mu.RLock()
+	series := make([]*Counter, 0, len(c.values))
+	for _, counter := range c.values {
+		series = append(series, counter)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(series, func(i, j int) bool {
+		return strings.Join(series[i].labels, "\xff") < strings.Join(series[j].labels, "\xff")
+	})
+
+	for _, counter := range series {
+		writeSample(b, c.metric, c.labels, counter.labels, "", "", counter.Value())
+	}
+}
+
+// HistogramVec is a family of histograms sharing bucket boundaries.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.RWMutex
+	values  map[string]*Histogram
+}
+
+// Histogram is a single histogram series.
+type Histogram struct {
+	labels  []string
+	buckets []float64
+	mu      sync.Mutex
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	h := &HistogramVec{
+		desc:    desc{metric: name, help: help, labels: labels},
+		buckets: sorted,
+		values:  make(map[string]*Histogram),
+	}
+	Default.register(h)
+	return h
+}
+
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	key := h.key(values)
+
+	h.mu.RLock()
+	hist, ok := h.values[key]
+	h.mu.RUnlock()
+	if ok {
+		return hist
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if hist, ok = h.values[key]; ok {
+		return hist
+	}
+	hist = &Histogram{
+		labels:  append([]string(nil), values...),
+		buckets: h.buckets,
+		counts:  make([]uint64, len(h.buckets)),
+	}
+	h.values[key] = hist
+	return hist
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func (h *HistogramVec) write(b *strings.Builder) {
+	h.header(b, "histogram")
+
+	h.mu.RLock()
+	series := make([]*Histogram, 0, len(h.values))
+	for _, hist := range h.values {
+		series = append(series, hist)
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(series, func(i, j int) bool {
+		return strings.Join(series[i].labels, "\xff") < strings.Join(series[j].labels, "\xff")
+	})
+
+	for _, hist := range series {
+		hist.mu.Lock()
+		counts := append([]uint64(nil), hist.counts...)
+		sum, count := hist.sum, hist.count
+		hist.mu.Unlock()
+
+		for i, bound := range hist.buckets {
+			writeSample(b, h.metric+"_bucket", h.labels, hist.labels, "le", formatFloat(bound), float64(counts[i]))
+		}
+		writeSample(b, h.metric+"_bucket", h.labels, hist.labels, "le", "+Inf", float64(count))
+		writeSample(b, h.metric+"_sum", h.labels, hist.labels, "", "", sum)
+		writeSample(b, h.metric+"_count", h.labels, hist.labels, "", "", float64(count))
+	}
+}
+
+// Sample is one labelled value reported by a GaugeFunc.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// GaugeFunc computes its samples at scrape time.
+type GaugeFunc struct {
+	desc
+	collect func() []Sample
+}
+
+func NewGaugeFunc(name, help string, collect func() []Sample, labels ...string) *GaugeFunc {
+	g := &GaugeFunc{
+		desc:    desc{metric: name, help: help, labels: labels},
+		collect: collect,
+	}
+	Default.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(b *strings.Builder) {
+	g.header(b, "gauge")
+
+	samples := g.collect()
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].Labels, "\xff") < strings.Join(samples[j].Labels, "\xff")
+	})
+
+	for _, sample := range samples {
+		if len(sample.Labels) != len(g.labels) {
+			continue
+		}
+		writeSample(b, g.metric, g.labels, sample.Labels, "", "", sample.Value)
+	}
+}
+
+func writeSample(b *strings.Builder, metric string, names, values []string, extraName, extraValue string, value float64) {
+	b.WriteString(metric)
+
+	if len(names) > 0 || extraName != "" {
+		b.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", name, escapeLabel(values[i]))
+		}
+		if extraName != "" {
+			if len(names) > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "%s=\"%s\"", extraName, extraValue)
+		}
+		b.WriteByte('}')
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(value string) string { return labelEscaper.Replace(value) }
+
+func escapeHelp(value string) string { return helpEscaper.Replace(value) }
@@ -0,0 +1,258 @@
+package model
+
+import (
+	"encoding/json"
+
+	"ss-api/internal/alias"
+)
+
+// characterOrder is the key order of character payloads. Keys it does not
+// name follow in stored order; nested objects use the same order.
+var characterOrder = []string{
+	"id",
+	"name",
+	"icon",
+	"portrait",
+	"background",
+	"variants",
+	"description",
+	"voiceActor",
+	"birthday",
+	"grade",
+	"element",
+	"position",
+	"attackType",
+	"style",
+	"faction",
+	"tags",
+	"dateEvents",
+	"giftPreferences",
+	"normalAttack",
+	"skill",
+	"supportSkill",
+	"ultimate",
+	"potentials",
+	"talents",
+	"stats",
+	"upgrades",
+	"skillUpgrades",
+}
+
+// summaryOmit lists the character fields left out of the list payload.
+var summaryOmit = map[string]bool{
+	"stats":           true,
+	"normalAttack":    true,
+	"skill":           true,
+	"supportSkill":    true,
+	"ultimate":        true,
+	"potentials":      true,
+	"talents":         true,
+	"upgrades":        true,
+	"skillUpgrades":   true,
+	"dateEvents":      true,
+	"giftPreferences": true,
+	"textures":        true,
+}
+
+// Character is a character entry of one region.
+type Character struct {
+	Fields `json:"-"`
+
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// VoiceActors maps a voice language to the actor's name.
+	VoiceActors     map[string]string `json:"voiceActors"`
+	Grade           int               `json:"grade"`
+	Element         string            `json:"element"`
+	Position        string            `json:"position"`
+	AttackType      string            `json:"attackType"`
+	Style           string            `json:"style"`
+	Faction         string            `json:"faction"`
+	Tags            []string          `json:"tags"`
+	DateEvents      []DateEvent       `json:"dateEvents"`
+	GiftPreferences GiftPreferences   `json:"giftPreferences"`
+	NormalAttack    Skill             `json:"normalAttack"`
+	Skill           Skill             `json:"skill"`
+	SupportSkill    Skill             `json:"supportSkill"`
+	Ultimate        Skill             `json:"ultimate"`
+	Potentials      Potentials        `json:"potentials"`
+	Talents         Talents           `json:"talents"`
+	Stats           Stats             `json:"stats"`
+	Upgrades        Upgrades          `json:"upgrades"`
+	SkillUpgrades   SkillUpgrades     `json:"skillUpgrades"`
+	Textures        Textures          `json:"textures"`
+}
+
+// UnmarshalBSON decodes a stored entry.
+func (c *Character) UnmarshalBSON(data []byte) error { return unmarshalBSON(c, data) }
+
+// UnmarshalJSON decodes an entry in its stored layout.
+func (c *Character) UnmarshalJSON(data []byte) error { return unmarshalJSON(c, data) }
+
+// MarshalJSON writes the detail payload: voiceActors becomes voiceActor and
+// the friendly textures become root-level icon, portrait, background and
+// variants asset paths.
+func (c Character) MarshalJSON() ([]byte, error) {
+	fields := characterFields(recordFieldsOf(c), nil)
+	if c.Has("textures") {
+		fields = append(fields, c.Textures.friendlyFields()...)
+	}
+	return json.Marshal(arrangeFields(fields, characterOrder))
+}
+
+// CharacterSummary is the list payload of a character: the entry without
+// its heavy sections, with icon and portrait paths derived from the ID.
+type CharacterSummary Character
+
+// MarshalJSON writes the list payload.
+func (s CharacterSummary) MarshalJSON() ([]byte, error) {
+	fields := arrangeFields(characterFields(recordFieldsOf(s), summaryOmit), characterOrder)
+	if s.ID <= 0 {
+		return json.Marshal(fields)
+	}
+
+	var inserted []Field
+	if icon := alias.IconPathFromID(s.ID); icon != "" {
+		inserted = append(inserted, Field{Key: "icon", Value: icon})
+	}
+	if portrait := alias.HeadPortraitPath(s.ID); portrait != "" {
+		inserted = append(inserted, Field{Key: "portrait", Value: portrait})
+	}
+	return json.Marshal(insertAfterName(fields, inserted...))
+}
+
+// characterFields drops textures and the keys in omit and turns
+// voiceActors into a voiceActor map of the non-empty names.
+func characterFields(fields []Field, omit map[string]bool) []Field {
+	result := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		if omit[f.Key] || f.Key == "textures" {
+			continue
+		}
+		if f.Key == "voiceActors" {
+			actors, _ := f.Value.(map[string]string)
+			if named := voiceActors(actors); len(named) > 0 {
+				result = append(result, Field{Key: "voiceActor", Value: named})
+			}
+			continue
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+func voiceActors(actors map[string]string) map[string]string {
+	named := make(map[string]string, len(actors))
+	for language, name := range actors {
+		if name != "" {
+			named[language] = name
+		}
+	}
+	return named
+}
+
+// insertAfterName inserts fields after "name", or first without one.
+func insertAfterName(doc Document, fields ...Field) Document {
+	at := 0
+	for i, f := range doc {
+		if f.Key == "name" {
+			at = i + 1
+			break
+		}
+	}
+	result := make(Document, 0, len(doc)+len(fields))
+	result = append(result, doc[:at]...)
+	result = append(result, fields...)
+	return append(result, doc[at:]...)
+}
+
+// Skill is a character skill: the normal attack, skill, support skill or
+// ultimate.
+type Skill struct {
+	Fields `json:"-"`
+
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	ShortDescription string `json:"shortDescription"`
+	// Params holds one value per placeholder in Description, levels
+	// separated by slashes.
+	Params   []string `json:"params"`
+	Cooldown string   `json:"cooldown"`
+}
+
+func (s *Skill) UnmarshalBSON(data []byte) error { return unmarshalBSON(s, data) }
+func (s *Skill) UnmarshalJSON(data []byte) error { return unmarshalJSON(s, data) }
+func (s Skill) MarshalJSON() ([]byte, error)     { return encodeRecord(s, characterOrder) }
+
+// DateEvent is a date a character can be taken on.
+type DateEvent struct {
+	Fields `json:"-"`
+
+	Name         string `json:"name"`
+	Clue         string `json:"clue"`
+	SecondChoice string `json:"secondChoice"`
+}
+
+func (e *DateEvent) UnmarshalBSON(data []byte) error { return unmarshalBSON(e, data) }
+func (e *DateEvent) UnmarshalJSON(data []byte) error { return unmarshalJSON(e, data) }
+func (e DateEvent) MarshalJSON() ([]byte, error)     { return encodeRecord(e, characterOrder) }
+
+// GiftPreferences lists the gifts a character loves and hates.
+type GiftPreferences struct {
+	Fields `json:"-"`
+
+	Loves []string `json:"loves"`
+	Hates []string `json:"hates"`
+}
+
+func (g *GiftPreferences) UnmarshalBSON(data []byte) error { return unmarshalBSON(g, data) }
+func (g *GiftPreferences) UnmarshalJSON(data []byte) error { return unmarshalJSON(g, data) }
+func (g GiftPreferences) MarshalJSON() ([]byte, error)     { return encodeRecord(g, characterOrder) }
+
+// Potentials groups a character's potentials by build. The groups differ
+// between releases, so they are kept as stored in Extra.
+type Potentials struct {
+	Fields `json:"-"`
+}
+
+func (p *Potentials) UnmarshalBSON(data []byte) error { return unmarshalBSON(p, data) }
+func (p *Potentials) UnmarshalJSON(data []byte) error { return unmarshalJSON(p, data) }
+func (p Potentials) MarshalJSON() ([]byte, error)     { return encodeRecord(p, characterOrder) }
+
+// Talents holds a character's talent tree, kept as stored in Extra.
+type Talents struct {
+	Fields `json:"-"`
+}
+
+func (t *Talents) UnmarshalBSON(data []byte) error { return unmarshalBSON(t, data) }
+func (t *Talents) UnmarshalJSON(data []byte) error { return unmarshalJSON(t, data) }
+func (t Talents) MarshalJSON() ([]byte, error)     { return encodeRecord(t, characterOrder) }
+
+// Stats holds a character's stats per level, kept as stored in Extra.
+type Stats struct {
+	Fields `json:"-"`
+}
+
+func (s *Stats) UnmarshalBSON(data []byte) error { return unmarshalBSON(s, data) }
+func (s *Stats) UnmarshalJSON(data []byte) error { return unmarshalJSON(s, data) }
+func (s Stats) MarshalJSON() ([]byte, error)     { return encodeRecord(s, characterOrder) }
+
+// Upgrades holds a character's promotion costs, kept as stored in Extra.
+type Upgrades struct {
+	Fields `json:"-"`
+}
+
+func (u *Upgrades) UnmarshalBSON(data []byte) error { return unmarshalBSON(u, data) }
+func (u *Upgrades) UnmarshalJSON(data []byte) error { return unmarshalJSON(u, data) }
+func (u Upgrades) MarshalJSON() ([]byte, error)     { return encodeRecord(u, characterOrder) }
+
+// SkillUpgrades holds a character's skill level costs, kept as stored in
+// Extra.
+type SkillUpgrades struct {
+	Fields `json:"-"`
+}
+
+func (u *SkillUpgrades) UnmarshalBSON(data []byte) error { return unmarshalBSON(u, data) }
+func (u *SkillUpgrades) UnmarshalJSON(data []byte) error { return unmarshalJSON(u, data) }
+func (u SkillUpgrades) MarshalJSON() ([]byte, error)     { return encodeRecord(u, characterOrder) }
@@ -0,0 +1,186 @@
+package model
+
+import "encoding/json"
+
+// discOrder is the key order of disc payloads. Keys it does not name
+// follow in stored order; nested objects use the same order.
+var discOrder = []string{
+	"id",
+	"name",
+	"icon",
+	"background",
+	"variants",
+	"star",
+	"element",
+	"tag",
+	"mainSkill",
+	"secondarySkills",
+	"supportNote",
+	"stats",
+	"dupe",
+	"upgrades",
+}
+
+// discSummaryOmit lists the disc fields left out of the list payload.
+var discSummaryOmit = map[string]bool{
+	"tag":             true,
+	"mainSkill":       true,
+	"secondarySkills": true,
+	"supportNote":     true,
+	"stats":           true,
+	"dupe":            true,
+	"upgrades":        true,
+}
+
+// Disc is a disc entry of one region. Disc stats are kept as stored in
+// Extra.
+type Disc struct {
+	Fields `json:"-"`
+
+	ID              int64       `json:"id"`
+	Name            string      `json:"name"`
+	Star            int         `json:"star"`
+	Element         string      `json:"element"`
+	Tag             []string    `json:"tag"`
+	MainSkill       DiscSkill   `json:"mainSkill"`
+	SecondarySkills []DiscSkill `json:"secondarySkills"`
+	// SupportNote lists, per level, the melodies the disc needs.
+	SupportNote [][]Melody `json:"supportNote"`
+	// Dupe lists, per duplicate, the stats it raises.
+	Dupe     [][]Dupe  `json:"dupe"`
+	Upgrades []Upgrade `json:"upgrades"`
+	Textures Textures  `json:"textures"`
+}
+
+func (d *Disc) UnmarshalBSON(data []byte) error { return unmarshalBSON(d, data) }
+func (d *Disc) UnmarshalJSON(data []byte) error { return unmarshalJSON(d, data) }
+
+// MarshalJSON writes the detail payload: textures become root-level icon,
+// background and variants asset paths.
+func (d Disc) MarshalJSON() ([]byte, error) {
+	fields := discFields(recordFieldsOf(d), nil)
+	if d.Has("textures") {
+		if icon := d.Textures.assetPath(friendlyIcon(d.Textures), d.Textures.Icon); icon != "" {
+			fields = append(fields, Field{Key: "icon", Value: icon})
+		}
+		if background := d.Textures.assetPath(friendlyBackground(d.Textures), d.Textures.Background); background != "" {
+			fields = append(fields, Field{Key: "background", Value: background})
+		}
+		if variants := d.Textures.variantPaths(); len(variants) > 0 {
+			fields = append(fields, Field{Key: "variants", Value: variants})
+		}
+	}
+	return json.Marshal(arrangeFields(fields, discOrder))
+}
+
+// DiscSummary is the list payload of a disc: the entry without its skills,
+// stats and costs, with the icon and base variant asset paths after the
+// name.
+type DiscSummary Disc
+
+// MarshalJSON writes the list payload.
+func (s DiscSummary) MarshalJSON() ([]byte, error) {
+	fields := arrangeFields(discFields(recordFieldsOf(s), discSummaryOmit), discOrder)
+	if !s.Has("textures") {
+		return json.Marshal(fields)
+	}
+
+	var inserted []Field
+	if icon := s.Textures.assetPath(friendlyIcon(s.Textures), s.Textures.Icon); icon != "" {
+		inserted = append(inserted, Field{Key: "icon", Value: icon})
+	}
+	if base, ok := s.Textures.variantPaths().String("base"); ok {
+		inserted = append(inserted, Field{Key: "base", Value: base})
+	}
+	return json.Marshal(insertAfterName(fields, inserted...))
+}
+
+// discFields drops textures and the keys in omit.
+func discFields(fields []Field, omit map[string]bool) []Field {
+	result := make([]Field, 0, len(fields))
+	for _, f := range fields {
+		if !omit[f.Key] && f.Key != "textures" {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func friendlyIcon(t Textures) string {
+	if t.Friendly == nil {
+		return ""
+	}
+	return t.Friendly.Icon
+}
+
+func friendlyBackground(t Textures) string {
+	if t.Friendly == nil {
+		return ""
+	}
+	return t.Friendly.Background
+}
+
+// DiscSkill is the main or a secondary skill of a disc.
+type DiscSkill struct {
+	Fields `json:"-"`
+
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Params holds, per level, one value per placeholder in Description.
+	Params [][]string `json:"params"`
+}
+
+func (s *DiscSkill) UnmarshalBSON(data []byte) error { return unmarshalBSON(s, data) }
+func (s *DiscSkill) UnmarshalJSON(data []byte) error { return unmarshalJSON(s, data) }
+func (s DiscSkill) MarshalJSON() ([]byte, error)     { return encodeRecord(s, discOrder) }
+
+// Melody is a melody note and how many of it a disc level needs.
+type Melody struct {
+	Fields `json:"-"`
+
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+func (m *Melody) UnmarshalBSON(data []byte) error { return unmarshalBSON(m, data) }
+func (m *Melody) UnmarshalJSON(data []byte) error { return unmarshalJSON(m, data) }
+func (m Melody) MarshalJSON() ([]byte, error)     { return encodeRecord(m, discOrder) }
+
+// Dupe is a stat raised by a duplicate of a disc.
+type Dupe struct {
+	Fields `json:"-"`
+
+	ID    string  `json:"id"`
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+}
+
+func (d *Dupe) UnmarshalBSON(data []byte) error { return unmarshalBSON(d, data) }
+func (d *Dupe) UnmarshalJSON(data []byte) error { return unmarshalJSON(d, data) }
+func (d Dupe) MarshalJSON() ([]byte, error)     { return encodeRecord(d, discOrder) }
+
+// Upgrade is the cost of one disc promotion.
+type Upgrade struct {
+	Fields `json:"-"`
+
+	Items []Item `json:"items"`
+	// Currency maps a currency such as "dorra" to the amount.
+	Currency Document `json:"currency"`
+}
+
+func (u *Upgrade) UnmarshalBSON(data []byte) error { return unmarshalBSON(u, data) }
+func (u *Upgrade) UnmarshalJSON(data []byte) error { return unmarshalJSON(u, data) }
+func (u Upgrade) MarshalJSON() ([]byte, error)     { return encodeRecord(u, discOrder) }
+
+// Item is a material and the quantity needed.
+type Item struct {
+	Fields `json:"-"`
+
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+func (i *Item) UnmarshalBSON(data []byte) error { return unmarshalBSON(i, data) }
+func (i *Item) UnmarshalJSON(data []byte) error { return unmarshalJSON(i, data) }
+func (i Item) MarshalJSON() ([]byte, error)     { return encodeRecord(i, discOrder) }
@@ -0,0 +1,231 @@
+// Package model holds the typed catalog records stored in MongoDB and
+// served by the API: characters and their skills, potentials, talents,
+// stats and upgrades, and discs with their melodies and dupes.
+//
+// Records remember the order their fields were stored in and keep fields
+// their type does not declare, so encoding a decoded record reproduces the
+// stored document with the API's key order and asset paths applied.
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Document is a JSON object that keeps its key order. Nested objects are
+// Documents and arrays are []any.
+type Document []Field
+
+// Field is one key of a Document.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Lookup returns the value stored under key.
+func (d Document) Lookup(key string) (any, bool) {
+	for _, f := range d {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// String returns the value under key when it is a non-empty string.
+func (d Document) String(key string) (string, bool) {
+	value, _ := d.Lookup(key)
+	s, ok := value.(string)
+	return s, ok && s != ""
+}
+
+// MarshalJSON writes the fields in order.
+func (d Document) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range d {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(f.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBSON reads an embedded document.
+func (d *Document) UnmarshalBSON(data []byte) error {
+	doc, err := documentFromBSON(bson.Raw(data))
+	if err != nil {
+		return err
+	}
+	*d = doc
+	return nil
+}
+
+// UnmarshalJSON reads an object; numbers are kept as json.Number.
+func (d *Document) UnmarshalJSON(data []byte) error {
+	value, err := valueFromJSON(data)
+	if err != nil {
+		return err
+	}
+	doc, ok := value.(Document)
+	if !ok {
+		return errors.New("model: expected a JSON object")
+	}
+	*d = doc
+	return nil
+}
+
+func documentFromBSON(raw bson.Raw) (Document, error) {
+	elements, err := raw.Elements()
+	if err != nil {
+		return nil, err
+	}
+	doc := make(Document, 0, len(elements))
+	for _, elem := range elements {
+		value, err := valueFromBSON(elem.Value())
+		if err != nil {
+			return nil, err
+		}
+		doc = append(doc, Field{Key: elem.Key(), Value: value})
+	}
+	return doc, nil
+}
+
+func valueFromBSON(rv bson.RawValue) (any, error) {
+	switch rv.Type {
+	case bsontype.EmbeddedDocument:
+		return documentFromBSON(rv.Document())
+	case bsontype.Array:
+		values, err := rv.Array().Values()
+		if err != nil {
+			return nil, err
+		}
+		result := make([]any, 0, len(values))
+		for _, value := range values {
+			converted, err := valueFromBSON(value)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, converted)
+		}
+		return result, nil
+	default:
+		var generic any
+		if err := rv.Unmarshal(&generic); err != nil {
+			return nil, err
+		}
+		return generic, nil
+	}
+}
+
+func valueFromJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	value, err := readJSONValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("model: trailing data after JSON value")
+	}
+	return value, nil
+}
+
+func readJSONValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		doc := Document{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			doc = append(doc, Field{Key: key.(string), Value: value})
+		}
+		_, err := decoder.Token()
+		return doc, err
+	case json.Delim('['):
+		values := []any{}
+		for decoder.More() {
+			value, err := readJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		_, err := decoder.Token()
+		return values, err
+	default:
+		return token, nil
+	}
+}
+
+// arrange puts the keys of every nested Document named in order first, in
+// that order, followed by the rest as stored.
+func arrange(value any, order []string) any {
+	switch v := value.(type) {
+	case Document:
+		fields := make([]Field, len(v))
+		for i, f := range v {
+			fields[i] = Field{Key: f.Key, Value: arrange(f.Value, order)}
+		}
+		return Document(sortFields(fields, order))
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = arrange(item, order)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// sortFields is a stable sort of fields by their position in order; keys
+// order does not name keep their relative order after the named ones.
+func sortFields(fields []Field, order []string) []Field {
+	if len(fields) == 0 {
+		return fields
+	}
+	sorted := make([]Field, 0, len(fields))
+	used := make([]bool, len(fields))
+	for _, key := range order {
+		for i, f := range fields {
+			if !used[i] && f.Key == key {
+				sorted = append(sorted, f)
+				used[i] = true
+				break
+			}
+		}
+	}
+	for i, f := range fields {
+		if !used[i] {
+			sorted = append(sorted, f)
+		}
+	}
+	return sorted
+}
@@ -0,0 +1,252 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// Fields is embedded in every record. It remembers the order the record's
+// fields were stored in and keeps the fields the record does not declare.
+type Fields struct {
+	order []string
+	// Extra holds the stored fields the record type does not declare, and
+	// declared fields stored as null.
+	Extra Document
+}
+
+// Keys returns the stored field names in stored order.
+func (f Fields) Keys() []string {
+	return append([]string(nil), f.order...)
+}
+
+// Has reports whether the record was stored with key.
+func (f Fields) Has(key string) bool {
+	for _, k := range f.order {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+var fieldsType = reflect.TypeFor[Fields]()
+
+// element is one stored field before it is decoded.
+type element struct {
+	key  string
+	null bool
+	// object is set for embedded documents and JSON objects.
+	object bool
+	// into decodes the field into a pointer to a declared field.
+	into func(target any) error
+	// value decodes the field generically for Extra.
+	value func() (any, error)
+}
+
+func bsonElements(data []byte) ([]element, error) {
+	elements, err := bson.Raw(data).Elements()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]element, 0, len(elements))
+	for _, elem := range elements {
+		rv := elem.Value()
+		result = append(result, element{
+			key:    elem.Key(),
+			null:   rv.Type == bsontype.Null,
+			object: rv.Type == bsontype.EmbeddedDocument,
+			into:   rv.Unmarshal,
+			value:  func() (any, error) { return valueFromBSON(rv) },
+		})
+	}
+	return result, nil
+}
+
+func jsonElements(data []byte) ([]element, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('{') {
+		return nil, errors.New("model: expected a JSON object")
+	}
+
+	var result []element
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+		result = append(result, element{
+			key:    token.(string),
+			null:   string(raw) == "null",
+			object: len(raw) > 0 && raw[0] == '{',
+			into:   func(target any) error { return json.Unmarshal(raw, target) },
+			value:  func() (any, error) { return valueFromJSON(raw) },
+		})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// decodeRecord fills the declared fields of the record rec points at and
+// records order and extras in its Fields.
+func decodeRecord(rec any, elements []element) error {
+	v := reflect.ValueOf(rec).Elem()
+	declared := recordFields(v.Type())
+
+	var fields Fields
+	for _, elem := range elements {
+		fields.order = append(fields.order, elem.key)
+		if index, ok := declared[elem.key]; ok && !elem.null {
+			if isObject(v.Field(index).Type()) && !elem.object {
+				return fmt.Errorf("%s: expected an object", elem.key)
+			}
+			if err := elem.into(v.Field(index).Addr().Interface()); err != nil {
+				return fmt.Errorf("%s: %w", elem.key, err)
+			}
+			continue
+		}
+		value, err := elem.value()
+		if err != nil {
+			return fmt.Errorf("%s: %w", elem.key, err)
+		}
+		fields.Extra = append(fields.Extra, Field{Key: elem.key, Value: value})
+	}
+
+	v.FieldByIndex(fieldsIndex(v.Type())).Set(reflect.ValueOf(fields))
+	return nil
+}
+
+// recordFields maps the JSON names of a record's declared fields to their
+// index.
+func recordFields(t reflect.Type) map[string]int {
+	if cached, ok := recordCache.Load(t); ok {
+		return cached.(map[string]int)
+	}
+	declared := map[string]int{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Type == fieldsType || !field.IsExported() {
+			continue
+		}
+		if name := jsonName(field); name != "" {
+			declared[name] = i
+		}
+	}
+	recordCache.Store(t, declared)
+	return declared
+}
+
+var recordCache sync.Map
+
+var documentType = reflect.TypeFor[Document]()
+
+// isObject reports whether t is decoded from an object: a record, a
+// pointer to one, or a Document.
+func isObject(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == documentType {
+		return true
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	field, ok := t.FieldByName("Fields")
+	return ok && field.Type == fieldsType
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func fieldsIndex(t reflect.Type) []int {
+	field, ok := t.FieldByName("Fields")
+	if !ok || field.Type != fieldsType {
+		panic("model: " + t.Name() + " does not embed Fields")
+	}
+	return field.Index
+}
+
+// recordFieldsOf returns the fields of rec in stored order, with Extra
+// values in place. A record that was not decoded lists its non-zero
+// declared fields followed by Extra.
+func recordFieldsOf(rec any) []Field {
+	v := reflect.ValueOf(rec)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	fields := v.FieldByIndex(fieldsIndex(v.Type())).Interface().(Fields)
+	declared := recordFields(v.Type())
+
+	if fields.order == nil {
+		var result []Field
+		for i := range v.NumField() {
+			name := jsonName(v.Type().Field(i))
+			if _, ok := declared[name]; ok && !v.Field(i).IsZero() {
+				result = append(result, Field{Key: name, Value: v.Field(i).Interface()})
+			}
+		}
+		return append(result, fields.Extra...)
+	}
+
+	result := make([]Field, 0, len(fields.order))
+	for _, key := range fields.order {
+		if value, ok := fields.Extra.Lookup(key); ok {
+			result = append(result, Field{Key: key, Value: value})
+			continue
+		}
+		if index, ok := declared[key]; ok {
+			result = append(result, Field{Key: key, Value: v.Field(index).Interface()})
+		}
+	}
+	return result
+}
+
+// encodeRecord writes rec with the keys named in order first and extras
+// arranged the same way.
+func encodeRecord(rec any, order []string) ([]byte, error) {
+	return json.Marshal(arrangeFields(recordFieldsOf(rec), order))
+}
+
+func arrangeFields(fields []Field, order []string) Document {
+	for i, f := range fields {
+		fields[i].Value = arrange(f.Value, order)
+	}
+	return Document(sortFields(fields, order))
+}
+
+func unmarshalBSON(rec any, data []byte) error {
+	elements, err := bsonElements(data)
+	if err != nil {
+		return err
+	}
+	return decodeRecord(rec, elements)
+}
+
+func unmarshalJSON(rec any, data []byte) error {
+	elements, err := jsonElements(data)
+	if err != nil {
+		return err
+	}
+	return decodeRecord(rec, elements)
+}
@@ -0,0 +1,90 @@
+package model
+
+import "ss-api/internal/alias"
+
+// Textures names the art of a character or disc. The top-level fields
+// hold source paths from the game files; Friendly holds the aliases the
+// files are served under at /stella/assets/.
+type Textures struct {
+	Fields `json:"-"`
+
+	Icon       string `json:"icon"`
+	Portrait   string `json:"portrait"`
+	Background string `json:"background"`
+	// Variants maps a variant name such as "base" to a path, possibly
+	// nested.
+	Variants Document  `json:"variants"`
+	Friendly *Textures `json:"friendly"`
+}
+
+func (t *Textures) UnmarshalBSON(data []byte) error { return unmarshalBSON(t, data) }
+func (t *Textures) UnmarshalJSON(data []byte) error { return unmarshalJSON(t, data) }
+func (t Textures) MarshalJSON() ([]byte, error)     { return encodeRecord(t, nil) }
+
+// friendlyFields returns the friendly icon, portrait, background and
+// variants as asset paths.
+func (t Textures) friendlyFields() []Field {
+	friendly := t.Friendly
+	if friendly == nil || (len(friendly.order) == 0 && len(friendly.Extra) == 0) {
+		return nil
+	}
+
+	var fields []Field
+	for _, texture := range [...]struct{ key, name string }{
+		{"icon", friendly.Icon},
+		{"portrait", friendly.Portrait},
+		{"background", friendly.Background},
+	} {
+		if texture.name == "" {
+			continue
+		}
+		if path := alias.PathFromAlias(texture.name); path != "" {
+			fields = append(fields, Field{Key: texture.key, Value: path})
+		}
+	}
+	if friendly.Variants != nil {
+		fields = append(fields, Field{Key: "variants", Value: pathify(friendly.Variants, alias.PathFromAlias)})
+	}
+	return fields
+}
+
+// assetPath resolves a texture to an asset path, preferring the friendly
+// alias over the source path.
+func (t Textures) assetPath(friendly, source string) string {
+	if t.Friendly != nil && friendly != "" {
+		if path := alias.PathFromAlias(friendly); path != "" {
+			return path
+		}
+	}
+	if source != "" {
+		return alias.PathFromSource(source)
+	}
+	return ""
+}
+
+// variantPaths returns the friendly variants, or else the source variants,
+// as asset paths.
+func (t Textures) variantPaths() Document {
+	if t.Friendly != nil && t.Friendly.Variants != nil {
+		return pathify(t.Friendly.Variants, alias.PathFromAlias)
+	}
+	if t.Variants != nil {
+		return pathify(t.Variants, alias.PathFromSource)
+	}
+	return nil
+}
+
+// pathify converts every string of doc, including nested ones.
+func pathify(doc Document, convert func(string) string) Document {
+	result := make(Document, len(doc))
+	for i, f := range doc {
+		switch v := f.Value.(type) {
+		case string:
+			f.Value = convert(v)
+		case Document:
+			f.Value = pathify(v, convert)
+		}
+		result[i] = f
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package validate
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"ss-api/internal/locale"
+)
+
+// Assets answers whether a texture has a file in the assets directory. It
+// resolves names the way /stella/assets/ does: by base name, with ".png"
+// added when there is no extension, case-insensitively, in the region's
+// subdirectory or the root.
+type Assets struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]map[string]bool // subdirectory → lower-case file names
+}
+
+// NewAssets indexes dir lazily. It returns nil when dir is not a
+// directory, which skips the asset checks.
+func NewAssets(dir string) *Assets {
+	if dir == "" {
+		return nil
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil
+	}
+	return &Assets{dir: dir, files: map[string]map[string]bool{}}
+}
+
+// Has reports whether source, a stored texture path such as
+// "Icon/Outfit/outfit_1001_b", has a file for region.
+func (a *Assets) Has(region locale.Locale, source string) bool {
+	base := path.Base(strings.TrimSpace(source))
+	if base == "" || base == "." || base == "/" {
+		return false
+	}
+	if filepath.Ext(base) == "" {
+		base += ".png"
+	}
+	name := strings.ToLower(base)
+
+	if region != "" && a.list(region.Dir())[name] {
+		return true
+	}
+	return a.list("")[name]
+}
+
+func (a *Assets) list(subdir string) map[string]bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if files, ok := a.files[subdir]; ok {
+		return files
+	}
+	files := map[string]bool{}
+	entries, _ := os.ReadDir(filepath.Join(a.dir, subdir))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files[strings.ToLower(entry.Name())] = true
+		}
+	}
+	a.files[subdir] = files
+	return files
+}
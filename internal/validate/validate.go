@@ -0,0 +1,497 @@
+// Package validate checks the catalog collections for data problems the
+// API would otherwise only log or silently skip: duplicate or missing IDs,
+// missing names, unparseable timestamps, textures without an asset file,
+// rate-up entries that match no character or disc, and regions missing
+// entries EN has. The report is machine readable and shared by the
+// stella-validate command and GET /stella/admin/validate.
+package validate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"ss-api/internal/app"
+	"ss-api/internal/locale"
+	"ss-api/internal/model"
+)
+
+// Checks, as reported in Issue.Check.
+const (
+	// CheckInvalidDocument: a region document without a region or an
+	// entries array, or an entry the typed model cannot decode.
+	CheckInvalidDocument = "invalid_document"
+	// CheckUnknownRegion: a region value the API never queries, e.g. "en"
+	// instead of "EN".
+	CheckUnknownRegion = "unknown_region"
+	CheckMissingID     = "missing_id"
+	CheckDuplicateID   = "duplicate_id"
+	// CheckMissingName: an empty name or title, or the "???" placeholder.
+	CheckMissingName = "missing_name"
+	// CheckInvalidTimestamp: a start, end or claim end time that is not
+	// RFC 3339.
+	CheckInvalidTimestamp = "invalid_timestamp"
+	// CheckMissingAsset: a texture whose file is not in the assets
+	// directory or the region's subdirectory.
+	CheckMissingAsset = "missing_asset"
+	// CheckUnknownRateUp: a banner rate-up ID that matches no character or
+	// disc of the region.
+	CheckUnknownRateUp = "unknown_rate_up"
+	// CheckMissingEntry: a character or disc EN has and the region lacks.
+	CheckMissingEntry = "missing_entry"
+)
+
+// Severities. Only errors make a report fail.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+var severities = map[string]string{
+	CheckInvalidDocument:  SeverityError,
+	CheckUnknownRegion:    SeverityError,
+	CheckMissingID:        SeverityError,
+	CheckDuplicateID:      SeverityError,
+	CheckMissingName:      SeverityError,
+	CheckInvalidTimestamp: SeverityError,
+	CheckMissingAsset:     SeverityWarning,
+	CheckUnknownRateUp:    SeverityError,
+	CheckMissingEntry:     SeverityWarning,
+}
+
+// Issue is one problem found.
+type Issue struct {
+	Check      string `json:"check"`
+	Severity   string `json:"severity"`
+	Collection string `json:"collection"`
+	Region     string `json:"region,omitempty"`
+	// Entry is the position of the entry in the region's entries, counted
+	// across the region's documents.
+	Entry *int  `json:"entry,omitempty"`
+	ID    int64 `json:"id,omitempty"`
+	// Field is the dotted path of the offending field, e.g.
+	// "rateUp.fiveStar.entries.0.id".
+	Field   string `json:"field,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+}
+
+// Scope is one collection and region that was checked.
+type Scope struct {
+	Collection string `json:"collection"`
+	Region     string `json:"region"`
+	Documents  int    `json:"documents"`
+	Entries    int    `json:"entries"`
+}
+
+// Report is the result of a validation run.
+type Report struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	// OK is false when any issue is an error.
+	OK       bool `json:"ok"`
+	Errors   int  `json:"errors"`
+	Warnings int  `json:"warnings"`
+	// Checks counts the issues per check.
+	Checks  map[string]int `json:"checks"`
+	Scopes  []Scope        `json:"scopes"`
+	Issues  []Issue        `json:"issues"`
+	Skipped []string       `json:"skipped,omitempty"`
+}
+
+// Region holds the entries of one collection and region, in stored order.
+type Region struct {
+	Collection string
+	Region     string
+	Documents  int
+	Entries    []bson.Raw
+}
+
+// Run loads every catalog collection from db and checks it. Asset checks
+// are skipped when assets is nil.
+func Run(ctx context.Context, db *mongo.Database, assets *Assets) (Report, error) {
+	regions, issues, err := Load(ctx, db)
+	if err != nil {
+		return Report{}, err
+	}
+	return Check(regions, assets, issues...), nil
+}
+
+// Load reads the region documents of every catalog collection. Documents
+// that are not in the {region, entries[]} shape are returned as issues.
+func Load(ctx context.Context, db *mongo.Database) ([]Region, []Issue, error) {
+	var regions []Region
+	var issues []Issue
+
+	for _, collection := range app.CatalogCollections {
+		cursor, err := db.Collection(collection).Find(ctx, bson.D{})
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", collection, err)
+		}
+
+		byRegion := map[string]*Region{}
+		var order []string
+		for cursor.Next(ctx) {
+			doc := cursor.Current
+			region, ok := doc.Lookup("region").StringValueOK()
+			if !ok {
+				issues = append(issues, newIssue(CheckInvalidDocument, collection, "", nil, "region",
+					"document "+documentID(doc)+" has no string region"))
+				continue
+			}
+			entries := doc.Lookup("entries")
+			if entries.Type != bsontype.Array {
+				issues = append(issues, newIssue(CheckInvalidDocument, collection, region, nil, "entries",
+					"document "+documentID(doc)+" has no entries array"))
+				continue
+			}
+
+			r := byRegion[region]
+			if r == nil {
+				r = &Region{Collection: collection, Region: region}
+				byRegion[region] = r
+				order = append(order, region)
+			}
+			r.Documents++
+
+			values, err := entries.Array().Values()
+			if err != nil {
+				_ = cursor.Close(ctx)
+				return nil, nil, fmt.Errorf("%s %s: %w", collection, region, err)
+			}
+			for _, value := range values {
+				entry, _ := value.DocumentOK()
+				r.Entries = append(r.Entries, entry)
+			}
+		}
+		err = cursor.Err()
+		_ = cursor.Close(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", collection, err)
+		}
+
+		for _, region := range order {
+			regions = append(regions, *byRegion[region])
+		}
+	}
+
+	return regions, issues, nil
+}
+
+func documentID(doc bson.Raw) string {
+	id := doc.Lookup("_id")
+	if oid, ok := id.ObjectIDOK(); ok {
+		return oid.Hex()
+	}
+	return id.String()
+}
+
+// Check validates regions and returns the report, including issues found
+// while loading them. A nil entry in Region.Entries is an entry that is
+// not a document.
+func Check(regions []Region, assets *Assets, issues ...Issue) Report {
+	c := checker{assets: assets, issues: issues, ids: map[string]map[string]map[int64]bool{}}
+
+	report := Report{GeneratedAt: time.Now().UTC(), Scopes: []Scope{}}
+	for _, r := range regions {
+		report.Scopes = append(report.Scopes, Scope{
+			Collection: r.Collection,
+			Region:     r.Region,
+			Documents:  r.Documents,
+			Entries:    len(r.Entries),
+		})
+		c.region(r)
+	}
+	c.rateUps(regions)
+	c.missingEntries()
+
+	if assets == nil {
+		report.Skipped = append(report.Skipped, CheckMissingAsset)
+	}
+
+	report.Issues = c.issues
+	if report.Issues == nil {
+		report.Issues = []Issue{}
+	}
+	report.Checks = map[string]int{}
+	for _, issue := range report.Issues {
+		report.Checks[issue.Check]++
+		if issue.Severity == SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	report.OK = report.Errors == 0
+	return report
+}
+
+type checker struct {
+	assets *Assets
+	issues []Issue
+	// ids holds the entry IDs per collection and region.
+	ids map[string]map[string]map[int64]bool
+}
+
+func newIssue(check, collection, region string, entry *int, field, message string) Issue {
+	return Issue{
+		Check:      check,
+		Severity:   severities[check],
+		Collection: collection,
+		Region:     region,
+		Entry:      entry,
+		Field:      field,
+		Message:    message,
+	}
+}
+
+func (c *checker) add(issue Issue) {
+	c.issues = append(c.issues, issue)
+}
+
+// region runs the per-entry checks of one collection and region.
+func (c *checker) region(r Region) {
+	l, supported := locale.Parse(r.Region)
+	if !supported || string(l) != r.Region {
+		message := fmt.Sprintf("region %q is not one of %s", r.Region, strings.Join(locale.Names(), ", "))
+		if supported {
+			message = fmt.Sprintf("region %q is never queried; the API reads %q", r.Region, l)
+		}
+		c.add(newIssue(CheckUnknownRegion, r.Collection, r.Region, nil, "region", message))
+	}
+
+	if c.ids[r.Collection] == nil {
+		c.ids[r.Collection] = map[string]map[int64]bool{}
+	}
+	ids := map[int64]bool{}
+	c.ids[r.Collection][r.Region] = ids
+	first := map[int64]int{}
+
+	for i, entry := range r.Entries {
+		index := i
+		at := func(check, field, message string) Issue {
+			return newIssue(check, r.Collection, r.Region, &index, field, message)
+		}
+
+		if entry == nil {
+			c.add(at(CheckInvalidDocument, "", "entry is not a document"))
+			continue
+		}
+
+		id, ok := numericID(entry.Lookup("id"))
+		if !ok {
+			c.add(at(CheckMissingID, "id", "entry has no numeric id"))
+		} else if prev, seen := first[id]; seen {
+			issue := at(CheckDuplicateID, "id", fmt.Sprintf("id %d is also used by entry %d", id, prev))
+			issue.ID = id
+			c.add(issue)
+		} else {
+			first[id] = i
+			ids[id] = true
+		}
+
+		withID := func(issue Issue) Issue {
+			issue.ID = id
+			return issue
+		}
+
+		c.decode(r.Collection, entry, func(check, field, message string) Issue {
+			return withID(at(check, field, message))
+		})
+
+		nameField := "name"
+		if r.Collection == "events" {
+			nameField = "title"
+		}
+		if name, _ := entry.Lookup(nameField).StringValueOK(); strings.TrimSpace(name) == "" || name == "???" {
+			issue := withID(at(CheckMissingName, nameField, "entry has no "+nameField))
+			issue.Value = name
+			c.add(issue)
+		}
+
+		for _, field := range timestampFields[r.Collection] {
+			value := entry.Lookup(field)
+			if value.Type == bsontype.Null || value.Type == 0 {
+				continue
+			}
+			raw, isString := value.StringValueOK()
+			if !isString {
+				c.add(withID(at(CheckInvalidTimestamp, field, field+" is a "+value.Type.String()+", not a string")))
+				continue
+			}
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+			if _, err := time.Parse(time.RFC3339, strings.TrimSpace(raw)); err != nil {
+				issue := withID(at(CheckInvalidTimestamp, field, err.Error()))
+				issue.Value = raw
+				c.add(issue)
+			}
+		}
+
+		if c.assets != nil {
+			for _, texture := range textureSources(entry.Lookup("textures"), "textures") {
+				if !c.assets.Has(l, texture.value) {
+					issue := withID(at(CheckMissingAsset, texture.field, "no asset file for "+texture.value))
+					issue.Value = texture.value
+					c.add(issue)
+				}
+			}
+		}
+	}
+}
+
+// timestampFields lists the RFC 3339 fields per collection.
+var timestampFields = map[string][]string{
+	"gacha":  {"startTime", "endTime"},
+	"events": {"startTime", "endTime", "claimEndTime"},
+}
+
+// decode checks that character and disc entries fit their typed model.
+func (c *checker) decode(collection string, entry bson.Raw, at func(check, field, message string) Issue) {
+	var err error
+	switch collection {
+	case "characters":
+		err = bson.Unmarshal(entry, new(model.Character))
+	case "discs":
+		err = bson.Unmarshal(entry, new(model.Disc))
+	}
+	if err != nil {
+		field, _, _ := strings.Cut(err.Error(), ":")
+		c.add(at(CheckInvalidDocument, field, err.Error()))
+	}
+}
+
+// rateUps checks that every banner rate-up ID is a character or disc of
+// the banner's region.
+func (c *checker) rateUps(regions []Region) {
+	for _, r := range regions {
+		if r.Collection != "gacha" {
+			continue
+		}
+		characters := c.ids["characters"][r.Region]
+		discs := c.ids["discs"][r.Region]
+
+		for i, entry := range r.Entries {
+			if entry == nil {
+				continue
+			}
+			bannerID, _ := numericID(entry.Lookup("id"))
+			for _, pool := range []string{"fiveStar", "fourStar"} {
+				array, ok := entry.Lookup("rateUp", pool, "entries").ArrayOK()
+				if !ok {
+					continue
+				}
+				values, err := array.Values()
+				if err != nil {
+					continue
+				}
+				for j, value := range values {
+					doc, ok := value.DocumentOK()
+					if !ok {
+						continue
+					}
+					id, ok := numericID(doc.Lookup("id"))
+					if !ok || characters[id] || discs[id] {
+						continue
+					}
+					index := i
+					issue := newIssue(CheckUnknownRateUp, r.Collection, r.Region, &index,
+						fmt.Sprintf("rateUp.%s.entries.%d.id", pool, j),
+						fmt.Sprintf("rate-up id %d matches no character or disc in %s", id, r.Region))
+					issue.ID = bannerID
+					issue.Value = fmt.Sprint(id)
+					c.add(issue)
+				}
+			}
+		}
+	}
+}
+
+// missingEntries reports the characters and discs EN has and another
+// region lacks. Every region with data in any collection is compared, so a
+// region with no characters or discs document at all lacks them all.
+func (c *checker) missingEntries() {
+	seen := map[string]bool{}
+	var regions []string
+	for _, byRegion := range c.ids {
+		for region := range byRegion {
+			if region != string(locale.EN) && !seen[region] {
+				seen[region] = true
+				regions = append(regions, region)
+			}
+		}
+	}
+	sort.Strings(regions)
+
+	for _, collection := range []string{"characters", "discs"} {
+		reference := c.ids[collection][string(locale.EN)]
+		if len(reference) == 0 {
+			continue
+		}
+		for _, region := range regions {
+			ids := c.ids[collection][region]
+			var missing []int64
+			for id := range reference {
+				if !ids[id] {
+					missing = append(missing, id)
+				}
+			}
+			sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+			for _, id := range missing {
+				issue := newIssue(CheckMissingEntry, collection, region, nil, "id",
+					fmt.Sprintf("%s has id %d and %s does not", locale.EN, id, region))
+				issue.ID = id
+				c.add(issue)
+			}
+		}
+	}
+}
+
+func numericID(value bson.RawValue) (int64, bool) {
+	switch value.Type {
+	case bsontype.Int32:
+		return int64(value.Int32()), true
+	case bsontype.Int64:
+		return value.Int64(), true
+	case bsontype.Double:
+		f := value.Double()
+		return int64(f), f == float64(int64(f))
+	}
+	return 0, false
+}
+
+type texture struct {
+	field string
+	value string
+}
+
+// textureSources returns the source paths under a textures document. The
+// friendly aliases are skipped: they name served files, not stored ones.
+func textureSources(value bson.RawValue, field string) []texture {
+	switch value.Type {
+	case bsontype.String:
+		if s := strings.TrimSpace(value.StringValue()); s != "" {
+			return []texture{{field: field, value: s}}
+		}
+	case bsontype.EmbeddedDocument:
+		elements, err := value.Document().Elements()
+		if err != nil {
+			return nil
+		}
+		var result []texture
+		for _, elem := range elements {
+			if elem.Key() == "friendly" {
+				continue
+			}
+			result = append(result, textureSources(elem.Value(), field+"."+elem.Key())...)
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package validate
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// entries marshals docs into region entries.
+func entries(t *testing.T, docs ...bson.D) []bson.Raw {
+	t.Helper()
+	raw := make([]bson.Raw, len(docs))
+	for i, doc := range docs {
+		b, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw[i] = b
+	}
+	return raw
+}
+
+func entry(id int64, name string, fields ...bson.E) bson.D {
+	return append(bson.D{{Key: "id", Value: id}, {Key: "name", Value: name}}, fields...)
+}
+
+func banner(id int64, rateUp ...int64) bson.D {
+	pool := bson.A{}
+	for _, rid := range rateUp {
+		pool = append(pool, bson.D{{Key: "id", Value: rid}})
+	}
+	return entry(id, "Banner", bson.E{Key: "rateUp", Value: bson.D{
+		{Key: "fiveStar", Value: bson.D{{Key: "entries", Value: pool}}},
+	}})
+}
+
+type found struct {
+	check, collection, region string
+	id                        int64
+}
+
+func TestCheck(t *testing.T) {
+	for name, tc := range map[string]struct {
+		regions []Region
+		want    []found
+	}{
+		"valid": {
+			regions: []Region{
+				{Collection: "characters", Region: "EN", Entries: entries(t, entry(1, "Amber"))},
+				{Collection: "gacha", Region: "EN", Entries: entries(t, banner(10, 1))},
+			},
+		},
+		"duplicate id": {
+			regions: []Region{
+				{Collection: "characters", Region: "EN", Entries: entries(t, entry(1, "Amber"), entry(1, "Amber again"))},
+			},
+			want: []found{{CheckDuplicateID, "characters", "EN", 1}},
+		},
+		"missing id and name": {
+			regions: []Region{
+				{Collection: "discs", Region: "EN", Entries: entries(t, bson.D{{Key: "name", Value: "???"}})},
+			},
+			want: []found{{CheckMissingID, "discs", "EN", 0}, {CheckMissingName, "discs", "EN", 0}},
+		},
+		"timestamps": {
+			regions: []Region{
+				{Collection: "events", Region: "EN", Entries: entries(t,
+					bson.D{{Key: "id", Value: int64(1)}, {Key: "title", Value: "Ok"}, {Key: "startTime", Value: "2025-11-01T03:00:00Z"}, {Key: "endTime", Value: ""}},
+					bson.D{{Key: "id", Value: int64(2)}, {Key: "title", Value: "Bad"}, {Key: "startTime", Value: "2025-11-01 03:00"}},
+					bson.D{{Key: "id", Value: int64(3)}, {Key: "title", Value: "Number"}, {Key: "endTime", Value: int64(1730430000)}},
+				)},
+			},
+			want: []found{{CheckInvalidTimestamp, "events", "EN", 2}, {CheckInvalidTimestamp, "events", "EN", 3}},
+		},
+		"rate-up of another region": {
+			regions: []Region{
+				{Collection: "characters", Region: "EN", Entries: entries(t, entry(1, "Amber"))},
+				{Collection: "characters", Region: "JP", Entries: entries(t, entry(1, "Amber"), entry(2, "Nanoha"))},
+				{Collection: "gacha", Region: "EN", Entries: entries(t, banner(10, 1, 2))},
+			},
+			want: []found{{CheckUnknownRateUp, "gacha", "EN", 10}},
+		},
+		"missing entry": {
+			regions: []Region{
+				{Collection: "characters", Region: "EN", Entries: entries(t, entry(1, "Amber"), entry(2, "Nanoha"))},
+				{Collection: "characters", Region: "JP", Entries: entries(t, entry(1, "Amber"))},
+			},
+			want: []found{{CheckMissingEntry, "characters", "JP", 2}},
+		},
+		"region without characters": {
+			regions: []Region{
+				{Collection: "characters", Region: "EN", Entries: entries(t, entry(1, "Amber"))},
+				{Collection: "events", Region: "KR", Entries: entries(t, bson.D{{Key: "id", Value: int64(5)}, {Key: "title", Value: "Event"}})},
+			},
+			want: []found{{CheckMissingEntry, "characters", "KR", 1}},
+		},
+		"unknown region": {
+			regions: []Region{
+				{Collection: "discs", Region: "en", Entries: entries(t, entry(1, "Disc"))},
+			},
+			want: []found{{CheckUnknownRegion, "discs", "en", 0}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			report := Check(tc.regions, nil)
+
+			var got []found
+			for _, issue := range report.Issues {
+				got = append(got, found{issue.Check, issue.Collection, issue.Region, issue.ID})
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("issues = %+v, want %+v\n%+v", got, tc.want, report.Issues)
+			}
+
+			wantOK := !slices.ContainsFunc(tc.want, func(f found) bool { return severities[f.check] == SeverityError })
+			if report.OK != wantOK {
+				t.Errorf("ok = %v, want %v", report.OK, wantOK)
+			}
+			if !slices.Equal(report.Skipped, []string{CheckMissingAsset}) {
+				t.Errorf("skipped = %v, want the asset check", report.Skipped)
+			}
+		})
+	}
+}